@@ -0,0 +1,115 @@
+package ctlstore
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/segmentio/ctlstore/pkg/scanfunc"
+	"github.com/segmentio/ctlstore/pkg/schema"
+)
+
+// maxBatchQueryParams is the most parameters a single batch lookup query
+// will bind, which keeps it under SQLite's SQLITE_MAX_VARIABLE_NUMBER.
+const maxBatchQueryParams = 999
+
+// keyBatch is one chunk of a batch lookup, ready to be queried.
+type keyBatch struct {
+	stmt *sql.Stmt
+	args []interface{}
+}
+
+// read queries the chunk and reads all of its rows. The statements come
+// from the reader's cache, so may be closed once the reader's lock has been
+// released, which is why the chunks after the first one are read up front.
+func (b keyBatch) read(ctx context.Context, numCols int) (*valueRows, error) {
+	rows, err := b.stmt.QueryContext(ctx, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := &valueRows{pos: -1}
+	for rows.Next() {
+		vals := make(scanfunc.Values, numCols)
+		targets := make([]interface{}, numCols)
+		for i := range vals {
+			targets[i] = &vals[i]
+		}
+		if err := rows.Scan(targets...); err != nil {
+			return nil, err
+		}
+		res.vals = append(res.vals, vals)
+	}
+	return res, rows.Err()
+}
+
+// keyBatchState holds the chunks of a batch lookup that come after the one
+// being read, so that Rows can move on to the next chunk once the current
+// one has been read.
+type keyBatchState struct {
+	pending []rowSource
+	err     error
+}
+
+// advance closes the current rows and moves on to the next chunk,
+// returning false if there are no chunks left.
+func (s *keyBatchState) advance(r *Rows) bool {
+	if len(s.pending) == 0 {
+		return false
+	}
+	if err := r.rows.Close(); err != nil {
+		s.err = err
+		return false
+	}
+	r.rows = s.pending[0]
+	s.pending = s.pending[1:]
+	return true
+}
+
+// keysPerBatch returns how many keys of the supplied primary key fit in
+// a single batch query. It's always a power of two, see batchSize.
+func keysPerBatch(pk schema.PrimaryKey) int {
+	size := 1
+	for size*2*len(pk.Fields) <= maxBatchQueryParams {
+		size *= 2
+	}
+	return size
+}
+
+// batchSize rounds the number of keys in a chunk up to a power of two, so
+// that only a handful of distinct statements are ever prepared per table.
+func batchSize(numKeys int) int {
+	size := 1
+	for size < numKeys {
+		size *= 2
+	}
+	return size
+}
+
+// batchArgs flattens the keys into query parameters, padding out to size
+// keys by repeating the first one. Duplicate keys are harmless in an IN
+// list.
+func batchArgs(keys [][]interface{}, size int) []interface{} {
+	args := make([]interface{}, 0, size*len(keys[0]))
+	for i := 0; i < size; i++ {
+		key := keys[0]
+		if i < len(keys) {
+			key = keys[i]
+		}
+		args = append(args, key...)
+	}
+	return args
+}
+
+// keyBatchQueryTokens builds the WHERE clause of a batch lookup of size
+// keys, matching the primary key against a list of row values.
+func keyBatchQueryTokens(pk schema.PrimaryKey, size int) []string {
+	params := "(" + strings.TrimSuffix(strings.Repeat("?,", len(pk.Fields)), ",") + ")"
+	values := strings.TrimSuffix(strings.Repeat(params+",", size), ",")
+	return []string{
+		"WHERE",
+		"(" + strings.Join(pk.Strings(), ",") + ")",
+		"IN",
+		"(VALUES " + values + ")",
+	}
+}
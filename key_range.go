@@ -0,0 +1,200 @@
+package ctlstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/segmentio/ctlstore/pkg/scanfunc"
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/segmentio/errors-go"
+)
+
+// KeyRangeOpts controls how GetRowsByKeyRange scans a table.
+type KeyRangeOpts struct {
+	// FromExclusive excludes rows matching the from bound.
+	FromExclusive bool
+	// ToExclusive excludes rows matching the to bound.
+	ToExclusive bool
+	// Limit is the maximum number of rows to return. Zero means no limit.
+	Limit int
+	// Descending returns rows in descending primary key order.
+	Descending bool
+	// Cursor continues a previous scan. It should be the value returned by
+	// Rows.Cursor from the previous page, and the table, bounds and the rest
+	// of the options other than the limit should be the same as the ones
+	// used to fetch that page, or ErrInvalidCursor is returned.
+	Cursor string
+}
+
+// keyRangeShape describes the parts of a key range scan that change the
+// generated SQL, so that statements can be cached per shape.
+type keyRangeShape struct {
+	ranged        bool
+	numFrom       int
+	numTo         int
+	fromExclusive bool
+	toExclusive   bool
+	descending    bool
+	limited       bool
+}
+
+var ErrInvalidCursor = errors.New("invalid key range cursor")
+
+// keyRangeState tracks the progress of a key range scan so that Rows can
+// produce a continuation cursor once iteration has finished.
+type keyRangeState struct {
+	pk      schema.PrimaryKey
+	scan    string // see keyRangeScanID
+	limit   int
+	count   int
+	targets []interface{} // scans only the PK columns of the current row
+	keys    []*keyCapture // the PK values of the current row, in PK order
+	more    bool          // whether rows remain beyond the limit
+	err     error
+}
+
+// keyCapture implements sql.Scanner, holding onto a copy of the value so
+// that it survives the underlying row being advanced.
+type keyCapture struct {
+	binary bool
+	val    interface{}
+}
+
+func (k *keyCapture) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []byte:
+		if k.binary {
+			k.val = append([]byte(nil), src...)
+		} else {
+			// sqlite returns a []byte for string columns
+			k.val = string(src)
+		}
+	default:
+		k.val = src
+	}
+	return nil
+}
+
+func newKeyRangeState(pk schema.PrimaryKey, cols []schema.DBColumnMeta, limit int, scan string) (*keyRangeState, error) {
+	state := &keyRangeState{
+		pk:      pk,
+		scan:    scan,
+		limit:   limit,
+		targets: make([]interface{}, len(cols)),
+		keys:    make([]*keyCapture, len(pk.Fields)),
+	}
+	noop := &scanfunc.NoOpScanner{}
+	for i := range state.targets {
+		state.targets[i] = noop
+	}
+	for i, field := range pk.Fields {
+		found := false
+		for j, col := range cols {
+			if col.Name == field.Name {
+				ft := pk.Types[i]
+				state.keys[i] = &keyCapture{binary: ft == schema.FTBinary || ft == schema.FTByteString}
+				state.targets[j] = state.keys[i]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("primary key column '%s' not found in results", field.Name)
+		}
+	}
+	return state, nil
+}
+
+// next advances rows, stopping once the limit has been reached and
+// remembering the key of each row to build a cursor from later.
+func (s *keyRangeState) next(rows rowSource) bool {
+	if s.limit > 0 && s.count >= s.limit {
+		// one extra row was requested to find out if there's another page
+		s.more = rows.Next()
+		return false
+	}
+	if !rows.Next() {
+		return false
+	}
+	s.count++
+	if err := rows.Scan(s.targets...); err != nil {
+		s.err = errors.Wrap(err, "scan key columns")
+		return false
+	}
+	return true
+}
+
+// keyRangeCursor is what a cursor encodes: the key of the last row that was
+// read, along with the scan it was read by, so that the cursor can't be used
+// to continue a different one.
+type keyRangeCursor struct {
+	Scan string        `json:"s"`
+	Key  []interface{} `json:"k"`
+}
+
+// keyRangeScanID identifies the table, bounds and direction of a scan.
+func keyRangeScanID(ldbTable string, from []interface{}, to []interface{}, opts KeyRangeOpts) (string, error) {
+	b, err := json.Marshal([]interface{}{ldbTable, from, to, opts.FromExclusive, opts.ToExclusive, opts.Descending})
+	if err != nil {
+		return "", errors.Wrap(err, "encode key range")
+	}
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:12]), nil
+}
+
+// cursor encodes the key of the last row that was read.
+func (s *keyRangeState) cursor() (string, error) {
+	if !s.more {
+		return "", nil
+	}
+	c := keyRangeCursor{Scan: s.scan, Key: make([]interface{}, len(s.keys))}
+	for i, k := range s.keys {
+		c.Key[i] = k.val
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", errors.Wrap(err, "encode cursor")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeKeyRangeCursor converts a cursor of the identified scan back into
+// the full primary key of the row it was created from.
+func decodeKeyRangeCursor(pk schema.PrimaryKey, scan string, cursor string) ([]interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var c keyRangeCursor
+	if err := dec.Decode(&c); err != nil || c.Scan != scan || len(c.Key) != len(pk.Fields) {
+		return nil, ErrInvalidCursor
+	}
+	key := c.Key
+	for i, k := range key {
+		switch k := k.(type) {
+		case json.Number:
+			if n, err := k.Int64(); err == nil {
+				key[i] = n
+			} else if f, err := k.Float64(); err == nil {
+				key[i] = f
+			} else {
+				return nil, ErrInvalidCursor
+			}
+		case string:
+			switch pk.Types[i] {
+			case schema.FTBinary, schema.FTByteString:
+				// binary keys were base64 encoded by encoding/json
+				raw, err := base64.StdEncoding.DecodeString(k)
+				if err != nil {
+					return nil, ErrInvalidCursor
+				}
+				key[i] = raw
+			}
+		}
+	}
+	return key, nil
+}
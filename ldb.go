@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/segmentio/ctlstore/pkg/ldb"
 	"github.com/segmentio/ctlstore/pkg/sqlite"
@@ -33,9 +34,12 @@ func init() {
 }
 
 // ReaderForPath opens an LDB at the provided path and returns an LDBReader
-// instance pointed at that LDB.
-func ReaderForPath(path string) (*LDBReader, error) {
-	_, err := os.Stat(path)
+// instance pointed at that LDB, configured with the supplied options.
+//
+// If the file at the path is later replaced, e.g. because the reflector
+// bootstrapped a new LDB, the reader will reopen it on a subsequent read.
+func ReaderForPath(path string, opts ...ReaderOpt) (*LDBReader, error) {
+	fi, err := os.Stat(path)
 	switch {
 	case os.IsNotExist(err):
 		return nil, fmt.Errorf("no LDB found at %s", path)
@@ -43,16 +47,30 @@ func ReaderForPath(path string) (*LDBReader, error) {
 		return nil, err
 	}
 
-	mode := "ro"
-	if !globalLDBReadOnly {
-		mode = "rwc"
-	}
-
-	ldb, err := ldb.OpenLDB(path, mode)
+	ldb, err := ldb.OpenLDB(path, ldbOpenMode())
 	if err != nil {
 		return nil, err
 	}
-	return &LDBReader{Db: ldb}, nil
+	reader := &LDBReader{
+		Db:                ldb,
+		path:              path,
+		changelogPath:     changelogPathFor(path),
+		fileInfo:          fi,
+		watch:             newLDBWatch(path),
+		lastReplacedCheck: time.Now().UnixNano(),
+	}
+	for _, opt := range opts {
+		opt(reader)
+	}
+	reader.startCache()
+	return reader, nil
+}
+
+func ldbOpenMode() string {
+	if globalLDBReadOnly {
+		return "ro"
+	}
+	return "rwc"
 }
 
 // Reader returns an LDBReader that can be used globally.
@@ -3,6 +3,7 @@ package ctlstore
 import (
 	"context"
 	"database/sql"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"github.com/segmentio/ctlstore/pkg/schema"
 	"github.com/segmentio/ctlstore/pkg/sqlgen"
 	"github.com/segmentio/errors-go"
+	"github.com/segmentio/events"
 	"github.com/segmentio/stats/v4"
 )
 
@@ -20,8 +22,17 @@ import (
 // thread-safe and it is safe to create as many of these as needed
 // across multiple processes.
 type LDBReader struct {
+	lastReplacedCheck           int64 // unix nanos, accessed atomically so kept 64-bit aligned
 	Db                          *sql.DB
+	path                        string                       // only set when opened with ReaderForPath
+	changelogPath               string                       // only set when opened with ReaderForPath
+	fileInfo                    os.FileInfo                  // of the LDB file that Db has open, only set when opened with ReaderForPath
+	watch                       *ldbWatch                    // only set when opened with ReaderForPath
+	maxLatency                  *latencyBound                // only set when opened WithMaxLatency
+	openTxs                     int32                        // number of ReadTx calls in progress, accessed atomically
+	cache                       *rowCache                    // only set when opened WithCache
 	pkCache                     map[string]schema.PrimaryKey // keyed by ldbTableName()
+	indexCache                  map[string]schema.PrimaryKey // keyed by LDBIndexName()
 	getRowByKeyStmtCache        map[string]*sql.Stmt         // keyed by ldbTableName()
 	getRowsByKeyPrefixStmtCache map[prefixCacheKey]*sql.Stmt
 	mu                          sync.RWMutex
@@ -30,46 +41,150 @@ type LDBReader struct {
 type prefixCacheKey struct {
 	ldbTableName string
 	numKeys      int
+	keyRange     keyRangeShape // zero value for prefix scans
+	batchSize    int           // number of keys in a batch lookup
+	indexName    string        // set for secondary index lookups
 }
 
 var (
 	ErrTableHasNoPrimaryKey = errors.New("Table provided has no primary key")
 	ErrNeedFullKey          = errors.New("All primary key fields are required")
 	ErrNoLedgerUpdates      = errors.New("no ledger updates have been received yet")
+	ErrIndexNotFound        = errors.New("Index not found")
+	ErrTableNotFound        = errors.New("Table not found")
+)
+
+var (
+	// waitForSequencePollInterval is how often WaitForSequence checks the
+	// LDB when it has no way of being notified of changes.
+	waitForSequencePollInterval = 100 * time.Millisecond
+	// waitForSequenceWatchInterval is how often WaitForSequence checks the
+	// LDB when it is being notified of changes, just in case a notification
+	// was missed.
+	waitForSequenceWatchInterval = time.Second
 )
 
 // Constructs an LDBReader from a sql.DB. Really only useful for testing.
-func NewLDBReaderFromDB(db *sql.DB) *LDBReader {
-	return &LDBReader{Db: db}
+func NewLDBReaderFromDB(db *sql.DB, opts ...ReaderOpt) *LDBReader {
+	reader := &LDBReader{Db: db}
+	for _, opt := range opts {
+		opt(reader)
+	}
+	reader.startCache()
+	return reader
 }
 
 // GetLastSequence returns the highest sequence number applied to the DB
 func (reader *LDBReader) GetLastSequence(ctx context.Context) (schema.DMLSequence, error) {
+	reader.reopenIfReplaced()
 	reader.mu.RLock()
 	defer reader.mu.RUnlock()
-	return ldb.FetchSeqFromLdb(ctx, reader.Db)
+	seq, err := ldb.FetchSeqFromLdb(ctx, reader.Db)
+	if err == nil && reader.cache != nil {
+		// later reads mustn't be served from a cache which trails this
+		reader.cache.observe(seq.Int())
+	}
+	return seq, err
+}
+
+// WaitForSequence blocks until the LDB has applied at least the supplied
+// sequence, or until the context is done, in which case the context's
+// error is returned. This is useful for reading your own writes after
+// mutating through the executive.
+//
+// If the reader was opened with ReaderForPath, the LDB and its WAL are
+// watched for changes instead of polling.
+func (reader *LDBReader) WaitForSequence(ctx context.Context, seq schema.DMLSequence) error {
+	start := time.Now()
+	defer func() {
+		globalstats.Observe("wait_for_sequence", time.Now().Sub(start))
+	}()
+
+	// Subscribe to the watch before checking the sequence so that a change
+	// which lands in between the two can't be missed.
+	var notifyCh <-chan struct{}
+	interval := waitForSequencePollInterval
+	if reader.watch != nil {
+		ch, unsubscribe, err := reader.watch.subscribe()
+		if err != nil {
+			events.Log("Could not watch LDB, falling back to polling: %{error}+v", err)
+		} else {
+			defer unsubscribe()
+			notifyCh = ch
+			interval = waitForSequenceWatchInterval
+		}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		current, err := reader.GetLastSequence(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				// the query was interrupted by the context
+				return ctx.Err()
+			}
+			return errors.Wrap(err, "get last sequence")
+		}
+		if current >= seq {
+			return nil
+		}
+		latency, err := reader.GetLedgerLatency(ctx)
+		if err == nil {
+			globalstats.Observe("wait_for_sequence_ledger_latency", latency)
+		}
+		select {
+		case <-notifyCh:
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
 
 // GetLedgerLatency returns the difference between the current time and the timestamp
 // from the last DML ledger update processed by the reflector. ErrNoLedgerUpdates will
 // be returned if no DML statements have been processed.
 func (reader *LDBReader) GetLedgerLatency(ctx context.Context) (time.Duration, error) {
+	reader.reopenIfReplaced()
+	reader.mu.RLock()
+	defer reader.mu.RUnlock()
+
+	timestamp, err := reader.getLastLedgerUpdate(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return time.Now().Sub(timestamp), nil
+}
+
+// WARNING: assumes mutex is read locked
+func (reader *LDBReader) getLastLedgerUpdate(ctx context.Context) (time.Time, error) {
 	row := reader.Db.QueryRowContext(ctx, "select timestamp from "+ldb.LDBLastUpdateTableName+" where name=?", ldb.LDBLastLedgerUpdateColumn)
 	var timestamp time.Time
 	err := row.Scan(&timestamp)
 	switch {
 	case err == sql.ErrNoRows:
-		return 0, ErrNoLedgerUpdates
+		return time.Time{}, ErrNoLedgerUpdates
 	case err != nil:
-		return 0, errors.Wrap(err, "get ledger latency")
+		return time.Time{}, errors.Wrap(err, "get ledger latency")
 	default:
-		return time.Now().Sub(timestamp), nil
+		return timestamp, nil
 	}
 }
 
 // GetRowsByKeyPrefix returns a *Rows iterator that will supply all of the rows in
 // the family and table match the supplied primary key prefix.
 func (reader *LDBReader) GetRowsByKeyPrefix(ctx context.Context, familyName string, tableName string, key ...interface{}) (*Rows, error) {
+	reader.reopenIfReplaced()
+	return reader.getRowsByKeyPrefix(ctx, nil, familyName, tableName, key...)
+}
+
+// getRowsByKeyPrefix implements GetRowsByKeyPrefix, querying within tx if
+// it's not nil.
+func (reader *LDBReader) getRowsByKeyPrefix(ctx context.Context, tx *sql.Tx, familyName string, tableName string, key ...interface{}) (*Rows, error) {
 	start := time.Now()
 	defer func() {
 		globalstats.Observe("get_rows_by_key_prefix", time.Now().Sub(start),
@@ -79,6 +194,9 @@ func (reader *LDBReader) GetRowsByKeyPrefix(ctx context.Context, familyName stri
 
 	reader.mu.RLock()
 	defer reader.mu.RUnlock()
+	if err := reader.checkLatency(ctx, familyName, tableName); err != nil {
+		return nil, err
+	}
 	famName, err := schema.NewFamilyName(familyName)
 	if err != nil {
 		return nil, err
@@ -102,10 +220,14 @@ func (reader *LDBReader) GetRowsByKeyPrefix(ctx context.Context, familyName stri
 	if err != nil {
 		return nil, err
 	}
-	stmt, err := reader.getRowsByKeyPrefixStmt(ctx, pk, ldbTable, len(key))
+	pck := prefixCacheKey{ldbTableName: ldbTable, numKeys: len(key)}
+	stmt, err := reader.getRowsByKeyPrefixStmt(ctx, pk, pck)
 	if err != nil {
 		return nil, err
 	}
+	if tx != nil {
+		stmt = tx.StmtContext(ctx, stmt)
+	}
 	if len(key) == 0 {
 		globalstats.Incr("full-table-scans", familyName, tableName)
 	}
@@ -125,6 +247,288 @@ func (reader *LDBReader) GetRowsByKeyPrefix(ctx context.Context, familyName stri
 	}
 }
 
+// GetRowsByKeyRange returns a *Rows iterator that will supply the rows in
+// the family and table whose primary key falls between the from and to
+// bounds, ordered by primary key. Each bound may be a prefix of the primary
+// key, and a nil bound leaves that side of the range open.
+//
+// Large ranges can be paged through by setting a Limit in the opts, and
+// passing the Rows.Cursor of each page as the Cursor for the next one.
+func (reader *LDBReader) GetRowsByKeyRange(ctx context.Context, familyName string, tableName string, from []interface{}, to []interface{}, opts KeyRangeOpts) (*Rows, error) {
+	start := time.Now()
+	defer func() {
+		globalstats.Observe("get_rows_by_key_range", time.Now().Sub(start),
+			stats.T("family", familyName),
+			stats.T("table", tableName))
+	}()
+
+	reader.reopenIfReplaced()
+	reader.mu.RLock()
+	defer reader.mu.RUnlock()
+	if err := reader.checkLatency(ctx, familyName, tableName); err != nil {
+		return nil, err
+	}
+	famName, err := schema.NewFamilyName(familyName)
+	if err != nil {
+		return nil, err
+	}
+	tblName, err := schema.NewTableName(tableName)
+	if err != nil {
+		return nil, err
+	}
+	ldbTable := schema.LDBTableName(famName, tblName)
+	pk, err := reader.getPrimaryKey(ctx, ldbTable)
+	if err != nil {
+		return nil, err
+	}
+	if pk.Zero() {
+		return nil, ErrTableHasNoPrimaryKey
+	}
+	if len(from) > len(pk.Fields) || len(to) > len(pk.Fields) {
+		return nil, errors.New("too many keys supplied for table's primary key")
+	}
+	if opts.Limit < 0 {
+		return nil, errors.New("limit must not be negative")
+	}
+
+	// copy the bounds since converting the keys modifies them in place
+	from = append([]interface{}(nil), from...)
+	to = append([]interface{}(nil), to...)
+	scan, err := keyRangeScanID(ldbTable, from, to, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Cursor != "" {
+		// the cursor is the full key of the last row that was returned, so
+		// it replaces the bound that the scan is moving away from.
+		last, err := decodeKeyRangeCursor(pk, scan, opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Descending {
+			to, opts.ToExclusive = last, true
+		} else {
+			from, opts.FromExclusive = last, true
+		}
+	}
+	if err = convertKeyBeforeQuery(pk, from); err != nil {
+		return nil, err
+	}
+	if err = convertKeyBeforeQuery(pk, to); err != nil {
+		return nil, err
+	}
+
+	pck := prefixCacheKey{
+		ldbTableName: ldbTable,
+		keyRange: keyRangeShape{
+			ranged:        true,
+			numFrom:       len(from),
+			numTo:         len(to),
+			fromExclusive: opts.FromExclusive,
+			toExclusive:   opts.ToExclusive,
+			descending:    opts.Descending,
+			limited:       opts.Limit > 0,
+		},
+	}
+	stmt, err := reader.getRowsByKeyPrefixStmt(ctx, pk, pck)
+	if err != nil {
+		return nil, err
+	}
+	if len(from) == 0 && len(to) == 0 {
+		globalstats.Incr("full-table-scans", familyName, tableName)
+	}
+	args := append(from, to...)
+	if opts.Limit > 0 {
+		// fetch one extra row to find out if there's another page
+		args = append(args, opts.Limit+1)
+	}
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	cols, err := schema.DBColumnMetaFromRows(rows)
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	keyRange, err := newKeyRangeState(pk, cols, opts.Limit, scan)
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	return &Rows{rows: rows, cols: cols, keyRange: keyRange}, nil
+}
+
+// GetRowsByKeys returns a *Rows iterator that will supply the rows in the
+// family and table matching any of the supplied full primary keys, in no
+// particular order. Keys that aren't found are skipped.
+//
+// This is much cheaper than calling GetRowByKey for each key, as the keys
+// are looked up in as few queries as possible.
+func (reader *LDBReader) GetRowsByKeys(ctx context.Context, familyName string, tableName string, keys [][]interface{}) (*Rows, error) {
+	start := time.Now()
+	defer func() {
+		globalstats.Observe("get_rows_by_keys", time.Now().Sub(start),
+			stats.T("family", familyName),
+			stats.T("table", tableName))
+	}()
+
+	reader.reopenIfReplaced()
+	reader.mu.RLock()
+	defer reader.mu.RUnlock()
+	if err := reader.checkLatency(ctx, familyName, tableName); err != nil {
+		return nil, err
+	}
+	famName, err := schema.NewFamilyName(familyName)
+	if err != nil {
+		return nil, err
+	}
+	tblName, err := schema.NewTableName(tableName)
+	if err != nil {
+		return nil, err
+	}
+	ldbTable := schema.LDBTableName(famName, tblName)
+	pk, err := reader.getPrimaryKey(ctx, ldbTable)
+	if err != nil {
+		return nil, err
+	}
+	if pk.Zero() {
+		return nil, ErrTableHasNoPrimaryKey
+	}
+	if len(keys) == 0 {
+		return &Rows{}, nil
+	}
+
+	// copy the keys since converting them modifies them in place
+	converted := make([][]interface{}, len(keys))
+	for i, key := range keys {
+		if len(key) != len(pk.Fields) {
+			return nil, ErrNeedFullKey
+		}
+		converted[i] = append([]interface{}(nil), key...)
+		if err = convertKeyBeforeQuery(pk, converted[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	perBatch := keysPerBatch(pk)
+	var chunks [][][]interface{}
+	var sizes []int
+	for len(converted) > 0 {
+		chunk := converted
+		if len(chunk) > perBatch {
+			chunk = chunk[:perBatch]
+		}
+		converted = converted[len(chunk):]
+		chunks = append(chunks, chunk)
+		sizes = append(sizes, batchSize(len(chunk)))
+	}
+	stmts, err := reader.getKeyBatchStmts(ctx, pk, ldbTable, sizes)
+	if err != nil {
+		return nil, err
+	}
+	batches := make([]keyBatch, len(chunks))
+	for i, chunk := range chunks {
+		batches[i] = keyBatch{stmt: stmts[sizes[i]], args: batchArgs(chunk, sizes[i])}
+	}
+
+	rows, err := batches[0].stmt.QueryContext(ctx, batches[0].args...)
+	if err != nil {
+		return nil, err
+	}
+	cols, err := schema.DBColumnMetaFromRows(rows)
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	// the rest of the chunks are read while the lock is still held, since
+	// their statements may be closed by a reopen once it's released
+	pending := make([]rowSource, 0, len(batches)-1)
+	for _, batch := range batches[1:] {
+		vals, err := batch.read(ctx, len(cols))
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		pending = append(pending, vals)
+	}
+	return &Rows{
+		rows:    rows,
+		cols:    cols,
+		batches: &keyBatchState{pending: pending},
+	}, nil
+}
+
+// GetRowsByIndex returns a *Rows iterator that will supply all of the rows in
+// the family and table which match the supplied values of the named secondary
+// index. As with GetRowsByKeyPrefix, the values may be a prefix of the fields
+// that make up the index.
+func (reader *LDBReader) GetRowsByIndex(ctx context.Context, familyName string, tableName string, indexName string, values ...interface{}) (*Rows, error) {
+	start := time.Now()
+	defer func() {
+		globalstats.Observe("get_rows_by_index", time.Now().Sub(start),
+			stats.T("family", familyName),
+			stats.T("table", tableName),
+			stats.T("index", indexName))
+	}()
+
+	reader.reopenIfReplaced()
+	reader.mu.RLock()
+	defer reader.mu.RUnlock()
+	if err := reader.checkLatency(ctx, familyName, tableName); err != nil {
+		return nil, err
+	}
+	famName, err := schema.NewFamilyName(familyName)
+	if err != nil {
+		return nil, err
+	}
+	tblName, err := schema.NewTableName(tableName)
+	if err != nil {
+		return nil, err
+	}
+	idxName, err := schema.NewIndexName(indexName)
+	if err != nil {
+		return nil, err
+	}
+	ldbTable := schema.LDBTableName(famName, tblName)
+	ldbIndex := schema.LDBIndexName(famName, tblName, idxName)
+	idx, err := reader.getIndexKey(ctx, ldbTable, ldbIndex)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, errors.New("at least one index value must be supplied")
+	}
+	if len(values) > len(idx.Fields) {
+		return nil, errors.New("too many values supplied for index")
+	}
+	err = convertKeyBeforeQuery(idx, values)
+	if err != nil {
+		return nil, err
+	}
+	pck := prefixCacheKey{ldbTableName: ldbTable, numKeys: len(values), indexName: ldbIndex}
+	stmt, err := reader.getRowsByKeyPrefixStmt(ctx, idx, pck)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, values...)
+	switch {
+	case err == nil:
+		cols, err := schema.DBColumnMetaFromRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		res := &Rows{rows: rows, cols: cols}
+		return res, nil
+	case err == sql.ErrNoRows:
+		return &Rows{}, nil
+	default:
+		// the index may have been dropped and re-created differently
+		reader.invalidateIndexCache(ldbIndex) // assumes RLock is held
+		return nil, err
+	}
+}
+
 // GetRowByKey fetches a row from the supplied table by the key parameter,
 // filling the data into the out param.
 //
@@ -132,6 +536,11 @@ func (reader *LDBReader) GetRowsByKeyPrefix(ctx context.Context, familyName stri
 //    * pointer to struct
 //    * map[string]interface{}
 //
+// Struct fields are mapped to columns with the `ctlstore:"col"` tag. Fields
+// which implement sql.Scanner are decoded with it, fields which implement
+// encoding.TextUnmarshaler are decoded with it from text columns, and
+// fields tagged `ctlstore:"col,json"` are decoded from JSON.
+//
 // The key parameter can support composite keys by passing a slice type.
 func (reader *LDBReader) GetRowByKey(
 	ctx context.Context,
@@ -139,6 +548,19 @@ func (reader *LDBReader) GetRowByKey(
 	familyName string,
 	tableName string,
 	key ...interface{},
+) (found bool, err error) {
+	reader.reopenIfReplaced()
+	return reader.getRowByKey(ctx, nil, out, familyName, tableName, key...)
+}
+
+// getRowByKey implements GetRowByKey, querying within tx if it's not nil.
+func (reader *LDBReader) getRowByKey(
+	ctx context.Context,
+	tx *sql.Tx,
+	out interface{},
+	familyName string,
+	tableName string,
+	key ...interface{},
 ) (found bool, err error) {
 	start := time.Now()
 	defer func() {
@@ -149,6 +571,9 @@ func (reader *LDBReader) GetRowByKey(
 
 	reader.mu.RLock()
 	defer reader.mu.RUnlock()
+	if err := reader.checkLatency(ctx, familyName, tableName); err != nil {
+		return false, err
+	}
 
 	famName, err := schema.NewFamilyName(familyName)
 	if err != nil {
@@ -197,6 +622,22 @@ func (reader *LDBReader) GetRowByKey(
 		return
 	}
 
+	var cacheKey string
+	var cacheEpoch int64
+	cacheable := false
+	if tx != nil {
+		stmt = tx.StmtContext(ctx, stmt)
+	} else {
+		var entry *rowCacheEntry
+		cacheKey, entry, cacheEpoch, cacheable = reader.getCachedRowByKey(ldbTable, pk, key)
+		if entry != nil {
+			globalstats.Incr("row-cache-hits", familyName, tableName)
+			return entry.scan(out)
+		}
+		if cacheable {
+			globalstats.Incr("row-cache-misses", familyName, tableName)
+		}
+	}
 	rows, err := stmt.QueryContext(ctx, key...)
 	if err == sql.ErrNoRows {
 		found = false
@@ -225,9 +666,20 @@ func (reader *LDBReader) GetRowByKey(
 	if !rows.Next() {
 		// found is already false by default
 		err = rows.Err()
+		if err == nil && cacheable {
+			reader.cache.add(&rowCacheEntry{key: cacheKey}, cacheEpoch)
+		}
 		return
 	}
 
+	if cacheable {
+		entry, err := captureRow(cacheKey, cols, rows)
+		if err != nil {
+			return false, errors.Wrap(err, "target row scan error")
+		}
+		reader.cache.add(entry, cacheEpoch)
+	}
+
 	found = true
 	err = scanFunc(rows)
 
@@ -241,6 +693,13 @@ func (reader *LDBReader) GetRowByKey(
 }
 
 func (reader *LDBReader) Close() error {
+	if reader.cache != nil && reader.cache.cancel != nil {
+		reader.cache.cancel()
+	}
+	if reader.watch != nil {
+		reader.watch.close()
+	}
+
 	reader.mu.Lock()
 	defer reader.mu.Unlock()
 
@@ -256,6 +715,7 @@ func (reader *LDBReader) Close() error {
 
 // Ping checks if the LDB is available
 func (reader *LDBReader) Ping(ctx context.Context) bool {
+	reader.reopenIfReplaced()
 	reader.mu.RLock()
 	defer reader.mu.RUnlock()
 
@@ -368,7 +828,7 @@ func (reader *LDBReader) getPrimaryKey(ctx context.Context, ldbTable string) (sc
 			_, err := reader.Db.ExecContext(ctx, qs)
 			if err != nil {
 				if strings.Index(err.Error(), "no such table:") == 0 {
-					return schema.PrimaryKeyZero, errors.New("Table not found")
+					return schema.PrimaryKeyZero, ErrTableNotFound
 				}
 				return schema.PrimaryKeyZero, err
 			}
@@ -391,7 +851,121 @@ func (reader *LDBReader) getPrimaryKey(ctx context.Context, ldbTable string) (sc
 	return reader.pkCache[ldbTable], nil
 }
 
-func (reader *LDBReader) getRowsByKeyPrefixStmt(ctx context.Context, pk schema.PrimaryKey, ldbTable string, numKeys int) (*sql.Stmt, error) {
+// WARNING: assumes mutex is read locked
+func (reader *LDBReader) invalidateIndexCache(ldbIndex string) {
+	if reader.indexCache == nil {
+		return
+	}
+
+	reader.mu.RUnlock()
+	reader.mu.Lock()
+	delete(reader.indexCache, ldbIndex)
+	reader.mu.Unlock()
+	reader.mu.RLock()
+}
+
+// getIndexKey returns the fields of a secondary index in index order. They're
+// returned as a PrimaryKey so that index lookups can share the key conversion
+// and statement building used for primary key lookups.
+//
+// WARNING: assumes mutex is read locked
+func (reader *LDBReader) getIndexKey(ctx context.Context, ldbTable string, ldbIndex string) (schema.PrimaryKey, error) {
+	if reader.indexCache == nil {
+		reader.mu.RUnlock()
+		reader.mu.Lock()
+
+		// double check because there could be a race which would result
+		// in us wiping out the cache
+		if reader.indexCache == nil {
+			reader.indexCache = make(map[string]schema.PrimaryKey)
+		}
+
+		reader.mu.Unlock()
+		reader.mu.RLock()
+	}
+
+	if idx, found := reader.indexCache[ldbIndex]; found {
+		return idx, nil
+	}
+
+	// joining against the table info both supplies the column types and
+	// ensures that the index belongs to the table.
+	const qs = "SELECT ii.name, ti.type FROM pragma_index_info(?) ii " +
+		"JOIN pragma_table_info(?) ti ON ii.name = ti.name ORDER BY ii.seqno ASC"
+	rows, err := reader.Db.QueryContext(ctx, qs, ldbIndex, ldbTable)
+	if err != nil {
+		return schema.PrimaryKeyZero, errors.Wrap(err, "query pragma_index_info error")
+	}
+	defer rows.Close()
+
+	rawFieldNames := []string{}
+	rawFieldTypes := []string{}
+	for rows.Next() {
+		var name string
+		var ftString string
+		err = rows.Scan(&name, &ftString)
+		if err != nil {
+			return schema.PrimaryKeyZero, errors.WithStack(err)
+		}
+		rawFieldNames = append(rawFieldNames, name)
+		rawFieldTypes = append(rawFieldTypes, ftString)
+	}
+	err = rows.Err()
+	if err != nil {
+		return schema.PrimaryKeyZero, errors.WithStack(err)
+	}
+
+	idx, err := schema.NewPKFromRawNamesAndTypes(rawFieldNames, rawFieldTypes)
+	if err != nil {
+		return schema.PrimaryKeyZero, err
+	}
+	if idx.Zero() {
+		// not cached, so that an index which is created later is found
+		return schema.PrimaryKeyZero, ErrIndexNotFound
+	}
+
+	reader.mu.RUnlock()
+	reader.mu.Lock()
+	reader.indexCache[ldbIndex] = idx
+	reader.mu.Unlock()
+	reader.mu.RLock()
+
+	return idx, nil
+}
+
+// getKeyBatchStmts returns the statements for batch lookups of each of the
+// sizes, all prepared against the same LDB. Preparing a statement drops the
+// lock, which lets a reopen close the statements prepared before it, so
+// they're looked up from the cache again once they've all been prepared,
+// and prepared again if they're no longer there.
+//
+// WARNING: assumes mutex is read locked
+func (reader *LDBReader) getKeyBatchStmts(ctx context.Context, pk schema.PrimaryKey, ldbTable string, sizes []int) (map[int]*sql.Stmt, error) {
+	for {
+		for _, size := range sizes {
+			pck := prefixCacheKey{ldbTableName: ldbTable, batchSize: size}
+			if _, err := reader.getRowsByKeyPrefixStmt(ctx, pk, pck); err != nil {
+				return nil, err
+			}
+		}
+		stmts := make(map[int]*sql.Stmt, len(sizes))
+		for _, size := range sizes {
+			pck := prefixCacheKey{ldbTableName: ldbTable, batchSize: size}
+			stmt, found := reader.getRowsByKeyPrefixStmtCache[pck]
+			if !found {
+				// the LDB was reopened
+				stmts = nil
+				break
+			}
+			stmts[size] = stmt
+		}
+		if stmts != nil {
+			return stmts, nil
+		}
+	}
+}
+
+func (reader *LDBReader) getRowsByKeyPrefixStmt(ctx context.Context, pk schema.PrimaryKey, pck prefixCacheKey) (*sql.Stmt, error) {
 	// assumes RLock is held
 	if reader.getRowsByKeyPrefixStmtCache == nil {
 		reader.mu.RUnlock()
@@ -404,7 +978,6 @@ func (reader *LDBReader) getRowsByKeyPrefixStmt(ctx context.Context, pk schema.P
 		reader.mu.Unlock()
 		reader.mu.RLock()
 	}
-	pck := prefixCacheKey{ldbTableName: ldbTable, numKeys: numKeys}
 	stmt, found := reader.getRowsByKeyPrefixStmtCache[pck]
 	if found {
 		return stmt, nil
@@ -417,11 +990,15 @@ func (reader *LDBReader) getRowsByKeyPrefixStmt(ctx context.Context, pk schema.P
 
 	qsTokens := []string{
 		"SELECT * FROM",
-		ldbTable,
+		pck.ldbTableName,
 	}
-	if numKeys > 0 {
+	if pck.keyRange.ranged {
+		qsTokens = append(qsTokens, keyRangeQueryTokens(pk, pck.keyRange)...)
+	} else if pck.batchSize > 0 {
+		qsTokens = append(qsTokens, keyBatchQueryTokens(pk, pck.batchSize)...)
+	} else if pck.numKeys > 0 {
 		qsTokens = append(qsTokens, "WHERE")
-		for i := 0; i < numKeys; i++ {
+		for i := 0; i < pck.numKeys; i++ {
 			pkField := pk.Fields[i]
 			if i > 0 {
 				qsTokens = append(qsTokens, "AND")
@@ -440,6 +1017,56 @@ func (reader *LDBReader) getRowsByKeyPrefixStmt(ctx context.Context, pk schema.P
 	return stmt, err
 }
 
+// keyRangeQueryTokens builds the WHERE, ORDER BY, and LIMIT clauses of a
+// key range scan. The bounds are compared as row values, so that a bound
+// made up of a prefix of the primary key works as expected.
+func keyRangeQueryTokens(pk schema.PrimaryKey, shape keyRangeShape) []string {
+	rowValue := func(numKeys int) (string, string) {
+		names := pk.Strings()[:numKeys]
+		params := strings.TrimSuffix(strings.Repeat("?,", numKeys), ",")
+		return "(" + strings.Join(names, ",") + ")", "(" + params + ")"
+	}
+	var conds []string
+	if shape.numFrom > 0 {
+		op := ">="
+		if shape.fromExclusive {
+			op = ">"
+		}
+		names, params := rowValue(shape.numFrom)
+		conds = append(conds, names, op, params)
+	}
+	if shape.numTo > 0 {
+		op := "<="
+		if shape.toExclusive {
+			op = "<"
+		}
+		if len(conds) > 0 {
+			conds = append(conds, "AND")
+		}
+		names, params := rowValue(shape.numTo)
+		conds = append(conds, names, op, params)
+	}
+
+	var tokens []string
+	if len(conds) > 0 {
+		tokens = append(tokens, "WHERE")
+		tokens = append(tokens, conds...)
+	}
+	dir := "ASC"
+	if shape.descending {
+		dir = "DESC"
+	}
+	orderBy := make([]string, len(pk.Fields))
+	for i, field := range pk.Fields {
+		orderBy[i] = field.Name + " " + dir
+	}
+	tokens = append(tokens, "ORDER BY", strings.Join(orderBy, ", "))
+	if shape.limited {
+		tokens = append(tokens, "LIMIT ?")
+	}
+	return tokens
+}
+
 func (reader *LDBReader) getGetRowByKeyStmt(ctx context.Context, pk schema.PrimaryKey, ldbTable string) (*sql.Stmt, error) {
 	// assumes RLock is held
 	if reader.getRowByKeyStmtCache == nil {
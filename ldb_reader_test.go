@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"fmt"
 	"io/ioutil"
+	"net"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
@@ -81,6 +83,95 @@ func TestGetLedgerLatency(t *testing.T) {
 	require.True(t, latency < time.Second, "weird latency: %v", latency)
 }
 
+func TestWaitForSequence(t *testing.T) {
+	for _, test := range []struct {
+		desc     string
+		usePath  bool
+		applyAt  schema.DMLSequence
+		waitFor  schema.DMLSequence
+		expected error
+	}{
+		{
+			desc:    "sequence reached with watch",
+			usePath: true,
+			applyAt: 2,
+			waitFor: 2,
+		},
+		{
+			desc:    "sequence reached with polling",
+			applyAt: 2,
+			waitFor: 2,
+		},
+		{
+			desc:    "sequence already passed",
+			usePath: true,
+			applyAt: 1,
+			waitFor: 1,
+		},
+		{
+			desc:     "sequence never reached",
+			usePath:  true,
+			applyAt:  2,
+			waitFor:  3,
+			expected: context.DeadlineExceeded,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			dbPath, teardown := ldb.NewLDBTmpPath(t)
+			defer teardown()
+			db, err := ldb.OpenLDB(dbPath, "rwc")
+			require.NoError(t, err)
+			defer db.Close()
+			require.NoError(t, ldb.EnsureLdbInitialized(ctx, db))
+
+			writer := ldbwriter.SqlLdbWriter{Db: db}
+			err = writer.ApplyDMLStatement(ctx, schema.DMLStatement{
+				Sequence:  1,
+				Statement: "CREATE TABLE foo___bar (key VARCHAR PRIMARY KEY)",
+				Timestamp: time.Now(),
+			})
+			require.NoError(t, err)
+
+			reader := &LDBReader{Db: db}
+			if test.usePath {
+				reader, err = ReaderForPath(dbPath)
+				require.NoError(t, err)
+				defer reader.Close()
+			}
+
+			applied := make(chan struct{})
+			go func() {
+				defer close(applied)
+				time.Sleep(100 * time.Millisecond)
+				for seq := schema.DMLSequence(2); seq <= test.applyAt; seq++ {
+					err := writer.ApplyDMLStatement(ctx, schema.DMLStatement{
+						Sequence:  seq,
+						Statement: fmt.Sprintf("INSERT INTO foo___bar VALUES('%d')", seq),
+						Timestamp: time.Now(),
+					})
+					if err != nil {
+						t.Error(err)
+					}
+				}
+			}()
+
+			waitCtx, waitCancel := context.WithTimeout(ctx, time.Second)
+			defer waitCancel()
+			err = reader.WaitForSequence(waitCtx, test.waitFor)
+			<-applied
+			require.Equal(t, test.expected, err)
+			if test.expected == nil {
+				seq, err := reader.GetLastSequence(ctx)
+				require.NoError(t, err)
+				require.True(t, seq >= test.waitFor, "seq %d < %d", seq, test.waitFor)
+			}
+		})
+	}
+}
+
 func TestLDBMustExistForReader(t *testing.T) {
 	oldGlobal := globalLDBPath
 	defer func() { globalLDBPath = oldGlobal }()
@@ -98,6 +189,71 @@ func TestLDBMustExistForReader(t *testing.T) {
 	require.Nil(t, r)
 }
 
+func TestReaderForPathReopensReplacedLDB(t *testing.T) {
+	oldInterval := ldbReplacedCheckInterval
+	defer func() { ldbReplacedCheckInterval = oldInterval }()
+	ldbReplacedCheckInterval = 0
+
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "ldb_replaced")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ldb.DefaultLDBFilename)
+
+	createLDB := func(path string, initSQL string) {
+		db, err := ldb.OpenLDB(path, "rwc")
+		require.NoError(t, err)
+		defer db.Close()
+		require.NoError(t, ldb.EnsureLdbInitialized(ctx, db))
+		_, err = db.Exec(initSQL)
+		require.NoError(t, err)
+	}
+	createLDB(path, `
+		CREATE TABLE foo___bar (key VARCHAR PRIMARY KEY, value VARCHAR);
+		INSERT INTO foo___bar VALUES ('a', 'old'), ('b', 'old');
+	`)
+
+	reader, err := ReaderForPath(path)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	var row testKVStruct
+	found, err := reader.GetRowByKey(ctx, &row, "foo", "bar", "a")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "old", row.Val)
+
+	// start a scan before the LDB is replaced, which should be unaffected
+	rows, err := reader.GetRowsByKeyPrefix(ctx, "foo", "bar")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	// the replacement has a different primary key, so the cached primary
+	// key and statements must not be used against it
+	createLDB(path+".tmp", `
+		CREATE TABLE foo___bar (id INTEGER, key VARCHAR, value VARCHAR, PRIMARY KEY (id, key));
+		INSERT INTO foo___bar VALUES (1, 'a', 'new');
+	`)
+	require.NoError(t, os.Rename(path+".tmp", path))
+
+	_, err = reader.GetRowByKey(ctx, &row, "foo", "bar", "a")
+	require.Equal(t, ErrNeedFullKey, err)
+	row = testKVStruct{}
+	found, err = reader.GetRowByKey(ctx, &row, "foo", "bar", 1, "a")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "new", row.Val)
+
+	var scanned []testKVStruct
+	for rows.Next() {
+		var row testKVStruct
+		require.NoError(t, rows.Scan(&row))
+		scanned = append(scanned, row)
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, []testKVStruct{{"a", "old"}, {"b", "old"}}, scanned)
+}
+
 func TestGetRowsByKeyPrefix(t *testing.T) {
 	type mrStruct struct {
 		K1  string `ctlstore:"k1"`
@@ -249,6 +405,501 @@ func TestGetRowsByKeyPrefix(t *testing.T) {
 
 }
 
+func TestGetRowsByKeyRange(t *testing.T) {
+	row := func(k1, k2 string, val int64) map[string]interface{} {
+		return map[string]interface{}{"k1": k1, "k2": k2, "val": val}
+	}
+	for _, test := range []struct {
+		desc     string
+		from     []interface{}
+		to       []interface{}
+		opts     KeyRangeOpts
+		err      error
+		expected []map[string]interface{}
+	}{
+		{
+			desc:     "unbounded",
+			expected: []map[string]interface{}{row("a", "A", 42), row("a", "B", 43), row("b", "B", 44)},
+		},
+		{
+			desc:     "unbounded descending",
+			opts:     KeyRangeOpts{Descending: true},
+			expected: []map[string]interface{}{row("b", "B", 44), row("a", "B", 43), row("a", "A", 42)},
+		},
+		{
+			desc:     "inclusive bounds",
+			from:     []interface{}{"a", "B"},
+			to:       []interface{}{"b", "B"},
+			expected: []map[string]interface{}{row("a", "B", 43), row("b", "B", 44)},
+		},
+		{
+			desc:     "exclusive bounds",
+			from:     []interface{}{"a", "A"},
+			to:       []interface{}{"b", "B"},
+			opts:     KeyRangeOpts{FromExclusive: true, ToExclusive: true},
+			expected: []map[string]interface{}{row("a", "B", 43)},
+		},
+		{
+			desc:     "prefix bounds",
+			from:     []interface{}{"a"},
+			to:       []interface{}{"a"},
+			expected: []map[string]interface{}{row("a", "A", 42), row("a", "B", 43)},
+		},
+		{
+			desc:     "exclusive prefix bound",
+			from:     []interface{}{"a"},
+			opts:     KeyRangeOpts{FromExclusive: true},
+			expected: []map[string]interface{}{row("b", "B", 44)},
+		},
+		{
+			desc:     "limit",
+			opts:     KeyRangeOpts{Limit: 2},
+			expected: []map[string]interface{}{row("a", "A", 42), row("a", "B", 43)},
+		},
+		{
+			desc: "empty range",
+			from: []interface{}{"c"},
+		},
+		{
+			desc: "too many keys supplied",
+			from: []interface{}{"a", "b", "c"},
+			err:  errors.New("too many keys supplied for table's primary key"),
+		},
+		{
+			desc: "invalid cursor",
+			opts: KeyRangeOpts{Cursor: "nope"},
+			err:  ErrInvalidCursor,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			ctx := context.Background()
+			db, teardown := ldb.LDBForTest(t)
+			defer teardown()
+			_, err := db.Exec(initSQLForReadKeyByRow)
+			require.NoError(t, err)
+
+			reader := LDBReader{Db: db}
+			rows, err := reader.GetRowsByKeyRange(ctx, "foo", "multirow", test.from, test.to, test.opts)
+			if test.err != nil {
+				require.EqualError(t, err, test.err.Error())
+				return
+			}
+			require.NoError(t, err)
+			defer rows.Close()
+			var res []map[string]interface{}
+			for rows.Next() {
+				out := map[string]interface{}{}
+				require.NoError(t, rows.Scan(out))
+				res = append(res, out)
+			}
+			require.NoError(t, rows.Err())
+			require.EqualValues(t, test.expected, res)
+		})
+	}
+}
+
+func TestGetRowsByKeyRangePagination(t *testing.T) {
+	for _, test := range []struct {
+		desc     string
+		table    string
+		opts     KeyRangeOpts
+		expected []interface{}
+	}{
+		{
+			desc:     "ascending",
+			table:    "multirow",
+			opts:     KeyRangeOpts{Limit: 1},
+			expected: []interface{}{int64(42), int64(43), int64(44)},
+		},
+		{
+			desc:     "descending",
+			table:    "multirow",
+			opts:     KeyRangeOpts{Limit: 2, Descending: true},
+			expected: []interface{}{int64(44), int64(43), int64(42)},
+		},
+		{
+			desc:     "binary keys",
+			table:    "varbinarykey",
+			opts:     KeyRangeOpts{Limit: 1},
+			expected: []interface{}{"moo", "baa"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			ctx := context.Background()
+			db, teardown := ldb.LDBForTest(t)
+			defer teardown()
+			_, err := db.Exec(initSQLForReadKeyByRow)
+			require.NoError(t, err)
+			_, err = db.Exec(`INSERT INTO foo___varbinarykey (key, value) VALUES (x'ff', "baa")`)
+			require.NoError(t, err)
+
+			reader := LDBReader{Db: db}
+			var res []interface{}
+			opts := test.opts
+			for pages := 0; ; pages++ {
+				require.True(t, pages <= len(test.expected), "too many pages")
+				rows, err := reader.GetRowsByKeyRange(ctx, "foo", test.table, nil, nil, opts)
+				require.NoError(t, err)
+				for rows.Next() {
+					out := map[string]interface{}{}
+					require.NoError(t, rows.Scan(out))
+					if test.table == "multirow" {
+						res = append(res, out["val"])
+					} else {
+						res = append(res, out["value"])
+					}
+				}
+				require.NoError(t, rows.Err())
+				cursor, err := rows.Cursor()
+				require.NoError(t, err)
+				require.NoError(t, rows.Close())
+				if cursor == "" {
+					break
+				}
+				opts.Cursor = cursor
+			}
+			require.EqualValues(t, test.expected, res)
+		})
+	}
+}
+
+func TestGetRowsByKeyRangeCursorOfAnotherScan(t *testing.T) {
+	ctx := context.Background()
+	db, teardown := ldb.LDBForTest(t)
+	defer teardown()
+	_, err := db.Exec(initSQLForReadKeyByRow)
+	require.NoError(t, err)
+
+	reader := LDBReader{Db: db}
+	rows, err := reader.GetRowsByKeyRange(ctx, "foo", "multirow", []interface{}{"a"}, nil, KeyRangeOpts{Limit: 1})
+	require.NoError(t, err)
+	for rows.Next() {
+	}
+	cursor, err := rows.Cursor()
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+	require.NotEmpty(t, cursor)
+
+	// the page size may change between pages
+	rows, err = reader.GetRowsByKeyRange(ctx, "FOO", "multirow", []interface{}{"a"}, nil, KeyRangeOpts{Limit: 2, Cursor: cursor})
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	for _, test := range []struct {
+		desc  string
+		table string
+		from  []interface{}
+		opts  KeyRangeOpts
+	}{
+		{desc: "other bounds", table: "multirow", from: []interface{}{"b"}},
+		{desc: "other direction", table: "multirow", from: []interface{}{"a"}, opts: KeyRangeOpts{Descending: true}},
+		{desc: "other table", table: "varbinarykey", from: []interface{}{"a"}},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			test.opts.Cursor = cursor
+			_, err := reader.GetRowsByKeyRange(ctx, "foo", test.table, test.from, nil, test.opts)
+			require.Equal(t, ErrInvalidCursor, err)
+		})
+	}
+}
+
+func TestGetRowsByKeys(t *testing.T) {
+	for _, test := range []struct {
+		desc     string
+		table    string
+		keys     [][]interface{}
+		err      error
+		expected []map[string]interface{}
+	}{
+		{
+			desc:  "composite keys",
+			table: "multirow",
+			keys:  [][]interface{}{{"b", "B"}, {"a", "A"}, {"a", "nope"}},
+			expected: []map[string]interface{}{
+				{"k1": "a", "k2": "A", "val": int64(42)},
+				{"k1": "b", "k2": "B", "val": int64(44)},
+			},
+		},
+		{
+			desc:  "duplicate keys",
+			table: "bar",
+			keys:  [][]interface{}{{"foo"}, {"foo"}},
+			expected: []map[string]interface{}{
+				{"key": "foo", "value": "bar"},
+			},
+		},
+		{
+			desc:  "binary key by string",
+			table: "varbinarykey",
+			keys:  [][]interface{}{{"beef"}},
+			expected: []map[string]interface{}{
+				{"key": []byte("beef"), "value": "moo"},
+			},
+		},
+		{
+			desc:  "no keys",
+			table: "bar",
+		},
+		{
+			desc:  "partial key",
+			table: "multirow",
+			keys:  [][]interface{}{{"a"}},
+			err:   ErrNeedFullKey,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			ctx := context.Background()
+			db, teardown := ldb.LDBForTest(t)
+			defer teardown()
+			_, err := db.Exec(initSQLForReadKeyByRow)
+			require.NoError(t, err)
+
+			reader := LDBReader{Db: db}
+			rows, err := reader.GetRowsByKeys(ctx, "foo", test.table, test.keys)
+			if test.err != nil {
+				require.Equal(t, test.err, err)
+				return
+			}
+			require.NoError(t, err)
+			defer rows.Close()
+			var res []map[string]interface{}
+			for rows.Next() {
+				out := map[string]interface{}{}
+				require.NoError(t, rows.Scan(out))
+				res = append(res, out)
+			}
+			require.NoError(t, rows.Err())
+			require.EqualValues(t, test.expected, res)
+		})
+	}
+}
+
+func TestGetRowsByKeysManyBatches(t *testing.T) {
+	ctx := context.Background()
+	db, teardown := ldb.LDBForTest(t)
+	defer teardown()
+	_, err := db.Exec("CREATE TABLE foo___many (id INTEGER PRIMARY KEY, val VARCHAR)")
+	require.NoError(t, err)
+	for i := 0; i < 1500; i++ {
+		_, err = db.Exec("INSERT INTO foo___many VALUES(?, ?)", i, fmt.Sprint(i))
+		require.NoError(t, err)
+	}
+
+	// every other id, including some that don't exist, spanning several
+	// batches with a partial batch at the end.
+	var keys [][]interface{}
+	for i := 0; i < 2100; i += 2 {
+		keys = append(keys, []interface{}{i})
+	}
+
+	reader := LDBReader{Db: db}
+	rows, err := reader.GetRowsByKeys(ctx, "foo", "many", keys)
+	require.NoError(t, err)
+	defer rows.Close()
+	// the cached statements are closed when the LDB is reopened, which must
+	// not break rows that are already being read.
+	reader.mu.Lock()
+	for _, stmt := range reader.getRowsByKeyPrefixStmtCache {
+		require.NoError(t, stmt.Close())
+	}
+	reader.mu.Unlock()
+	found := map[int64]bool{}
+	for rows.Next() {
+		var out struct {
+			ID  int64  `ctlstore:"id"`
+			Val string `ctlstore:"val"`
+		}
+		require.NoError(t, rows.Scan(&out))
+		require.Equal(t, fmt.Sprint(out.ID), out.Val)
+		require.False(t, found[out.ID], "duplicate row %d", out.ID)
+		found[out.ID] = true
+	}
+	require.NoError(t, rows.Err())
+	require.Len(t, found, 750)
+	for i := int64(0); i < 1500; i += 2 {
+		require.True(t, found[i], "missing row %d", i)
+	}
+}
+
+func TestGetRowsByKeysWhileLDBReplaced(t *testing.T) {
+	oldInterval := ldbReplacedCheckInterval
+	defer func() { ldbReplacedCheckInterval = oldInterval }()
+	ldbReplacedCheckInterval = 0
+
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "ldb_replaced_batch")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ldb.DefaultLDBFilename)
+	template := filepath.Join(dir, "template.db")
+
+	db, err := ldb.OpenLDB(template, "rwc")
+	require.NoError(t, err)
+	require.NoError(t, ldb.EnsureLdbInitialized(ctx, db))
+	_, err = db.Exec("CREATE TABLE foo___many (id INTEGER PRIMARY KEY, val VARCHAR)")
+	require.NoError(t, err)
+	for i := 0; i < 600; i++ {
+		_, err = db.Exec("INSERT INTO foo___many VALUES(?, ?)", i, fmt.Sprint(i))
+		require.NoError(t, err)
+	}
+	require.NoError(t, db.Close())
+	contents, err := ioutil.ReadFile(template)
+	require.NoError(t, err)
+	replace := func() error {
+		if err := ioutil.WriteFile(path+".tmp", contents, 0644); err != nil {
+			return err
+		}
+		return os.Rename(path+".tmp", path)
+	}
+	require.NoError(t, replace())
+
+	reader, err := ReaderForPath(path)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	// the keys span two batch sizes, so each lookup after a reopen prepares
+	// two statements, dropping the lock in between
+	var keys [][]interface{}
+	for i := 0; i < 600; i++ {
+		keys = append(keys, []interface{}{i})
+	}
+	done := make(chan struct{})
+	replaced := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case <-done:
+				replaced <- nil
+				return
+			default:
+			}
+			if err := replace(); err != nil {
+				replaced <- err
+				return
+			}
+		}
+	}()
+
+	errs := make(chan error, 4)
+	for g := 0; g < cap(errs); g++ {
+		go func() {
+			errs <- func() error {
+				for i := 0; i < 50; i++ {
+					rows, err := reader.GetRowsByKeys(ctx, "foo", "many", keys)
+					if err != nil {
+						return err
+					}
+					n := 0
+					for rows.Next() {
+						n++
+					}
+					rows.Close()
+					if err := rows.Err(); err != nil {
+						return err
+					}
+					if n != len(keys) {
+						return fmt.Errorf("read %d rows, expected %d", n, len(keys))
+					}
+				}
+				return nil
+			}()
+		}()
+	}
+	for g := 0; g < cap(errs); g++ {
+		require.NoError(t, <-errs)
+	}
+	close(done)
+	require.NoError(t, <-replaced)
+}
+
+func TestGetRowsByIndex(t *testing.T) {
+	for _, test := range []struct {
+		desc     string
+		index    string
+		values   []interface{}
+		err      string
+		expected []map[string]interface{}
+	}{
+		{
+			desc:   "single field",
+			index:  "by_k2",
+			values: []interface{}{"B"},
+			expected: []map[string]interface{}{
+				{"k1": "a", "k2": "B", "val": int64(43)},
+				{"k1": "b", "k2": "B", "val": int64(44)},
+			},
+		},
+		{
+			desc:   "composite index full",
+			index:  "by_k2_val",
+			values: []interface{}{"B", 44},
+			expected: []map[string]interface{}{
+				{"k1": "b", "k2": "B", "val": int64(44)},
+			},
+		},
+		{
+			desc:   "composite index prefix",
+			index:  "by_k2_val",
+			values: []interface{}{"A"},
+			expected: []map[string]interface{}{
+				{"k1": "a", "k2": "A", "val": int64(42)},
+			},
+		},
+		{
+			desc:   "no match",
+			index:  "by_k2",
+			values: []interface{}{"C"},
+		},
+		{
+			desc:   "too many values",
+			index:  "by_k2",
+			values: []interface{}{"B", 43},
+			err:    "too many values supplied for index",
+		},
+		{
+			desc:  "no values",
+			index: "by_k2",
+			err:   "at least one index value must be supplied",
+		},
+		{
+			desc:   "unknown index",
+			index:  "by_val",
+			values: []interface{}{42},
+			err:    ErrIndexNotFound.Error(),
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			ctx := context.Background()
+			db, teardown := ldb.LDBForTest(t)
+			defer teardown()
+			_, err := db.Exec(`
+				CREATE TABLE foo___indexed ("k1" VARCHAR(191), "k2" VARCHAR(191), "val" INTEGER, PRIMARY KEY("k1","k2"));
+				CREATE INDEX foo___indexed___by_k2 ON foo___indexed ("k2");
+				CREATE INDEX foo___indexed___by_k2_val ON foo___indexed ("k2","val");
+				INSERT INTO foo___indexed VALUES ('a', 'A', 42), ('a', 'B', 43), ('b', 'B', 44);
+			`)
+			require.NoError(t, err)
+
+			reader := LDBReader{Db: db}
+			rows, err := reader.GetRowsByIndex(ctx, "foo", "indexed", test.index, test.values...)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			defer rows.Close()
+			var res []map[string]interface{}
+			for rows.Next() {
+				out := map[string]interface{}{}
+				require.NoError(t, rows.Scan(out))
+				res = append(res, out)
+			}
+			require.NoError(t, rows.Err())
+			require.ElementsMatch(t, test.expected, res)
+		})
+	}
+}
+
 func TestGetRowByKey(t *testing.T) {
 	suite := []struct {
 		desc        string
@@ -391,6 +1042,103 @@ func TestGetRowByKey(t *testing.T) {
 	}
 }
 
+func TestGetRowByKeyDecodesFields(t *testing.T) {
+	type hostRow struct {
+		Name  string            `ctlstore:"name"`
+		Addr  net.IP            `ctlstore:"addr"`
+		Attrs map[string]string `ctlstore:"attrs,json"`
+	}
+	ctx := context.Background()
+	db, teardown := ldb.LDBForTest(t)
+	defer teardown()
+	_, err := db.Exec(`
+		CREATE TABLE foo___hosts (name VARCHAR PRIMARY KEY, addr VARCHAR, attrs VARCHAR);
+		INSERT INTO foo___hosts VALUES
+			('a', '10.0.0.1', '{"zone":"us-west-2a"}'),
+			('b', NULL, NULL);
+	`)
+	require.NoError(t, err)
+	reader := LDBReader{Db: db}
+
+	var row hostRow
+	found, err := reader.GetRowByKey(ctx, &row, "foo", "hosts", "a")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, hostRow{
+		Name:  "a",
+		Addr:  net.ParseIP("10.0.0.1"),
+		Attrs: map[string]string{"zone": "us-west-2a"},
+	}, row)
+
+	rows, err := reader.GetRowsByKeyPrefix(ctx, "foo", "hosts")
+	require.NoError(t, err)
+	defer rows.Close()
+	var got []hostRow
+	for rows.Next() {
+		require.NoError(t, rows.Scan(&row))
+		got = append(got, row)
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, []hostRow{
+		{Name: "a", Addr: net.ParseIP("10.0.0.1"), Attrs: map[string]string{"zone": "us-west-2a"}},
+		{Name: "b"},
+	}, got)
+
+	_, err = db.Exec(`UPDATE foo___hosts SET attrs = 'not json' WHERE name = 'a'`)
+	require.NoError(t, err)
+	_, err = reader.GetRowByKey(ctx, &row, "foo", "hosts", "a")
+	require.Error(t, err)
+}
+
+// testTier is an integer-backed enum which can also be unmarshaled from
+// its name.
+type testTier int
+
+func (t *testTier) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "free":
+		*t = 1
+	case "paid":
+		*t = 2
+	default:
+		return fmt.Errorf("unknown tier %q", text)
+	}
+	return nil
+}
+
+func TestGetRowByKeyDecodesNonTextColumns(t *testing.T) {
+	type accountRow struct {
+		Name     string    `ctlstore:"name"`
+		Addr     net.IP    `ctlstore:"addr"`
+		Tier     testTier  `ctlstore:"tier"`
+		TierName *testTier `ctlstore:"tier_name"`
+	}
+	ctx := context.Background()
+	db, teardown := ldb.LDBForTest(t)
+	defer teardown()
+	_, err := db.Exec(`
+		CREATE TABLE foo___accounts (name VARCHAR PRIMARY KEY, addr BLOB, tier INTEGER, tier_name VARCHAR);
+		INSERT INTO foo___accounts VALUES ('a', x'0a000001', 2, 'free');
+	`)
+	require.NoError(t, err)
+	reader := LDBReader{Db: db}
+
+	// only text is unmarshaled, so the BLOB and INTEGER columns are
+	// converted the way they would be without UnmarshalText
+	var row accountRow
+	found, err := reader.GetRowByKey(ctx, &row, "foo", "accounts", "a")
+	require.NoError(t, err)
+	require.True(t, found)
+	free := testTier(1)
+	require.Equal(t, accountRow{
+		Name:     "a",
+		Addr:     net.IP{10, 0, 0, 1},
+		Tier:     2,
+		TierName: &free,
+	}, row)
+	require.Equal(t, "10.0.0.1", row.Addr.String())
+}
+
 func TestLDBReaderEmptyFileHandling(t *testing.T) {
 	ctx := context.Background()
 	dbPath, teardown := ldb.NewLDBTmpPath(t)
@@ -0,0 +1,106 @@
+package ctlstore
+
+import (
+	"database/sql"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/ctlstore/pkg/globalstats"
+	"github.com/segmentio/ctlstore/pkg/ldb"
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/segmentio/events"
+)
+
+// ldbReplacedCheckInterval is how often a reader opened with ReaderForPath
+// checks whether the LDB file has been replaced.
+var ldbReplacedCheckInterval = time.Second
+
+// reopenIfReplaced reopens the LDB if the file at the reader's path is no
+// longer the one that the reader has open. This happens when the reflector
+// bootstraps a fresh LDB from a snapshot, or when an operator replaces it,
+// and without this the reader would keep serving the old LDB forever.
+//
+// The check is rate limited by ldbReplacedCheckInterval and does nothing
+// for readers which weren't opened with ReaderForPath. Reads already in
+// flight against the old LDB are allowed to finish, and the LDB is not
+// reopened while a ReadTx is in progress.
+//
+// WARNING: assumes mutex is not held
+func (reader *LDBReader) reopenIfReplaced() {
+	if reader.path == "" {
+		return
+	}
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&reader.lastReplacedCheck)
+	if now-last < int64(ldbReplacedCheckInterval) {
+		return
+	}
+	if !atomic.CompareAndSwapInt64(&reader.lastReplacedCheck, last, now) {
+		// another read is already checking
+		return
+	}
+
+	fi, err := os.Stat(reader.path)
+	if err != nil {
+		// The LDB may be in the middle of being replaced, so keep serving
+		// from the one that's open and check again later.
+		events.Debug("Could not stat LDB at %{path}s: %{error}+v", reader.path, err)
+		return
+	}
+	reader.mu.RLock()
+	replaced := !os.SameFile(reader.fileInfo, fi)
+	reader.mu.RUnlock()
+	if !replaced || atomic.LoadInt32(&reader.openTxs) > 0 {
+		// transactions have to finish against the LDB they started on, so
+		// reopening waits until the next check without any open
+		return
+	}
+
+	db, err := ldb.OpenLDB(reader.path, ldbOpenMode())
+	if err != nil {
+		events.Log("Could not reopen replaced LDB at %{path}s: %{error}+v", reader.path, err)
+		return
+	}
+
+	reader.mu.Lock()
+	if atomic.LoadInt32(&reader.openTxs) > 0 {
+		reader.mu.Unlock()
+		db.Close()
+		return
+	}
+	oldDB := reader.Db
+	oldStmts := make([]*sql.Stmt, 0, len(reader.getRowByKeyStmtCache)+len(reader.getRowsByKeyPrefixStmtCache))
+	for _, stmt := range reader.getRowByKeyStmtCache {
+		oldStmts = append(oldStmts, stmt)
+	}
+	for _, stmt := range reader.getRowsByKeyPrefixStmtCache {
+		oldStmts = append(oldStmts, stmt)
+	}
+	reader.Db = db
+	reader.fileInfo = fi
+	// The caches are replaced rather than set to nil, since reads which
+	// dropped the lock to fill them expect them to still be allocated.
+	reader.pkCache = make(map[string]schema.PrimaryKey)
+	reader.indexCache = make(map[string]schema.PrimaryKey)
+	reader.getRowByKeyStmtCache = make(map[string]*sql.Stmt)
+	reader.getRowsByKeyPrefixStmtCache = make(map[prefixCacheKey]*sql.Stmt)
+	if reader.maxLatency != nil {
+		reader.maxLatency.reset()
+	}
+	if reader.cache != nil {
+		reader.cache.purge()
+	}
+	reader.mu.Unlock()
+
+	// Closing waits for any rows still open against the old LDB, so this
+	// doesn't fail reads which started before the swap.
+	for _, stmt := range oldStmts {
+		stmt.Close()
+	}
+	if err := oldDB.Close(); err != nil {
+		events.Log("Could not close replaced LDB: %{error}+v", err)
+	}
+	globalstats.Incr("ldb-reopened", "", "")
+	events.Log("Reopened LDB at %{path}s after it was replaced", reader.path)
+}
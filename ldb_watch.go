@@ -0,0 +1,128 @@
+package ctlstore
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/segmentio/errors-go"
+	"github.com/segmentio/events"
+)
+
+// ldbWatch watches the LDB at a path, or its WAL, for writes on behalf of
+// any number of subscribers. Since the reflector commits every change
+// through the WAL, this is a cheap signal that the LDB may have moved
+// forward.
+//
+// A reader shares one watch between all of its WaitForSequence calls, so
+// that they don't each use up an inotify instance. The watch only holds an
+// fsnotify watcher while it has subscribers.
+type ldbWatch struct {
+	path    string
+	mu      sync.Mutex
+	watcher *fsnotify.Watcher // nil while there are no subscribers
+	subs    map[chan struct{}]struct{}
+	closed  bool
+}
+
+func newLDBWatch(path string) *ldbWatch {
+	return &ldbWatch{
+		path: filepath.Clean(path),
+		subs: make(map[chan struct{}]struct{}),
+	}
+}
+
+// subscribe returns a channel which receives a value each time the LDB is
+// written to, along with a function which unsubscribes from the watch.
+// Notifications are coalesced, so a slow receiver will only see one pending
+// notification no matter how many writes have occurred.
+func (w *ldbWatch) subscribe() (<-chan struct{}, func(), error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil, nil, errors.New("the LDB watch is closed")
+	}
+	if w.watcher == nil {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "create fsnotify watcher")
+		}
+		// watch the parent dir rather than the files themselves so that the
+		// WAL being created, truncated, or the LDB being replaced is also
+		// picked up.
+		dir := filepath.Dir(w.path)
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, nil, errors.Wrapf(err, "could not watch '%s'", dir)
+		}
+		w.watcher = watcher
+		go w.run(watcher)
+	}
+	ch := make(chan struct{}, 1)
+	w.subs[ch] = struct{}{}
+	return ch, func() { w.unsubscribe(ch) }, nil
+}
+
+func (w *ldbWatch) unsubscribe(ch chan struct{}) {
+	w.mu.Lock()
+	delete(w.subs, ch)
+	var watcher *fsnotify.Watcher
+	if len(w.subs) == 0 {
+		watcher, w.watcher = w.watcher, nil
+	}
+	w.mu.Unlock()
+	// closed without holding the lock, since run takes it to notify
+	closeWatcher(watcher)
+}
+
+// run notifies the subscribers of writes seen by the watcher, until the
+// watcher is closed.
+func (w *ldbWatch) run(watcher *fsnotify.Watcher) {
+	walPath := w.path + "-wal"
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			name := filepath.Clean(event.Name)
+			if name != w.path && name != walPath {
+				continue
+			}
+			w.mu.Lock()
+			for ch := range w.subs {
+				select {
+				case ch <- struct{}{}:
+				default:
+					// a notification is already pending
+				}
+			}
+			w.mu.Unlock()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			events.Log("LDB watcher err: %{err}s", err)
+		}
+	}
+}
+
+// close stops the watch. Subscribers stop being notified, and any further
+// subscriptions fail.
+func (w *ldbWatch) close() {
+	w.mu.Lock()
+	w.closed = true
+	watcher := w.watcher
+	w.watcher = nil
+	w.mu.Unlock()
+	closeWatcher(watcher)
+}
+
+func closeWatcher(watcher *fsnotify.Watcher) {
+	if watcher == nil {
+		return
+	}
+	if err := watcher.Close(); err != nil {
+		events.Log("Could not close LDB watcher: %{err}s", err)
+	}
+}
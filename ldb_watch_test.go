@@ -0,0 +1,48 @@
+package ctlstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLDBWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ldb_watch")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ldb.db")
+
+	watch := newLDBWatch(path)
+	ch1, unsubscribe1, err := watch.subscribe()
+	require.NoError(t, err)
+	watcher := watch.watcher
+	ch2, unsubscribe2, err := watch.subscribe()
+	require.NoError(t, err)
+	require.True(t, watcher == watch.watcher, "subscribers don't share a watcher")
+
+	// writes to the LDB and its WAL are fanned out to every subscriber
+	for _, name := range []string{path, path + "-wal"} {
+		require.NoError(t, ioutil.WriteFile(name, []byte("x"), 0644))
+		for _, ch := range []<-chan struct{}{ch1, ch2} {
+			select {
+			case <-ch:
+			case <-time.After(time.Second):
+				t.Fatalf("write to %s wasn't notified", name)
+			}
+		}
+	}
+
+	// the watcher is only kept while there are subscribers
+	unsubscribe1()
+	require.NotNil(t, watch.watcher)
+	unsubscribe2()
+	require.Nil(t, watch.watcher)
+
+	watch.close()
+	_, _, err = watch.subscribe()
+	require.Error(t, err)
+}
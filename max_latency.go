@@ -0,0 +1,108 @@
+package ctlstore
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/ctlstore/pkg/globalstats"
+	"github.com/segmentio/errors-go"
+)
+
+// ReaderOpt configures an LDBReader.
+type ReaderOpt func(reader *LDBReader)
+
+// ErrStale is returned by reads of rows when the LDB is further behind
+// the ledger than the reader's maximum latency, see WithMaxLatency.
+var ErrStale = errors.New("LDB is staler than the maximum latency")
+
+// maxLatencyRecheckInterval is the least amount of time between queries
+// for the ledger latency once the LDB has been found to be stale.
+var maxLatencyRecheckInterval = 100 * time.Millisecond
+
+// WithMaxLatency bounds how stale the data returned by the reader may be.
+// GetRowByKey and the GetRowsBy* methods return ErrStale once the last
+// ledger update applied to the LDB is older than maxLatency, which includes
+// when no ledger updates have been applied at all.
+//
+// The ledger latency is only queried once the previous result implies that
+// the LDB could have become stale, so this doesn't add a query to every read.
+func WithMaxLatency(maxLatency time.Duration) ReaderOpt {
+	return func(reader *LDBReader) {
+		if reader.maxLatency == nil {
+			reader.maxLatency = &latencyBound{}
+		}
+		reader.maxLatency.max = maxLatency
+	}
+}
+
+// WithMaxLatencyFailOpen makes reads which would otherwise return ErrStale
+// succeed, so that WithMaxLatency only counts them in the stale-reads
+// metric.
+func WithMaxLatencyFailOpen() ReaderOpt {
+	return func(reader *LDBReader) {
+		if reader.maxLatency == nil {
+			reader.maxLatency = &latencyBound{}
+		}
+		reader.maxLatency.failOpen = true
+	}
+}
+
+// latencyBound tracks whether the LDB is within the maximum latency.
+type latencyBound struct {
+	freshUntil int64 // unix nanos, accessed atomically so kept 64-bit aligned
+	max        time.Duration
+	failOpen   bool
+	mu         sync.Mutex // held while querying the latency
+	checkedAt  time.Time
+}
+
+// reset forgets the result of the last latency query, which is needed when
+// a different LDB has been opened.
+func (b *latencyBound) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	atomic.StoreInt64(&b.freshUntil, 0)
+	b.checkedAt = time.Time{}
+}
+
+// checkLatency returns ErrStale if the LDB is beyond the reader's maximum
+// latency, unless the reader fails open.
+//
+// WARNING: assumes mutex is read locked
+func (reader *LDBReader) checkLatency(ctx context.Context, familyName string, tableName string) error {
+	b := reader.maxLatency
+	if b == nil || b.max <= 0 {
+		return nil
+	}
+	now := time.Now()
+	if now.UnixNano() < atomic.LoadInt64(&b.freshUntil) {
+		return nil
+	}
+
+	b.mu.Lock()
+	if now.Sub(b.checkedAt) >= maxLatencyRecheckInterval {
+		timestamp, err := reader.getLastLedgerUpdate(ctx)
+		switch {
+		case err == ErrNoLedgerUpdates:
+			atomic.StoreInt64(&b.freshUntil, 0)
+		case err != nil:
+			b.mu.Unlock()
+			return errors.Wrap(err, "check ledger latency")
+		default:
+			atomic.StoreInt64(&b.freshUntil, timestamp.Add(b.max).UnixNano())
+		}
+		b.checkedAt = now
+	}
+	b.mu.Unlock()
+	if now.UnixNano() < atomic.LoadInt64(&b.freshUntil) {
+		return nil
+	}
+
+	globalstats.Incr("stale-reads", familyName, tableName)
+	if b.failOpen {
+		return nil
+	}
+	return ErrStale
+}
@@ -0,0 +1,121 @@
+package ctlstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/segmentio/ctlstore/pkg/ldb"
+	"github.com/segmentio/ctlstore/pkg/ldbwriter"
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxLatency(t *testing.T) {
+	for _, test := range []struct {
+		desc       string
+		opts       []ReaderOpt
+		lastUpdate time.Duration // ago, zero for no ledger updates
+		expected   error
+	}{
+		{
+			desc: "no max latency",
+		},
+		{
+			desc:       "fresh",
+			opts:       []ReaderOpt{WithMaxLatency(time.Minute)},
+			lastUpdate: time.Second,
+		},
+		{
+			desc:       "stale",
+			opts:       []ReaderOpt{WithMaxLatency(time.Minute)},
+			lastUpdate: time.Hour,
+			expected:   ErrStale,
+		},
+		{
+			desc:     "no ledger updates",
+			opts:     []ReaderOpt{WithMaxLatency(time.Minute)},
+			expected: ErrStale,
+		},
+		{
+			desc:       "stale but fail open",
+			opts:       []ReaderOpt{WithMaxLatency(time.Minute), WithMaxLatencyFailOpen()},
+			lastUpdate: time.Hour,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			ctx := context.Background()
+			db, teardown := ldb.LDBForTest(t)
+			defer teardown()
+			_, err := db.Exec(`
+				CREATE TABLE foo___bar (key VARCHAR PRIMARY KEY, value VARCHAR);
+				INSERT INTO foo___bar VALUES ('a', 'b');
+			`)
+			require.NoError(t, err)
+			if test.lastUpdate != 0 {
+				writer := ldbwriter.SqlLdbWriter{Db: db}
+				err = writer.ApplyDMLStatement(ctx, schema.DMLStatement{
+					Sequence:  1,
+					Statement: "INSERT INTO foo___bar VALUES ('c', 'd')",
+					Timestamp: time.Now().Add(-test.lastUpdate),
+				})
+				require.NoError(t, err)
+			}
+
+			reader := NewLDBReaderFromDB(db, test.opts...)
+			var row testKVStruct
+			found, err := reader.GetRowByKey(ctx, &row, "foo", "bar", "a")
+			require.Equal(t, test.expected, err)
+			require.Equal(t, test.expected == nil, found)
+
+			rows, err := reader.GetRowsByKeyPrefix(ctx, "foo", "bar")
+			require.Equal(t, test.expected, err)
+			if rows != nil {
+				rows.Close()
+			}
+		})
+	}
+}
+
+func TestMaxLatencyIsCached(t *testing.T) {
+	oldInterval := maxLatencyRecheckInterval
+	defer func() { maxLatencyRecheckInterval = oldInterval }()
+	maxLatencyRecheckInterval = 0
+
+	ctx := context.Background()
+	db, teardown := ldb.LDBForTest(t)
+	defer teardown()
+	_, err := db.Exec("CREATE TABLE foo___bar (key VARCHAR PRIMARY KEY, value VARCHAR)")
+	require.NoError(t, err)
+
+	writer := ldbwriter.SqlLdbWriter{Db: db}
+	applyAt := func(seq schema.DMLSequence, timestamp time.Time) {
+		err := writer.ApplyDMLStatement(ctx, schema.DMLStatement{
+			Sequence:  seq,
+			Statement: "DELETE FROM foo___bar",
+			Timestamp: timestamp,
+		})
+		require.NoError(t, err)
+	}
+	applyAt(1, time.Now())
+
+	reader := NewLDBReaderFromDB(db, WithMaxLatency(time.Minute))
+	var row testKVStruct
+	_, err = reader.GetRowByKey(ctx, &row, "foo", "bar", "a")
+	require.NoError(t, err)
+
+	// the reader already knows that the LDB can't be stale for another
+	// minute, so it doesn't look at the ledger update again
+	applyAt(2, time.Now().Add(-time.Hour))
+	_, err = reader.GetRowByKey(ctx, &row, "foo", "bar", "a")
+	require.NoError(t, err)
+
+	// once the LDB could be stale, the ledger update is checked again
+	reader.maxLatency.freshUntil = 0
+	_, err = reader.GetRowByKey(ctx, &row, "foo", "bar", "a")
+	require.Equal(t, ErrStale, err)
+
+	applyAt(3, time.Now())
+	_, err = reader.GetRowByKey(ctx, &row, "foo", "bar", "a")
+	require.NoError(t, err)
+}
@@ -0,0 +1,246 @@
+package ctlstore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/segmentio/errors-go"
+)
+
+// MultiReaderConfig describes the LDBs a MultiReader reads from and which
+// families are read from each of them.
+type MultiReaderConfig struct {
+	// Shards maps the name of each shard to the path of its LDB.
+	Shards map[string]string
+	// Families maps family names to the name of the shard which holds them.
+	// Family names are normalized, so are matched case insensitively.
+	Families map[string]string
+	// DefaultShard, if set, is the shard read from for families which
+	// aren't listed in Families.
+	DefaultShard string
+}
+
+// ShardHealth reports the state of a single shard of a MultiReader.
+type ShardHealth struct {
+	Shard string
+	Path  string
+	// Healthy is true if the LDB could be read and has received at least
+	// one ledger update.
+	Healthy       bool
+	LedgerLatency time.Duration
+	Err           error
+}
+
+// MultiReader reads from several LDBs, e.g. ones kept up to date by
+// different ctlstore deployments, routing each read to an LDB by the name
+// of the family being read. It has the methods a sidecar needs to serve
+// reads from it.
+type MultiReader struct {
+	shards       map[string]*LDBReader
+	paths        map[string]string
+	families     map[string]string
+	defaultShard string
+}
+
+var ErrNoShardForFamily = errors.New("no shard is configured for family")
+
+// NewMultiReader opens the LDB of each shard in the config with
+// ReaderForPath, configured with the supplied options. Make sure to Close()
+// the reader when done with it.
+func NewMultiReader(cfg MultiReaderConfig, opts ...ReaderOpt) (*MultiReader, error) {
+	if len(cfg.Shards) == 0 {
+		return nil, errors.New("no shards configured")
+	}
+	if cfg.DefaultShard != "" {
+		if _, ok := cfg.Shards[cfg.DefaultShard]; !ok {
+			return nil, errors.Errorf("unknown default shard '%s'", cfg.DefaultShard)
+		}
+	}
+	families := make(map[string]string, len(cfg.Families))
+	for family, shard := range cfg.Families {
+		famName, err := schema.NewFamilyName(family)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid family '%s'", family)
+		}
+		if _, ok := cfg.Shards[shard]; !ok {
+			return nil, errors.Errorf("unknown shard '%s' for family '%s'", shard, family)
+		}
+		if routed, ok := families[famName.Name]; ok && routed != shard {
+			return nil, errors.Errorf("family '%s' is routed to both shard '%s' and '%s'", famName.Name, routed, shard)
+		}
+		families[famName.Name] = shard
+	}
+
+	mr := &MultiReader{
+		shards:       make(map[string]*LDBReader, len(cfg.Shards)),
+		paths:        make(map[string]string, len(cfg.Shards)),
+		families:     families,
+		defaultShard: cfg.DefaultShard,
+	}
+	for shard, path := range cfg.Shards {
+		reader, err := ReaderForPath(path, opts...)
+		if err != nil {
+			mr.Close()
+			return nil, errors.Wrapf(err, "open shard '%s'", shard)
+		}
+		mr.shards[shard] = reader
+		mr.paths[shard] = path
+	}
+	return mr, nil
+}
+
+// ShardFor returns the name of the shard the specified family is read from.
+// ErrNoShardForFamily is returned if the family isn't routed to any shard.
+func (mr *MultiReader) ShardFor(familyName string) (string, error) {
+	famName, err := schema.NewFamilyName(familyName)
+	if err != nil {
+		return "", err
+	}
+	if shard, ok := mr.families[famName.Name]; ok {
+		return shard, nil
+	}
+	if mr.defaultShard != "" {
+		return mr.defaultShard, nil
+	}
+	return "", errors.Wrapf(ErrNoShardForFamily, "family '%s'", familyName)
+}
+
+// ReaderFor returns the reader of the shard the specified family is read
+// from, for reads which MultiReader doesn't route itself.
+func (mr *MultiReader) ReaderFor(familyName string) (*LDBReader, error) {
+	shard, err := mr.ShardFor(familyName)
+	if err != nil {
+		return nil, err
+	}
+	return mr.shards[shard], nil
+}
+
+// GetRowByKey reads a row from the shard holding the family, see
+// LDBReader.GetRowByKey.
+func (mr *MultiReader) GetRowByKey(ctx context.Context, out interface{}, familyName string, tableName string, key ...interface{}) (found bool, err error) {
+	reader, err := mr.ReaderFor(familyName)
+	if err != nil {
+		return false, err
+	}
+	return reader.GetRowByKey(ctx, out, familyName, tableName, key...)
+}
+
+// GetRowsByKeyPrefix reads rows from the shard holding the family, see
+// LDBReader.GetRowsByKeyPrefix.
+func (mr *MultiReader) GetRowsByKeyPrefix(ctx context.Context, familyName string, tableName string, key ...interface{}) (*Rows, error) {
+	reader, err := mr.ReaderFor(familyName)
+	if err != nil {
+		return nil, err
+	}
+	return reader.GetRowsByKeyPrefix(ctx, familyName, tableName, key...)
+}
+
+// GetRowsByKeyRange reads a range of rows from the shard holding the
+// family, see LDBReader.GetRowsByKeyRange.
+func (mr *MultiReader) GetRowsByKeyRange(ctx context.Context, familyName string, tableName string, from []interface{}, to []interface{}, opts KeyRangeOpts) (*Rows, error) {
+	reader, err := mr.ReaderFor(familyName)
+	if err != nil {
+		return nil, err
+	}
+	return reader.GetRowsByKeyRange(ctx, familyName, tableName, from, to, opts)
+}
+
+// GetLedgerLatency returns the highest ledger latency of all of the
+// shards, since reads from any of them may be that far behind. If any shard
+// can't report its latency, the error for that shard is returned instead.
+// ErrNoLedgerUpdates is returned as is, so that it can be compared against.
+func (mr *MultiReader) GetLedgerLatency(ctx context.Context) (time.Duration, error) {
+	var max time.Duration
+	for _, shard := range mr.shardNames() {
+		latency, err := mr.shards[shard].GetLedgerLatency(ctx)
+		switch {
+		case err == ErrNoLedgerUpdates:
+			return 0, err
+		case err != nil:
+			return 0, errors.Wrapf(err, "shard '%s'", shard)
+		}
+		if latency > max {
+			max = latency
+		}
+	}
+	return max, nil
+}
+
+// ListFamilies returns the names of the families which are read from the
+// shards they're routed to, in sorted order.
+func (mr *MultiReader) ListFamilies(ctx context.Context) ([]string, error) {
+	var families []string
+	for _, shard := range mr.shardNames() {
+		names, err := mr.shards[shard].ListFamilies(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "shard '%s'", shard)
+		}
+		for _, name := range names {
+			if routed, err := mr.ShardFor(name); err == nil && routed == shard {
+				families = append(families, name)
+			}
+		}
+	}
+	sort.Strings(families)
+	return families, nil
+}
+
+// ListTables lists the tables of the family in the shard holding it, see
+// LDBReader.ListTables.
+func (mr *MultiReader) ListTables(ctx context.Context, familyName string) ([]string, error) {
+	reader, err := mr.ReaderFor(familyName)
+	if err != nil {
+		return nil, err
+	}
+	return reader.ListTables(ctx, familyName)
+}
+
+// DescribeTable describes the table in the shard holding its family, see
+// LDBReader.DescribeTable.
+func (mr *MultiReader) DescribeTable(ctx context.Context, familyName string, tableName string) (TableSchema, error) {
+	reader, err := mr.ReaderFor(familyName)
+	if err != nil {
+		return TableSchema{}, err
+	}
+	return reader.DescribeTable(ctx, familyName, tableName)
+}
+
+// Health reports the health of each shard, in order of shard name.
+func (mr *MultiReader) Health(ctx context.Context) []ShardHealth {
+	names := mr.shardNames()
+	health := make([]ShardHealth, 0, len(names))
+	for _, shard := range names {
+		h := ShardHealth{Shard: shard, Path: mr.paths[shard]}
+		reader := mr.shards[shard]
+		if !reader.Ping(ctx) {
+			h.Err = errors.New("LDB is unavailable")
+		} else {
+			h.LedgerLatency, h.Err = reader.GetLedgerLatency(ctx)
+		}
+		h.Healthy = h.Err == nil
+		health = append(health, h)
+	}
+	return health
+}
+
+// Close closes the reader of every shard.
+func (mr *MultiReader) Close() error {
+	var errs []error
+	for _, shard := range mr.shardNames() {
+		if err := mr.shards[shard].Close(); err != nil {
+			errs = append(errs, errors.Wrapf(err, "shard '%s'", shard))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (mr *MultiReader) shardNames() []string {
+	names := make([]string, 0, len(mr.shards))
+	for name := range mr.shards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,154 @@
+package ctlstore
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/segmentio/ctlstore/pkg/ldb"
+	"github.com/segmentio/ctlstore/pkg/ldbwriter"
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/segmentio/errors-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiReader(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "multi_reader")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	createLDB := func(name string, statements ...string) string {
+		path := filepath.Join(dir, name+".db")
+		db, err := ldb.OpenLDB(path, "rwc")
+		require.NoError(t, err)
+		defer db.Close()
+		require.NoError(t, ldb.EnsureLdbInitialized(ctx, db))
+		writer := ldbwriter.SqlLdbWriter{Db: db}
+		for i, statement := range statements {
+			err := writer.ApplyDMLStatement(ctx, schema.DMLStatement{
+				Sequence:  schema.DMLSequence(i + 1),
+				Statement: statement,
+			})
+			require.NoError(t, err)
+		}
+		return path
+	}
+	pathA := createLDB("a",
+		"CREATE TABLE foo___bar (key VARCHAR PRIMARY KEY, value VARCHAR)",
+		"INSERT INTO foo___bar VALUES ('k', 'from a')",
+		"CREATE TABLE baz___bar (key VARCHAR PRIMARY KEY, value VARCHAR)",
+	)
+	pathB := createLDB("b",
+		"CREATE TABLE foo___bar (key VARCHAR PRIMARY KEY, value VARCHAR)",
+		"INSERT INTO foo___bar VALUES ('k', 'from b')",
+		"CREATE TABLE qux___bar (key VARCHAR PRIMARY KEY, value VARCHAR)",
+		"INSERT INTO qux___bar VALUES ('k', 'from b')",
+	)
+
+	_, err = NewMultiReader(MultiReaderConfig{
+		Shards:   map[string]string{"a": pathA},
+		Families: map[string]string{"foo": "b"},
+	})
+	require.EqualError(t, err, "unknown shard 'b' for family 'foo'")
+
+	_, err = NewMultiReader(MultiReaderConfig{
+		Shards:   map[string]string{"a": pathA},
+		Families: map[string]string{"foo__bar": "a"},
+	})
+	require.Equal(t, schema.ErrFamilyNameInvalid, errors.Cause(err))
+
+	_, err = NewMultiReader(MultiReaderConfig{
+		Shards:   map[string]string{"a": pathA, "b": pathB},
+		Families: map[string]string{"foo": "a", "FOO": "b"},
+	})
+	require.Error(t, err)
+
+	// family names are matched case insensitively, like everywhere else
+	mr, err := NewMultiReader(MultiReaderConfig{
+		Shards:       map[string]string{"a": pathA, "b": pathB},
+		Families:     map[string]string{"Foo": "b"},
+		DefaultShard: "a",
+	})
+	require.NoError(t, err)
+	defer mr.Close()
+
+	var row testKVStruct
+	found, err := mr.GetRowByKey(ctx, &row, "FOO", "bar", "k")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "from b", row.Val)
+
+	// qux isn't routed to b, so it's read from the default shard
+	_, err = mr.GetRowByKey(ctx, &row, "qux", "bar", "k")
+	require.Equal(t, ErrTableNotFound, errors.Cause(err))
+
+	rows, err := mr.GetRowsByKeyPrefix(ctx, "foo", "bar")
+	require.NoError(t, err)
+	var rowsRead []testKVStruct
+	require.NoError(t, rows.ScanAll(&rowsRead))
+	require.Equal(t, []testKVStruct{{"k", "from b"}}, rowsRead)
+
+	rows, err = mr.GetRowsByKeyRange(ctx, "foo", "bar", nil, nil, KeyRangeOpts{Limit: 1})
+	require.NoError(t, err)
+	rowsRead = nil
+	require.NoError(t, rows.ScanAll(&rowsRead))
+	require.Equal(t, []testKVStruct{{"k", "from b"}}, rowsRead)
+
+	families, err := mr.ListFamilies(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"baz", "foo"}, families)
+
+	_, err = mr.GetLedgerLatency(ctx)
+	require.NoError(t, err)
+
+	health := mr.Health(ctx)
+	require.Len(t, health, 2)
+	for i, shard := range []string{"a", "b"} {
+		require.Equal(t, shard, health[i].Shard)
+		require.True(t, health[i].Healthy, "%s: %v", shard, health[i].Err)
+	}
+
+	// without a default shard, unrouted families can't be read
+	mr2, err := NewMultiReader(MultiReaderConfig{
+		Shards:   map[string]string{"a": pathA, "b": pathB},
+		Families: map[string]string{"foo": "b"},
+	})
+	require.NoError(t, err)
+	defer mr2.Close()
+	_, err = mr2.GetRowByKey(ctx, &row, "baz", "bar", "k")
+	require.Equal(t, ErrNoShardForFamily, errors.Cause(err))
+	families, err = mr2.ListFamilies(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo"}, families)
+}
+
+func TestMultiReaderUnhealthyShard(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "multi_reader")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// a shard whose reflector hasn't applied anything yet
+	path := filepath.Join(dir, "empty.db")
+	db, err := ldb.OpenLDB(path, "rwc")
+	require.NoError(t, err)
+	require.NoError(t, ldb.EnsureLdbInitialized(ctx, db))
+	require.NoError(t, db.Close())
+
+	mr, err := NewMultiReader(MultiReaderConfig{
+		Shards:       map[string]string{"empty": path},
+		DefaultShard: "empty",
+	})
+	require.NoError(t, err)
+	defer mr.Close()
+
+	_, err = mr.GetLedgerLatency(ctx)
+	require.Equal(t, ErrNoLedgerUpdates, err)
+	health := mr.Health(ctx)
+	require.Len(t, health, 1)
+	require.False(t, health[0].Healthy)
+	require.Equal(t, path, health[0].Path)
+}
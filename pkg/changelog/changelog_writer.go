@@ -20,24 +20,56 @@ type (
 		Family string
 		Table  string
 		Key    []interface{}
+		// LedgerSeq is the ledger sequence of the statement which made the
+		// change, and LedgerIndex the position of the change among those
+		// the statement made. Unlike Seq, they don't start over when the
+		// reflector restarts. LedgerLast is set on the last change the
+		// statement made.
+		LedgerSeq   int64
+		LedgerIndex int
+		LedgerLast  bool
+		// Op is one of the Op* constants. It, along with the row values,
+		// is left empty for entries which only identify the changed row.
+		Op  string
+		Old map[string]interface{} // the row before the change, keyed by column
+		New map[string]interface{} // the row after the change, keyed by column
 	}
 )
 
+// The kinds of change a changelog entry can describe.
+const (
+	OpInsert = "insert"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
 func NewChangelogEntry(seq int64, family string, table string, key []interface{}) *ChangelogEntry {
 	return &ChangelogEntry{Seq: seq, Family: family, Table: table, Key: key}
 }
 
 func (w *ChangelogWriter) WriteChange(e ChangelogEntry) error {
 	structure := struct {
-		Seq    int64         `json:"seq"`
-		Family string        `json:"family"`
-		Table  string        `json:"table"`
-		Key    []interface{} `json:"key"`
+		Seq         int64                  `json:"seq"`
+		Family      string                 `json:"family"`
+		Table       string                 `json:"table"`
+		Key         []interface{}          `json:"key"`
+		LedgerSeq   int64                  `json:"ledger_seq,omitempty"`
+		LedgerIndex int                    `json:"ledger_index,omitempty"`
+		LedgerLast  bool                   `json:"ledger_last,omitempty"`
+		Op          string                 `json:"op,omitempty"`
+		Old         map[string]interface{} `json:"old,omitempty"`
+		New         map[string]interface{} `json:"new,omitempty"`
 	}{
 		e.Seq,
 		e.Family,
 		e.Table,
 		e.Key,
+		e.LedgerSeq,
+		e.LedgerIndex,
+		e.LedgerLast,
+		e.Op,
+		e.Old,
+		e.New,
 	}
 
 	bytes, err := json.Marshal(structure)
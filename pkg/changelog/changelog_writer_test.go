@@ -34,3 +34,24 @@ func TestWriteChange(t *testing.T) {
 	require.EqualValues(t, 1, len(mock.Lines))
 	require.Equal(t, `{"seq":42,"family":"family1","table":"table1","key":[18014398509481984,"foo"]}`, mock.Lines[0])
 }
+
+func TestWriteChangeWithRows(t *testing.T) {
+	mock := &clwWriteLineMock{}
+	clw := ChangelogWriter{WriteLine: mock}
+
+	err := clw.WriteChange(ChangelogEntry{
+		Seq:         43,
+		Family:      "family1",
+		Table:       "table1",
+		Key:         []interface{}{"foo"},
+		LedgerSeq:   1001,
+		LedgerIndex: 1,
+		LedgerLast:  true,
+		Op:          OpUpdate,
+		Old:         map[string]interface{}{"id": "foo", "data": []byte{0x01}},
+		New:         map[string]interface{}{"id": "foo", "data": nil},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, len(mock.Lines))
+	require.Equal(t, `{"seq":43,"family":"family1","table":"table1","key":["foo"],"ledger_seq":1001,"ledger_index":1,"ledger_last":true,"op":"update","old":{"data":"AQ==","id":"foo"},"new":{"data":null,"id":"foo"}}`, mock.Lines[0])
+}
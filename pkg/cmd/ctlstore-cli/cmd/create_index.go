@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(createIndexCmd)
+	useFlagExecutive(createIndexCmd)
+	useFlagFamily(createIndexCmd)
+	useFlagTable(createIndexCmd)
+	useFlagIndexFields(createIndexCmd)
+	useFlagUnique(createIndexCmd)
+}
+
+// createIndexCmd represents the create-index command
+var createIndexCmd = &cobra.Command{
+	Use:   "create-index [indexName]",
+	Short: "Create a new secondary index on a table",
+	Long: unindent(`
+			Create a new secondary index on a table
+
+			This command makes an HTTP request to the executive service
+			to create a new index. The index is replicated to each LDB,
+			where it can be queried using LDBReader.GetRowsByIndex.
+
+			Example:
+
+			create-index --family foo --table testtable --index-field foo --unique by_foo
+
+			Resulting schema:
+
+			CREATE UNIQUE INDEX foo___testtable___by_foo ON foo___testtable (foo);
+	`),
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		executive, err := getExecutive(cmd)
+		if err != nil {
+			return err
+		}
+		familyName, err := getFamilyName(cmd)
+		if err != nil {
+			return err
+		}
+		tableName, err := getTableName(cmd)
+		if err != nil {
+			return err
+		}
+		indexFields, err := getIndexFields(cmd)
+		if err != nil {
+			return err
+		}
+		unique, err := cmd.Flags().GetBool(keyUnique)
+		if err != nil {
+			return err
+		}
+		indexName := args[0]
+
+		payload := struct {
+			Fields []string `json:"fields"`
+			Unique bool     `json:"unique"`
+		}{
+			Fields: indexFields,
+			Unique: unique,
+		}
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			bail("could not marshal payload: %s", err)
+		}
+		url := executive + "/families/" + familyName + "/tables/" + tableName + "/indexes/" + indexName
+		req, err := http.NewRequest("POST", url, bytes.NewReader(payloadBytes))
+		if err != nil {
+			bail("could not create request: %s", err)
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			bail("could not make request: %s", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			bailResponse(resp, "could not create index '%s'", indexName)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/segmentio/ctlstore"
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(describeTableCmd)
+	useFlagFamily(describeTableCmd)
+	useFlagTable(describeTableCmd)
+	useFlagLDB(describeTableCmd)
+	useFlagQuiet(describeTableCmd)
+}
+
+// describeTableCmd represents the describe-table command
+var describeTableCmd = &cobra.Command{
+	Use:   "describe-table",
+	Short: "Describes a table in a local LDB",
+	Long: unindent(`
+		Describes a table in a local LDB
+
+		The output of this command will be a table of the fields in column
+		order, with their types and, for primary key fields, their position
+		in the primary key.
+	`),
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ldbPath, err := getLDB(cmd)
+		if err != nil {
+			return err
+		}
+		familyName, err := getFamilyName(cmd)
+		if err != nil {
+			return err
+		}
+		tableName, err := getTableName(cmd)
+		if err != nil {
+			return err
+		}
+		quiet, err := cmd.Flags().GetBool(keyQuiet)
+		if err != nil {
+			return err
+		}
+		reader, err := ctlstore.ReaderForPath(ldbPath)
+		if err != nil {
+			bail("Could not get reader: %s", err)
+		}
+		defer reader.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		ts, err := reader.DescribeTable(ctx, familyName, tableName)
+		if err != nil {
+			bail("Could not describe table: %s", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.TabIndent)
+		if !quiet {
+			fmt.Fprintln(w, "FIELD\tTYPE\tKEY")
+			fmt.Fprintln(w, "-----\t----\t---")
+		}
+		for _, field := range ts.Fields {
+			key := ""
+			for i, pkField := range ts.PrimaryKey.Fields {
+				if pkField == field.Name {
+					key = fmt.Sprint(i + 1)
+				}
+			}
+			typ := schema.FieldTypeStringsByFieldType[field.FieldType]
+			fmt.Fprintf(w, "%s\t%s\t%s\n", field.Name, typ, key)
+		}
+		w.Flush()
+		return nil
+	},
+}
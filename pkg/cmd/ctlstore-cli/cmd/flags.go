@@ -22,6 +22,8 @@ const (
 	keyExecutiveLocationShort = "e"
 	keyFields                 = "field"
 	keyKeyFields              = "key-field"
+	keyIndexFields            = "index-field"
+	keyUnique                 = "unique"
 	keyMaxSize                = "max-size"
 	keyWarnSize               = "warn-size"
 	keyWriter                 = "writer"
@@ -45,6 +47,14 @@ func useFlagKeyFields(cmd *cobra.Command) {
 	cmd.Flags().StringArray(keyKeyFields, nil, "the names of the fields that should serve as primary keys")
 }
 
+func useFlagIndexFields(cmd *cobra.Command) {
+	cmd.Flags().StringArray(keyIndexFields, nil, "the names of the fields that should be indexed, in order")
+}
+
+func useFlagUnique(cmd *cobra.Command) {
+	cmd.Flags().Bool(keyUnique, false, "whether the index should enforce uniqueness")
+}
+
 func useFlagExecutive(cmd *cobra.Command) {
 	cmd.Flags().StringP(keyExecutiveLocation, keyExecutiveLocationShort, executive.DefaultExecutiveURL, "the location of the executive service")
 }
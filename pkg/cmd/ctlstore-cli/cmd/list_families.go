@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/ctlstore"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(listFamiliesCmd)
+	useFlagLDB(listFamiliesCmd)
+}
+
+// listFamiliesCmd represents the list-families command
+var listFamiliesCmd = &cobra.Command{
+	Use:   "list-families",
+	Short: "Lists the families in a local LDB",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ldbPath, err := getLDB(cmd)
+		if err != nil {
+			return err
+		}
+		reader, err := ctlstore.ReaderForPath(ldbPath)
+		if err != nil {
+			bail("Could not get reader: %s", err)
+		}
+		defer reader.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		families, err := reader.ListFamilies(ctx)
+		if err != nil {
+			bail("Could not list families: %s", err)
+		}
+		for _, family := range families {
+			fmt.Println(family)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/ctlstore"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(listTablesCmd)
+	useFlagFamily(listTablesCmd)
+	useFlagLDB(listTablesCmd)
+}
+
+// listTablesCmd represents the list-tables command
+var listTablesCmd = &cobra.Command{
+	Use:   "list-tables",
+	Short: "Lists the tables of a family in a local LDB",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ldbPath, err := getLDB(cmd)
+		if err != nil {
+			return err
+		}
+		familyName, err := getFamilyName(cmd)
+		if err != nil {
+			return err
+		}
+		reader, err := ctlstore.ReaderForPath(ldbPath)
+		if err != nil {
+			bail("Could not get reader: %s", err)
+		}
+		defer reader.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		tables, err := reader.ListTables(ctx, familyName)
+		if err != nil {
+			bail("Could not list tables: %s", err)
+		}
+		for _, table := range tables {
+			fmt.Println(table)
+		}
+		return nil
+	},
+}
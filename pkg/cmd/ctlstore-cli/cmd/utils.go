@@ -59,6 +59,17 @@ func getKeyFields(cmd *cobra.Command) ([]string, error) {
 	return fields, nil
 }
 
+func getIndexFields(cmd *cobra.Command) ([]string, error) {
+	fields, err := cmd.Flags().GetStringArray(keyIndexFields)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("at least one index field required")
+	}
+	return fields, nil
+}
+
 func getFields(cmd *cobra.Command) ([]field, error) {
 	vals, err := cmd.Flags().GetStringArray(keyFields)
 	if err != nil {
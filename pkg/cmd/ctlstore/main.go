@@ -2,11 +2,16 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -18,6 +23,7 @@ import (
 	executivepkg "github.com/segmentio/ctlstore/pkg/executive"
 	heartbeatpkg "github.com/segmentio/ctlstore/pkg/heartbeat"
 	"github.com/segmentio/ctlstore/pkg/ledger"
+	"github.com/segmentio/ctlstore/pkg/metrics"
 	reflectorpkg "github.com/segmentio/ctlstore/pkg/reflector"
 	sidecarpkg "github.com/segmentio/ctlstore/pkg/sidecar"
 	supervisorpkg "github.com/segmentio/ctlstore/pkg/supervisor"
@@ -37,18 +43,34 @@ type dogstatsdConfig struct {
 	FlushEvery time.Duration `conf:"flush-every" help:"Flush AT LEAST this frequently"`
 }
 
+type prometheusConfig struct {
+	Bind string `conf:"bind" help:"Address to serve Prometheus metrics on at /metrics, if any"`
+}
+
 type sidecarConfig struct {
-	BindAddr    string          `conf:"bind-addr" help:"The address and port to bind on"`
-	LDBPath     string          `conf:"ldb-path" help:"The location of the LDB"`
-	MaxRows     int             `conf:"max-rows" help:"Maximum number of rows that can be returned in one response"`
-	Application string          `conf:"application" help:"The name of the application that will be using the sidecar"`
-	Dogstatsd   dogstatsdConfig `conf:"dogstatsd" help:"dogstatsd Configuration"`
+	BindAddr           string           `conf:"bind-addr" help:"The address and port to bind on, or a Unix socket as unix:///path/to.sock"`
+	GRPCBindAddr       string           `conf:"grpc-bind-addr" help:"The address and port to serve gRPC on. It may be the same as bind-addr, but gRPC is served faster from an address of its own"`
+	LDBPath            string           `conf:"ldb-path" help:"The location of the LDB"`
+	ChangelogPath      string           `conf:"changelog-path" help:"Path to the reflector's changelog, which the /changes endpoint streams changes from"`
+	MaxRows            int              `conf:"max-rows" help:"Maximum number of rows that can be returned in one response"`
+	Application        string           `conf:"application" help:"The name of the application that will be using the sidecar"`
+	ACLPath            string           `conf:"acl-path" help:"Path to a JSON ACL limiting the families and tables each client may read"`
+	TLSCert            string           `conf:"tls-cert" help:"Path to a certificate to serve the sidecar over TLS with"`
+	TLSKey             string           `conf:"tls-key" help:"Path to the private key of the TLS certificate"`
+	TLSClientCA        string           `conf:"tls-client-ca" help:"Path to CA certificates which client certificates are required to be signed by"`
+	SocketMode         string           `conf:"socket-mode" help:"The file mode of Unix sockets the sidecar listens on, in octal"`
+	MinSequenceTimeout time.Duration    `conf:"min-sequence-timeout" help:"How long requests may wait for the LDB to apply their minimum sequence"`
+	MaxStreamDuration  time.Duration    `conf:"max-stream-duration" help:"How long prefix scans streamed as NDJSON or over gRPC may take"`
+	StreamAllRows      bool             `conf:"stream-all-rows" help:"Stream all of the rows of prefix scans requested as NDJSON or over gRPC, rather than limiting them to max-rows"`
+	Dogstatsd          dogstatsdConfig  `conf:"dogstatsd" help:"dogstatsd Configuration"`
+	Prometheus         prometheusConfig `conf:"prometheus" help:"Prometheus Configuration"`
 }
 
 type reflectorCliConfig struct {
 	LDBPath               string             `conf:"ldb-path" help:"Path to LDB file" validate:"nonzero"`
 	ChangelogPath         string             `conf:"changelog-path" help:"Path to changelog file"`
 	ChangelogSize         int                `conf:"changelog-size" help:"Maximum size of the changelog file"`
+	ChangelogRowValues    bool               `conf:"changelog-row-values" help:"Write the values of changed rows to the changelog, not just their keys"`
 	UpstreamDriver        string             `conf:"upstream-driver" help:"Upstream driver name (e.g. sqlite3)" validate:"nonzero"`
 	UpstreamDSN           string             `conf:"upstream-dsn" help:"Upstream DSN (e.g. path to file if sqlite3)" validate:"nonzero"`
 	UpstreamLedgerTable   string             `conf:"upstream-ledger-table" help:"Table on the upstream to look for statement ledger"`
@@ -59,25 +81,29 @@ type reflectorCliConfig struct {
 	Debug                 bool               `conf:"debug" help:"Turns on debug logging"`
 	LedgerHealth          ledgerHealthConfig `conf:"ledger-latency" help:"Configure ledger latency behavior"`
 	Dogstatsd             dogstatsdConfig    `conf:"dogstatsd" help:"dogstatsd Configuration"`
+	Prometheus            prometheusConfig   `conf:"prometheus" help:"Prometheus Configuration"`
 }
 
 type executiveCliConfig struct {
-	Bind              string          `conf:"bind" help:"Address for binding the HTTP server" validate:"nonzero"`
-	CtlDBDSN          string          `conf:"ctldb" help:"SQL DSN for ctldb" validate:"nonzero"`
-	Debug             bool            `conf:"debug" help:"Turns on debug logging"`
-	HandlerTimeout    time.Duration   `conf:"handler-timeout" help:"Timeout on request handling"`
-	MaxTableSize      int64           `conf:"max-table-size" help:"Max table size in bytes"`
-	WarnTableSize     int64           `conf:"warn-table-size" help:"Emit a metric when a table sizes grows past this threshold"`
-	WriterLimitPeriod time.Duration   `conf:"writer-limit-period" help:"The period to use for writer-limit"`
-	WriterLimit       int64           `conf:"writer-limit" help:"How many rows a writer may mutate per period"`
-	Shadow            bool            `conf:"shadow" help:"set this to true to emit shadow=true metric tags"`
-	Dogstatsd         dogstatsdConfig `conf:"dogstatsd" help:"dogstatsd Configuration"`
-	EnableClearTables bool            `conf:"enable-clear-tables" help:"Turns on the ability to use the clear table executive endpoint which deletes all rows from a table"`
+	Bind              string           `conf:"bind" help:"Address for binding the HTTP server" validate:"nonzero"`
+	CtlDBDSN          string           `conf:"ctldb" help:"SQL DSN for ctldb" validate:"nonzero"`
+	Debug             bool             `conf:"debug" help:"Turns on debug logging"`
+	HandlerTimeout    time.Duration    `conf:"handler-timeout" help:"Timeout on request handling"`
+	MaxTableSize      int64            `conf:"max-table-size" help:"Max table size in bytes"`
+	WarnTableSize     int64            `conf:"warn-table-size" help:"Emit a metric when a table sizes grows past this threshold"`
+	WriterLimitPeriod time.Duration    `conf:"writer-limit-period" help:"The period to use for writer-limit"`
+	WriterLimit       int64            `conf:"writer-limit" help:"How many rows a writer may mutate per period"`
+	Shadow            bool             `conf:"shadow" help:"set this to true to emit shadow=true metric tags"`
+	Dogstatsd         dogstatsdConfig  `conf:"dogstatsd" help:"dogstatsd Configuration"`
+	Prometheus        prometheusConfig `conf:"prometheus" help:"Prometheus Configuration"`
+	EnableClearTables bool             `conf:"enable-clear-tables" help:"Turns on the ability to use the clear table executive endpoint which deletes all rows from a table"`
 }
 
 // supervisorCliConfig also composes a reflectorCliConfig because it ends up
 // running its own reflector.  The LDBPath will come from the composed
 // reflector config instead of being a top level element in this struct.
+// The reflector's stats are served with the supervisor's, so its own
+// prometheus config is rejected.
 type supervisorCliConfig struct {
 	SnapshotInterval    time.Duration      `conf:"snapshot-interval" help:"Wait time between snapshots" validate:"nonzero"`
 	SnapshotURL         string             `conf:"snapshot-url" help:"URL for snapshot upload (i.e. s3://bucket/key)" validate:"nonzero"`
@@ -86,6 +112,7 @@ type supervisorCliConfig struct {
 	ReflectorConfig     reflectorCliConfig `conf:"reflector" help:"reflector configuration"`
 	Shadow              bool               `conf:"shadow" help:"set this to true to emit shadow=true metric tags"`
 	Dogstatsd           dogstatsdConfig    `conf:"dogstatsd" help:"dogstatsd Configuration"`
+	Prometheus          prometheusConfig   `conf:"prometheus" help:"Prometheus Configuration"`
 }
 
 // ledgerHealthConfig configures the behavior of the container
@@ -103,14 +130,15 @@ type ledgerHealthConfig struct {
 }
 
 type heartbeatCliConfig struct {
-	HeartbeatInterval time.Duration   `conf:"heartbeat-interval" help:"Wait time between heartbeats" validate:"nonzero"`
-	ExecutiveURL      string          `conf:"executive-url" help:"URL for the executive API" validate:"nonzero"`
-	FamilyName        string          `conf:"family-name" help:"The family name" validate:"nonzero"`
-	TableName         string          `conf:"table-name" help:"The table name" validate:"nonzero"`
-	WriterName        string          `conf:"writer-name" help:"Writer name" validate:"nonzero"`
-	WriterSecret      string          `conf:"writer-secret" help:"Writer secret" validate:"nonzero"`
-	Debug             bool            `conf:"debug" help:"Turns on debug logging"`
-	Dogstatsd         dogstatsdConfig `conf:"dogstatsd" help:"dogstatsd Configuration"`
+	HeartbeatInterval time.Duration    `conf:"heartbeat-interval" help:"Wait time between heartbeats" validate:"nonzero"`
+	ExecutiveURL      string           `conf:"executive-url" help:"URL for the executive API" validate:"nonzero"`
+	FamilyName        string           `conf:"family-name" help:"The family name" validate:"nonzero"`
+	TableName         string           `conf:"table-name" help:"The table name" validate:"nonzero"`
+	WriterName        string           `conf:"writer-name" help:"Writer name" validate:"nonzero"`
+	WriterSecret      string           `conf:"writer-secret" help:"Writer secret" validate:"nonzero"`
+	Debug             bool             `conf:"debug" help:"Turns on debug logging"`
+	Dogstatsd         dogstatsdConfig  `conf:"dogstatsd" help:"dogstatsd Configuration"`
+	Prometheus        prometheusConfig `conf:"prometheus" help:"Prometheus Configuration"`
 }
 
 type siteConfig struct {
@@ -199,46 +227,71 @@ func defaultDogstatsdConfig() dogstatsdConfig {
 	}
 }
 
-type dogstatsdOpts struct {
-	config            dogstatsdConfig
+type statsOpts struct {
+	dogstatsd         dogstatsdConfig
+	prometheus        prometheusConfig
 	statsPrefix       string
 	defaultTags       []stats.Tag
 	defaultTagFilters []string
 }
 
-func configureDogstatsd(ctx context.Context, opts dogstatsdOpts) (dd *datadog.Client, teardown func()) {
-	config := opts.config
-	if config.Address != "" {
-		if opts.statsPrefix == "" {
-			panic("configureDogstatsd: Invalid statsPrefix passed. Stop.")
+// configureStats reports stats to dogstatsd and serves them to Prometheus,
+// if either is configured, and returns the handler they are reported to. An
+// error is returned if the Prometheus address can't be bound.
+func configureStats(ctx context.Context, opts statsOpts) (handler stats.Handler, teardown func(), err error) {
+	config := opts.dogstatsd
+	if config.Address == "" && opts.prometheus.Bind == "" {
+		// nothing to be done for teardown here
+		return nil, func() {}, nil
+	}
+	if opts.statsPrefix == "" {
+		panic("configureStats: Invalid statsPrefix passed. Stop.")
+	}
+	var promLis net.Listener
+	if opts.prometheus.Bind != "" {
+		// bound before anything is registered, so that a bad address fails
+		// startup rather than leaving the metrics unserved
+		promLis, err = net.Listen("tcp", opts.prometheus.Bind)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "listen for prometheus metrics")
 		}
+	}
 
-		dd = datadog.NewClientWith(datadog.ClientConfig{
+	stats.DefaultEngine.Prefix = fmt.Sprintf("ctlstore.%s", opts.statsPrefix)
+	stats.DefaultEngine.Tags = append(stats.DefaultEngine.Tags, stats.Tag{Name: "version", Value: ctlstore.Version})
+	for _, t := range opts.defaultTags {
+		stats.DefaultEngine.Tags = append(stats.DefaultEngine.Tags, t)
+	}
+	stats.DefaultEngine.Tags = stats.SortTags(stats.DefaultEngine.Tags) // tags must be sorted
+
+	if config.Address != "" {
+		dd := datadog.NewClientWith(datadog.ClientConfig{
 			Address:    config.Address,
 			BufferSize: config.BufferSize,
 			Filters:    opts.defaultTagFilters,
 		})
 		stats.Register(dd)
-		stats.DefaultEngine.Prefix = fmt.Sprintf("ctlstore.%s", opts.statsPrefix)
-		stats.DefaultEngine.Tags = append(stats.DefaultEngine.Tags, stats.Tag{Name: "version", Value: ctlstore.Version})
-		for _, t := range opts.defaultTags {
-			stats.DefaultEngine.Tags = append(stats.DefaultEngine.Tags, t)
-		}
-		stats.DefaultEngine.Tags = stats.SortTags(stats.DefaultEngine.Tags) // tags must be sorted
-
-		c := procstats.StartCollector(procstats.NewGoMetrics())
 
 		events.Log("Setup dogstatsd with addr:%{addr}s, buffersize:%{buffersize}d, prefix:%{pfx}s, version:%{version}s",
 			config.Address, config.BufferSize, opts.statsPrefix, ctlstore.Version)
 
 		go utils.CtxLoop(ctx, config.FlushEvery, stats.Flush)
-		return dd, func() {
-			c.Close()
-			stats.Flush()
-		}
 	}
-	// nothing to be done for teardown here
-	return dd, func() {}
+	if promLis != nil {
+		ph := metrics.NewPrometheusHandler()
+		stats.Register(ph)
+		go func() {
+			if err := metrics.ServePrometheus(ctx, promLis, ph); err != nil {
+				events.Log("Prometheus metrics stopped: %{error}+v", err)
+			}
+		}()
+	}
+
+	c := procstats.StartCollector(procstats.NewGoMetrics())
+	return stats.DefaultEngine.Handler, func() {
+		c.Close()
+		stats.Flush()
+	}, nil
 }
 
 func ldbReadKey(_ context.Context, args []string) {
@@ -270,16 +323,24 @@ func supervisor(ctx context.Context, args []string) {
 		if cliCfg.Debug {
 			enableDebug()
 		}
+		if cliCfg.ReflectorConfig.Prometheus.Bind != "" {
+			// the supervisor's reflector reports to the supervisor's stats
+			return errors.New("reflector.prometheus.bind is not supported by the supervisor, use prometheus.bind instead")
+		}
 
 		shadow := "false"
 		if cliCfg.Shadow {
 			shadow = "true"
 		}
-		_, teardown := configureDogstatsd(ctx, dogstatsdOpts{
-			config:      cliCfg.Dogstatsd,
+		_, teardown, err := configureStats(ctx, statsOpts{
+			dogstatsd:   cliCfg.Dogstatsd,
+			prometheus:  cliCfg.Prometheus,
 			statsPrefix: "supervisor",
 			defaultTags: []stats.Tag{stats.T("shadow", shadow)},
 		})
+		if err != nil {
+			return errors.Wrap(err, "configure stats")
+		}
 		defer teardown()
 		if err := utils.EnsureDirForFile(cliCfg.ReflectorConfig.LDBPath); err != nil {
 			return errors.Wrap(err, "ensure ldb dir")
@@ -347,10 +408,16 @@ func heartbeat(ctx context.Context, args []string) {
 	if cliCfg.Debug {
 		enableDebug()
 	}
-	_, teardown := configureDogstatsd(ctx, dogstatsdOpts{
-		config:      cliCfg.Dogstatsd,
+	_, teardown, err := configureStats(ctx, statsOpts{
+		dogstatsd:   cliCfg.Dogstatsd,
+		prometheus:  cliCfg.Prometheus,
 		statsPrefix: "heartbeat",
 	})
+	if err != nil {
+		events.Log("Fatal error starting heartbeat: %+v", err)
+		errs.IncrDefault(stats.T("op", "startup"))
+		return
+	}
 	defer teardown()
 	heartbeat, err := heartbeatpkg.HeartbeatFromConfig(heartbeatpkg.HeartbeatConfig{
 		HeartbeatInterval: cliCfg.HeartbeatInterval,
@@ -395,11 +462,17 @@ func executive(ctx context.Context, args []string) {
 		shadow = "true"
 	}
 
-	_, teardown := configureDogstatsd(ctx, dogstatsdOpts{
-		config:      cliCfg.Dogstatsd,
+	_, teardown, err := configureStats(ctx, statsOpts{
+		dogstatsd:   cliCfg.Dogstatsd,
+		prometheus:  cliCfg.Prometheus,
 		statsPrefix: "executive",
 		defaultTags: []stats.Tag{stats.T("shadow", shadow)},
 	})
+	if err != nil {
+		errs.IncrDefault(stats.T("op", "startup"))
+		events.Log("Fatal error starting Executive: %{error}+v", err)
+		return
+	}
 	defer teardown()
 
 	executive, err := executivepkg.ExecutiveServiceFromConfig(executivepkg.ExecutiveServiceConfig{
@@ -432,14 +505,20 @@ func sidecar(ctx context.Context, args []string) {
 		Dogstatsd: defaultDogstatsdConfig(),
 	}
 	loadConfig(&config, "sidecar", args)
-	dd, teardown := configureDogstatsd(ctx, dogstatsdOpts{
-		config:            config.Dogstatsd,
+	handler, teardown, err := configureStats(ctx, statsOpts{
+		dogstatsd:         config.Dogstatsd,
+		prometheus:        config.Prometheus,
 		statsPrefix:       "sidecar",
 		defaultTagFilters: []string{},
 	})
+	if err != nil {
+		events.Log("Fatal error starting sidecar: %{error}+v", err)
+		errs.IncrDefault(stats.T("op", "startup"))
+		return
+	}
 	defer teardown()
-	if dd != nil {
-		ctlstore.Initialize(ctx, "ctlstore-sidecar", dd)
+	if handler != nil {
+		ctlstore.Initialize(ctx, "ctlstore-sidecar", handler)
 	}
 	sidecar, err := newSidecar(config)
 	if err != nil {
@@ -447,7 +526,9 @@ func sidecar(ctx context.Context, args []string) {
 		errs.IncrDefault(stats.T("op", "startup"))
 		return
 	}
-	sidecar.Start(ctx)
+	if err := sidecar.Start(ctx); err != nil {
+		events.Log("Sidecar stopped: %{error}+v", err)
+	}
 }
 
 func reflector(ctx context.Context, args []string) {
@@ -456,10 +537,16 @@ func reflector(ctx context.Context, args []string) {
 	if cliCfg.Debug {
 		enableDebug()
 	}
-	_, teardown := configureDogstatsd(ctx, dogstatsdOpts{
-		config:      cliCfg.Dogstatsd,
+	_, teardown, err := configureStats(ctx, statsOpts{
+		dogstatsd:   cliCfg.Dogstatsd,
+		prometheus:  cliCfg.Prometheus,
 		statsPrefix: "reflector",
 	})
+	if err != nil {
+		events.Log("Fatal error starting Reflector: %{error}+v", err)
+		errs.IncrDefault(stats.T("op", "startup"))
+		return
+	}
 	defer teardown()
 	reflector, err := newReflector(cliCfg, false)
 	if err != nil {
@@ -512,24 +599,72 @@ func newSidecar(config sidecarConfig) (*sidecarpkg.Sidecar, error) {
 	if err != nil {
 		return nil, err
 	}
+	var acl *sidecarpkg.ACL
+	if config.ACLPath != "" {
+		if acl, err = sidecarpkg.LoadACL(config.ACLPath); err != nil {
+			return nil, err
+		}
+	}
+	tlsConfig, err := newSidecarTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	var socketMode uint64
+	if config.SocketMode != "" {
+		if socketMode, err = strconv.ParseUint(config.SocketMode, 8, 32); err != nil {
+			return nil, errors.Wrap(err, "parse socket mode")
+		}
+	}
 	return sidecarpkg.New(sidecarpkg.Config{
-		BindAddr:    config.BindAddr,
-		Reader:      reader,
-		MaxRows:     config.MaxRows,
-		Application: config.Application,
+		BindAddr:           config.BindAddr,
+		Reader:             reader,
+		MaxRows:            config.MaxRows,
+		Application:        config.Application,
+		ChangelogPath:      config.ChangelogPath,
+		GRPCBindAddr:       config.GRPCBindAddr,
+		ACL:                acl,
+		TLSConfig:          tlsConfig,
+		SocketMode:         os.FileMode(socketMode),
+		MinSequenceTimeout: config.MinSequenceTimeout,
+		MaxStreamDuration:  config.MaxStreamDuration,
+		StreamAllRows:      config.StreamAllRows,
 	})
 }
 
+func newSidecarTLSConfig(config sidecarConfig) (*tls.Config, error) {
+	if config.TLSCert == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(config.TLSCert, config.TLSKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "load tls certificate")
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if config.TLSClientCA != "" {
+		pem, err := ioutil.ReadFile(config.TLSClientCA)
+		if err != nil {
+			return nil, errors.Wrap(err, "read tls client ca")
+		}
+		tlsConfig.ClientCAs = x509.NewCertPool()
+		if !tlsConfig.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in tls client ca")
+		}
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
 func newReflector(cliCfg reflectorCliConfig, isSupervisor bool) (*reflectorpkg.Reflector, error) {
 	if cliCfg.LedgerHealth.Disable {
 		events.Log("DEPRECATION NOTICE: use --disable-ecs-behavior instead of --disable to control this ledger monitor behavior")
 	}
 	return reflectorpkg.ReflectorFromConfig(reflectorpkg.ReflectorConfig{
-		LDBPath:       cliCfg.LDBPath,
-		ChangelogPath: cliCfg.ChangelogPath,
-		ChangelogSize: cliCfg.ChangelogSize,
-		BootstrapURL:  cliCfg.BootstrapURL,
-		IsSupervisor:  isSupervisor,
+		LDBPath:            cliCfg.LDBPath,
+		ChangelogPath:      cliCfg.ChangelogPath,
+		ChangelogSize:      cliCfg.ChangelogSize,
+		ChangelogRowValues: cliCfg.ChangelogRowValues,
+		BootstrapURL:       cliCfg.BootstrapURL,
+		IsSupervisor:       isSupervisor,
 		LedgerHealth: ledger.HealthConfig{
 			DisableECSBehavior:      cliCfg.LedgerHealth.Disable || cliCfg.LedgerHealth.DisableECSBehavior,
 			MaxHealthyLatency:       cliCfg.LedgerHealth.MaxHealthyLatency,
@@ -2,22 +2,34 @@ package event
 
 // entry represents a single row in the changelog
 // e.g.
-//   {"seq":1,"family":"fam","table":"foo","key":[{"name":"id","type":"int","value":1}]}
+//   {"seq":1,"family":"fam","table":"foo","key":[{"name":"id","type":"int","value":1}],"ledger_seq":42,"ledger_last":true,"op":"insert","new":{"id":1}}
 type entry struct {
-	Seq    int64  `json:"seq"`
-	Family string `json:"family"`
-	Table  string `json:"table"`
-	Key    []Key  `json:"key"`
+	Seq         int64  `json:"seq"`
+	Family      string `json:"family"`
+	Table       string `json:"table"`
+	Key         []Key  `json:"key"`
+	LedgerSeq   int64  `json:"ledger_seq,omitempty"`
+	LedgerIndex int    `json:"ledger_index,omitempty"`
+	LedgerLast  bool   `json:"ledger_last,omitempty"`
+	Op          string `json:"op,omitempty"`
+	Old         Row    `json:"old,omitempty"`
+	New         Row    `json:"new,omitempty"`
 }
 
 // event converts the entry into an event for the iterator to return
 func (e entry) event() Event {
 	return Event{
-		Sequence: e.Seq,
+		Sequence:       e.Seq,
+		LedgerSequence: e.LedgerSeq,
+		LedgerIndex:    e.LedgerIndex,
+		LedgerLast:     e.LedgerLast,
 		RowUpdate: RowUpdate{
 			FamilyName: e.Family,
 			TableName:  e.Table,
 			Keys:       e.Key,
+			Op:         e.Op,
+			OldRow:     e.Old,
+			NewRow:     e.New,
 		},
 	}
 }
@@ -1,16 +1,51 @@
 package event
 
+import (
+	"bytes"
+	"encoding/json"
+)
+
 // Event is the type that the Iterator produces
 type Event struct {
-	Sequence  int64
-	RowUpdate RowUpdate
+	Sequence int64
+	// LedgerSequence is the ledger sequence of the statement which made the
+	// change, and LedgerIndex the position of the change among those the
+	// statement made. Unlike Sequence, they are kept when the reflector
+	// restarts, but they're zero for changelogs written by older reflectors.
+	// LedgerLast is set on the last change the statement made, after which
+	// all of its changes have been read.
+	LedgerSequence int64
+	LedgerIndex    int
+	LedgerLast     bool
+	RowUpdate      RowUpdate
 }
 
-// RowUpdate represents a single row update
+// RowUpdate represents a single row update. Op is one of the changelog.Op*
+// constants, and along with OldRow and NewRow is only populated if the
+// changelog was written by a reflector which records row values.
 type RowUpdate struct {
 	FamilyName string `json:"family"`
 	TableName  string `json:"table"`
 	Keys       []Key  `json:"keys"`
+	Op         string `json:"op,omitempty"`
+	OldRow     Row    `json:"old,omitempty"` // nil for inserts
+	NewRow     Row    `json:"new,omitempty"` // nil for deletes
+}
+
+// Row holds the values of a row keyed by column name. Since the changelog
+// is JSON, numbers are decoded as json.Number so that large integers
+// survive the trip, and binary values are base64 encoded strings.
+type Row map[string]interface{}
+
+func (r *Row) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return err
+	}
+	*r = m
+	return nil
 }
 
 // Key represents a single primary key column value and metadata
@@ -3,6 +3,7 @@ package event
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
@@ -52,6 +53,7 @@ func TestIteratorIntegration(t *testing.T) {
 		ChangelogWriter: changeLogWriter,
 		DB:              db,
 		ChangeBuffer:    changeBuffer,
+		RowValues:       true,
 	}
 
 	const numChanges = 50
@@ -70,10 +72,16 @@ func TestIteratorIntegration(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, iter)
 
+	var ledgerSeq int64
 	for i := 0; i < numChanges; i++ {
 		e, err := iter.Next(ctx)
 		require.NoError(t, err)
 		require.EqualValues(t, i+1, e.Sequence)
+		// each change is made by a statement of its own
+		require.Greater(t, e.LedgerSequence, ledgerSeq)
+		require.Zero(t, e.LedgerIndex)
+		require.True(t, e.LedgerLast)
+		ledgerSeq = e.LedgerSequence
 		update := e.RowUpdate
 		require.Equal(t, "fam", update.FamilyName)
 		require.Equal(t, "foo", update.TableName)
@@ -83,6 +91,12 @@ func TestIteratorIntegration(t *testing.T) {
 			Type:  "int",
 			Value: float64(i),
 		}}, keys)
+		require.Equal(t, changelogpkg.OpInsert, update.Op)
+		require.Nil(t, update.OldRow)
+		require.EqualValues(t, Row{
+			"id":  json.Number(fmt.Sprint(i)),
+			"val": "hello",
+		}, update.NewRow)
 
 	}
 
@@ -229,6 +229,109 @@ func (e *dbExecutive) AddFields(familyName string, tableName string, fieldNames
 	return nil
 }
 
+func (e *dbExecutive) CreateIndex(familyName string, tableName string, indexName string, fieldNames []string, unique bool) error {
+	ctx, cancel := e.ctx()
+	defer cancel()
+
+	famName, err := schema.NewFamilyName(familyName)
+	if err != nil {
+		return err
+	}
+
+	tblName, err := schema.NewTableName(tableName)
+	if err != nil {
+		return err
+	}
+
+	idxName, err := schema.NewIndexName(indexName)
+	if err != nil {
+		return &errs.BadRequestError{Err: err.Error()}
+	}
+
+	fns := make([]schema.FieldName, len(fieldNames))
+	for i, fieldName := range fieldNames {
+		fns[i], err = schema.NewFieldName(fieldName)
+		if err != nil {
+			return &errs.BadRequestError{Err: err.Error()}
+		}
+	}
+
+	_, ok, err := e.fetchFamilyByName(famName)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &errs.NotFoundError{Err: "Family not found"}
+	}
+
+	tbl, ok, err := e.fetchMetaTableByName(famName, tblName)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &errs.NotFoundError{Err: "Table not found"}
+	}
+
+	ddl, err := tbl.CreateIndexDDL(idxName, fns, unique)
+	if err != nil {
+		return &errs.BadRequestError{Err: err.Error()}
+	}
+
+	dmlLogTbl, err := tbl.ForDriver(ldb.LDBDatabaseDriver)
+	if err != nil {
+		return err
+	}
+
+	logDDL, err := dmlLogTbl.CreateIndexDDL(idxName, fns, unique)
+	if err != nil {
+		return err
+	}
+
+	events.Debug("[CreateIndex %{indexName}s] ctldb DDL: %{ddl}s", indexName, ddl)
+	events.Debug("[CreateIndex %{indexName}s] log DDL: %{ddl}s", indexName, logDDL)
+
+	tx, err := e.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	dlw := dmlLedgerWriter{
+		Tx:        tx,
+		TableName: dmlLedgerTableName,
+	}
+	defer dlw.Close()
+
+	// Creating the index in the ctldb first means that a unique index which
+	// the existing rows violate is rejected before it reaches the ledger.
+	_, err = e.DB.ExecContext(ctx, ddl)
+	if err != nil {
+		if strings.Index(err.Error(), "Error 1061:") == 0 || // mysql
+			strings.Contains(err.Error(), "already exists") { // sqlite
+			return &errs.ConflictError{Err: "Index already exists"}
+		}
+		if strings.Index(err.Error(), "Error 1062:") == 0 || // mysql
+			strings.Contains(err.Error(), "UNIQUE constraint failed") { // sqlite
+			return &errs.ConflictError{Err: "Existing rows violate unique index"}
+		}
+		return err
+	}
+
+	seq, err := dlw.Add(ctx, logDDL)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	events.Log("Successfully created new index `%{indexName}s` on table %{tableName}s at seq %{seq}v", indexName, tableName, seq)
+
+	return nil
+}
+
 func (e *dbExecutive) GetWriterCookie(writerName string, writerSecret string) ([]byte, error) {
 	ctx, cancel := e.ctx()
 	defer cancel()
@@ -294,41 +397,41 @@ func (e *dbExecutive) Mutate(
 	familyName string,
 	cookie []byte,
 	checkCookie []byte,
-	requests []ExecutiveMutationRequest) error {
+	requests []ExecutiveMutationRequest) (res ExecutiveMutationResult, err error) {
 
 	ctx, cancel := e.ctx()
 	defer cancel()
 
 	// Reject requests that are too large
 	if len(requests) > limits.LimitMaxMutateRequestCount {
-		return &errs.PayloadTooLargeError{Err: "Number of requests exceeds maximum"}
+		return res, &errs.PayloadTooLargeError{Err: "Number of requests exceeds maximum"}
 	}
 
 	famName, err := schema.NewFamilyName(familyName)
 	if err != nil {
-		return err
+		return res, err
 	}
 
 	wn, err := schema.NewWriterName(writerName)
 	if err != nil {
-		return err
+		return res, err
 	}
 
 	reqset, err := newMutationRequestSet(famName, requests)
 	if err != nil {
-		return err
+		return res, err
 	}
 
 	// Validate table names
 	tblNames := reqset.TableNames()
 	tbls, err := e.fetchMetaTablesByName(famName, tblNames)
 	if err != nil {
-		return errors.Wrap(err, "fetch meta tables error")
+		return res, errors.Wrap(err, "fetch meta tables error")
 	}
 
 	for _, tblName := range tblNames {
 		if _, ok := tbls[tblName]; !ok {
-			return errors.Errorf("Table not found: %s", tblName)
+			return res, errors.Errorf("Table not found: %s", tblName)
 		}
 	}
 
@@ -338,7 +441,7 @@ func (e *dbExecutive) Mutate(
 	// dope, y'all.
 	tx, err := e.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return errors.Wrap(err, "begin tx error")
+		return res, errors.Wrap(err, "begin tx error")
 	}
 	defer tx.Rollback()
 
@@ -349,10 +452,10 @@ func (e *dbExecutive) Mutate(
 		requests:   requests,
 	})
 	if err != nil {
-		return err
+		return res, err
 	}
 	if !allowed {
-		return &errs.RateLimitExceededErr{Err: "rate limit exceeded"}
+		return res, &errs.RateLimitExceededErr{Err: "rate limit exceeded"}
 	}
 
 	// For the ledger to behave as we expect, an exclusive lock needs to be held
@@ -376,7 +479,7 @@ func (e *dbExecutive) Mutate(
 	// not part of the SQL standard.
 	_, err = tx.ExecContext(ctx, "UPDATE locks SET clock = clock + 1 WHERE id = 'ledger'")
 	if err != nil {
-		return errors.Wrap(err, "taking ledger lock")
+		return res, errors.Wrap(err, "taking ledger lock")
 	}
 
 	// Check Cookie
@@ -391,7 +494,7 @@ func (e *dbExecutive) Mutate(
 	// GetWriterCookie endpoint.
 	err = ms.Update(wn, writerSecret, cookie, checkCookie)
 	if err != nil {
-		return err
+		return res, err
 	}
 
 	// Now apply all the requests
@@ -405,14 +508,14 @@ func (e *dbExecutive) Mutate(
 	// markers must be added into the log. The reflector uses these
 	// markers to know when the transaction should be started and
 	// committed as it tails the log.
+	var firstSeq, lastSeq schema.DMLSequence
 	if len(reqset.Requests) > 1 {
-		_, err := dlw.BeginTx(ctx)
+		firstSeq, err = dlw.BeginTx(ctx)
 		if err != nil {
-			return errors.Wrap(err, "logging tx begin failed")
+			return res, errors.Wrap(err, "logging tx begin failed")
 		}
 	}
 
-	var lastSeq schema.DMLSequence
 	for _, req := range reqset.Requests {
 		// TODO: wrap errors in here by request index
 		tbl := tbls[req.TableName]
@@ -425,54 +528,57 @@ func (e *dbExecutive) Mutate(
 			// UPSERT
 			values, err = req.valuesByOrder(tbl.FieldNames())
 			if err != nil {
-				return err
+				return res, err
 			}
 
 			dmlSQL, err = tbl.UpsertDML(values)
 			if err != nil {
-				return err
+				return res, err
 			}
 		} else {
 			// DELETE
 			values, err = req.valuesByOrder(tbl.KeyFields.Fields)
 			if err != nil {
-				return err
+				return res, err
 			}
 
 			dmlSQL, err = tbl.DeleteDML(values)
 			if err != nil {
-				return err
+				return res, err
 			}
 		}
 
 		if len(dmlSQL) > limits.LimitMaxDMLSize {
-			return &errs.BadRequestError{Err: "Request generated too large of a DML statement"}
+			return res, &errs.BadRequestError{Err: "Request generated too large of a DML statement"}
 		}
 
 		// Execute the actual DML write
 		_, err = tx.ExecContext(ctx, dmlSQL)
 		if err != nil {
 			events.Log("dml exec error, Request: %{req}+v SQL: %{sql}s", req, dmlSQL)
-			return errors.Wrap(err, "dml exec error")
+			return res, errors.Wrap(err, "dml exec error")
 		}
 
 		// Now record it in the log table
 		lastSeq, err = dlw.Add(ctx, dmlSQL)
 		if err != nil {
-			return errors.Wrap(err, "log write error")
+			return res, errors.Wrap(err, "log write error")
+		}
+		if firstSeq == 0 {
+			firstSeq = lastSeq
 		}
 	}
 
 	if len(reqset.Requests) > 1 {
 		lastSeq, err = dlw.CommitTx(ctx)
 		if err != nil {
-			return errors.Wrap(err, "logging tx commit failed")
+			return res, errors.Wrap(err, "logging tx commit failed")
 		}
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		return errors.Wrap(err, "commit failed")
+		return res, errors.Wrap(err, "commit failed")
 	}
 
 	events.Debug(
@@ -486,7 +592,9 @@ func (e *dbExecutive) Mutate(
 		writerName,
 	)
 
-	return nil
+	res.FirstSequence = firstSeq
+	res.LastSequence = lastSeq
+	return res, nil
 }
 
 func (e *dbExecutive) fetchMetaTablesByName(famName schema.FamilyName, tblNames []schema.TableName) (map[schema.TableName]sqlgen.MetaTable, error) {
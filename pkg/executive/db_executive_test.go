@@ -118,6 +118,7 @@ func TestAllDBExecutive(t *testing.T) {
 		"testDBExecutiveCreateFamily":         testDBExecutiveCreateFamily,
 		"testDBExecutiveCreateTable":          testDBExecutiveCreateTable,
 		"testDBExecutiveAddFields":            testDBExecutiveAddFields,
+		"testDBExecutiveCreateIndex":          testDBExecutiveCreateIndex,
 		"testDBExecutiveFetchFamilyByName":    testDBExecutiveFetchFamilyByName,
 		"testDBExecutiveMutate":               testDBExecutiveMutate,
 		"testDBExecutiveGetWriterCookie":      testDBExecutiveGetWriterCookie,
@@ -337,6 +338,64 @@ func testDBExecutiveAddFields(t *testing.T, dbType string) {
 	}
 }
 
+func testDBExecutiveCreateIndex(t *testing.T, dbType string) {
+	u := newDbExecTestUtil(t, dbType)
+	defer u.Close()
+
+	err := u.e.CreateTable("family1",
+		"table2",
+		[]string{"field1", "field2", "field3"},
+		[]schema.FieldType{schema.FTString, schema.FTInteger, schema.FTText},
+		[]string{"field1"},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error calling CreateTable: %+v", err)
+	}
+
+	_, err = u.db.Exec(`INSERT INTO family1___table2 (field1, field2, field3)
+		VALUES ('a', 1, 'x'), ('b', 1, 'y')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = u.e.CreateIndex("family1", "table2", "by_field2", []string{"field2"}, false)
+	if err != nil {
+		t.Fatalf("Unexpected error calling CreateIndex: %+v", err)
+	}
+
+	logRow := u.db.QueryRow("SELECT statement FROM " + dmlLedgerTableName + " ORDER BY seq DESC LIMIT 1")
+	var rowStatement string
+	err = logRow.Scan(&rowStatement)
+	if err != nil {
+		t.Fatalf("Unexpected error: %+v", err)
+	}
+	want := `CREATE INDEX family1___table2___by_field2 ON family1___table2 ("field2")`
+	if got := rowStatement; want != got {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	for _, test := range []struct {
+		desc   string
+		table  string
+		index  string
+		fields []string
+		unique bool
+		err    string
+	}{
+		{"duplicate index", "table2", "by_field2", []string{"field2"}, false, "Index already exists"},
+		{"violates unique", "table2", "uniq_field2", []string{"field2"}, true, "Existing rows violate unique index"},
+		{"missing table", "table3", "by_field2", []string{"field2"}, false, "Table not found"},
+		{"missing field", "table2", "by_field4", []string{"field4"}, false, "Index field 'field4' not specified as a field"},
+		{"unindexable field", "table2", "by_field3", []string{"field3"}, false, "Fields of type 'text' cannot be an index field"},
+		{"no fields", "table2", "by_nothing", nil, false, "index must have at least one field"},
+	} {
+		err = u.e.CreateIndex("family1", test.table, test.index, test.fields, test.unique)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%s: unexpected error calling CreateIndex: %+v", test.desc, err)
+		}
+	}
+}
+
 func testDBExecutiveCreateTable(t *testing.T, dbType string) {
 	u := newDbExecTestUtil(t, dbType)
 	defer u.Close()
@@ -1022,7 +1081,7 @@ func testDBExecutiveMutate(t *testing.T, dbType string) {
 				cookie = []byte{2}
 			}
 
-			err := u.e.Mutate(writerName, "", "family1", cookie, testCase.checkCookie, testCase.reqs)
+			res, err := u.e.Mutate(writerName, "", "family1", cookie, testCase.checkCookie, testCase.reqs)
 
 			if err != nil {
 				if testCase.expectErr != nil {
@@ -1038,6 +1097,21 @@ func testDBExecutiveMutate(t *testing.T, dbType string) {
 				}
 			}
 
+			if testCase.expectDML != nil && err == nil {
+				// the mutation result should span the whole batch in the ledger
+				var firstSeq, lastSeq int64
+				row := u.db.QueryRow("SELECT MIN(seq), MAX(seq) FROM " + dmlLedgerTableName)
+				if err := row.Scan(&firstSeq, &lastSeq); err != nil {
+					t.Fatalf("Unexpected error scanning: %+v", err)
+				}
+				if want, got := firstSeq, res.FirstSequence.Int(); want != got {
+					t.Errorf("Expected first sequence %v, got %v", want, got)
+				}
+				if want, got := lastSeq, res.LastSequence.Int(); want != got {
+					t.Errorf("Expected last sequence %v, got %v", want, got)
+				}
+			}
+
 			if testCase.expectDML != nil {
 				rows, err := u.db.Query(
 					"SELECT statement FROM " +
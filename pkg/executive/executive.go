@@ -17,13 +17,22 @@ type ExecutiveMutationRequest struct {
 	Values    map[string]interface{}
 }
 
+// ExecutiveMutationResult describes the range of the DML ledger that a
+// batch of mutations was committed to. Readers can wait for LastSequence
+// to show up in their LDB to read their own writes.
+type ExecutiveMutationResult struct {
+	FirstSequence schema.DMLSequence
+	LastSequence  schema.DMLSequence
+}
+
 //go:generate counterfeiter -o fakes/executive_interface.go . ExecutiveInterface
 type ExecutiveInterface interface {
 	CreateFamily(familyName string) error
 	CreateTable(familyName string, tableName string, fieldNames []string, fieldTypes []schema.FieldType, keyFields []string) error
 	AddFields(familyName string, tableName string, fieldNames []string, fieldTypes []schema.FieldType) error
+	CreateIndex(familyName string, tableName string, indexName string, fieldNames []string, unique bool) error
 
-	Mutate(writerName string, writerSecret string, familyName string, cookie []byte, checkCookie []byte, requests []ExecutiveMutationRequest) error
+	Mutate(writerName string, writerSecret string, familyName string, cookie []byte, checkCookie []byte, requests []ExecutiveMutationRequest) (ExecutiveMutationResult, error)
 	GetWriterCookie(writerName string, writerSecret string) ([]byte, error)
 	SetWriterCookie(writerName string, writerSecret string, cookie []byte) error
 	RegisterWriter(writerName string, writerSecret string) error
@@ -106,6 +106,36 @@ func (ee *ExecutiveEndpoint) handleTableRoute(w http.ResponseWriter, r *http.Req
 	}
 }
 
+func (ee *ExecutiveEndpoint) handleIndexRoute(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	familyName := vars["familyName"]
+	tableName := vars["tableName"]
+	indexName := vars["indexName"]
+
+	rawBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeErrorResponse(err, w)
+		return
+	}
+
+	payload := struct {
+		Fields []string `json:"fields"`
+		Unique bool     `json:"unique"`
+	}{}
+
+	err = json.Unmarshal(rawBody, &payload)
+	if err != nil {
+		writeErrorResponse(&errs.BadRequestError{Err: "JSON Error: " + err.Error()}, w)
+		return
+	}
+
+	err = ee.Exec.CreateIndex(familyName, tableName, indexName, payload.Fields, payload.Unique)
+	if err != nil {
+		writeErrorResponse(err, w)
+		return
+	}
+}
+
 func (ee *ExecutiveEndpoint) handleCookieRoute(w http.ResponseWriter, r *http.Request) {
 	hdrWriter := r.Header.Get("ctlstore-writer")
 	hdrSecret := r.Header.Get("ctlstore-secret")
@@ -216,7 +246,7 @@ func (ee *ExecutiveEndpoint) handleMutationsRoute(w http.ResponseWriter, r *http
 
 		stats.Add("mutation-values-received", totalValues, stats.T("writer", hdrWriter))
 
-		err = ee.Exec.Mutate(
+		res, err := ee.Exec.Mutate(
 			hdrWriter,
 			hdrSecret,
 			familyName,
@@ -224,12 +254,26 @@ func (ee *ExecutiveEndpoint) handleMutationsRoute(w http.ResponseWriter, r *http
 			payload.CheckCookie,
 			unpackedReqs)
 
-		_ = err
+		if err != nil {
+			writeErrorResponse(err, w)
+			return
+		}
 
+		// Tells the writer which range of the ledger the mutations landed
+		// in, so that readers can wait for the last sequence to show up
+		// in their LDB.
+		resBody, err := json.Marshal(struct {
+			FirstSeq int64 `json:"first_seq"`
+			LastSeq  int64 `json:"last_seq"`
+		}{
+			FirstSeq: res.FirstSequence.Int(),
+			LastSeq:  res.LastSequence.Int(),
+		})
 		if err != nil {
 			writeErrorResponse(err, w)
 			return
 		}
+		_, _ = w.Write(resBody)
 	}
 }
 
@@ -282,6 +326,7 @@ func (ee *ExecutiveEndpoint) Handler() http.Handler {
 	r.HandleFunc("/cookie", ee.handleCookieRoute).Methods("GET", "POST")
 	r.HandleFunc("/families/{familyName}", ee.handleFamilyRoute).Methods("POST")
 	r.HandleFunc("/families/{familyName}/tables/{tableName}", ee.handleTableRoute).Methods("POST", "PUT")
+	r.HandleFunc("/families/{familyName}/tables/{tableName}/indexes/{indexName}", ee.handleIndexRoute).Methods("POST")
 	r.HandleFunc("/families/{familyName}/mutations", ee.handleMutationsRoute).Methods("POST")
 	r.HandleFunc("/sleep", ee.handleSleepRoute).Methods("GET")
 	r.HandleFunc("/status", ee.handleStatusRoute).Methods("GET")
@@ -513,6 +513,54 @@ func TestExecEndpointHandler(_t *testing.T) {
 				}
 			},
 		},
+		{
+			Desc:   "Create Index Success",
+			Path:   "/families/foo/tables/bar/indexes/by_email",
+			Method: "POST",
+			JSONBody: map[string]interface{}{
+				"fields": []string{"email", "field1"},
+				"unique": true,
+			},
+			ExpectedStatusCode: 200,
+			PreFunc: func(t *testing.T, atom *testExecEndpointHandlerAtom) {
+				atom.ei.CreateIndexReturns(nil)
+			},
+			PostFunc: func(t *testing.T, atom *testExecEndpointHandlerAtom) {
+				if want, got := 1, atom.ei.CreateIndexCallCount(); want != got {
+					// Fatal cuz if not it'll panic below
+					t.Fatalf("Expected CreateIndex call count to be %v, was %v", want, got)
+				}
+
+				a1, a2, a3, a4, a5 := atom.ei.CreateIndexArgsForCall(0)
+				if want, got := "foo", a1; want != got {
+					t.Errorf("Expected: %v, got %v", want, got)
+				}
+				if want, got := "bar", a2; want != got {
+					t.Errorf("Expected: %v, got %v", want, got)
+				}
+				if want, got := "by_email", a3; want != got {
+					t.Errorf("Expected: %v, got %v", want, got)
+				}
+				if want, got := []string{"email", "field1"}, a4; !reflect.DeepEqual(want, got) {
+					t.Errorf("Expected: %v, got %v", want, got)
+				}
+				if want, got := true, a5; want != got {
+					t.Errorf("Expected: %v, got %v", want, got)
+				}
+			},
+		},
+		{
+			Desc:   "Create Index Conflict",
+			Path:   "/families/foo/tables/bar/indexes/by_email",
+			Method: "POST",
+			JSONBody: map[string]interface{}{
+				"fields": []string{"email"},
+			},
+			ExpectedStatusCode: 409,
+			PreFunc: func(t *testing.T, atom *testExecEndpointHandlerAtom) {
+				atom.ei.CreateIndexReturns(&errs.ConflictError{Err: "Index already exists"})
+			},
+		},
 		{
 			Desc:               "Fetch cookie + writer found",
 			Path:               "/cookie",
@@ -647,7 +695,7 @@ func TestExecEndpointHandler(_t *testing.T) {
 			},
 			ExpectedStatusCode: 200,
 			PreFunc: func(t *testing.T, atom *testExecEndpointHandlerAtom) {
-				atom.ei.MutateReturns(nil)
+				atom.ei.MutateReturns(executive.ExecutiveMutationResult{FirstSequence: 10, LastSequence: 13}, nil)
 			},
 			PostFunc: func(t *testing.T, atom *testExecEndpointHandlerAtom) {
 				if want, got := 1, atom.ei.MutateCallCount(); want != got {
@@ -693,6 +741,10 @@ func TestExecEndpointHandler(_t *testing.T) {
 				if want, got := expectedRequests, a6; !reflect.DeepEqual(want, got) {
 					t.Errorf("Expected: %v, got %v", want, got)
 				}
+
+				var res map[string]int64
+				require.NoError(t, json.NewDecoder(atom.rr.Body).Decode(&res))
+				require.EqualValues(t, map[string]int64{"first_seq": 10, "last_seq": 13}, res)
 			},
 		},
 		{
@@ -46,6 +46,21 @@ type FakeExecutiveInterface struct {
 	createFamilyReturnsOnCall map[int]struct {
 		result1 error
 	}
+	CreateIndexStub        func(string, string, string, []string, bool) error
+	createIndexMutex       sync.RWMutex
+	createIndexArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 string
+		arg4 []string
+		arg5 bool
+	}
+	createIndexReturns struct {
+		result1 error
+	}
+	createIndexReturnsOnCall map[int]struct {
+		result1 error
+	}
 	CreateTableStub        func(string, string, []string, []schema.FieldType, []string) error
 	createTableMutex       sync.RWMutex
 	createTableArgsForCall []struct {
@@ -97,7 +112,7 @@ type FakeExecutiveInterface struct {
 		result1 []byte
 		result2 error
 	}
-	MutateStub        func(string, string, string, []byte, []byte, []executive.ExecutiveMutationRequest) error
+	MutateStub        func(string, string, string, []byte, []byte, []executive.ExecutiveMutationRequest) (executive.ExecutiveMutationResult, error)
 	mutateMutex       sync.RWMutex
 	mutateArgsForCall []struct {
 		arg1 string
@@ -108,10 +123,12 @@ type FakeExecutiveInterface struct {
 		arg6 []executive.ExecutiveMutationRequest
 	}
 	mutateReturns struct {
-		result1 error
+		result1 executive.ExecutiveMutationResult
+		result2 error
 	}
 	mutateReturnsOnCall map[int]struct {
-		result1 error
+		result1 executive.ExecutiveMutationResult
+		result2 error
 	}
 	ReadFamilyTableNamesStub        func(schema.FamilyName) ([]schema.FamilyTable, error)
 	readFamilyTableNamesMutex       sync.RWMutex
@@ -409,6 +426,75 @@ func (fake *FakeExecutiveInterface) CreateFamilyReturnsOnCall(i int, result1 err
 	}{result1}
 }
 
+func (fake *FakeExecutiveInterface) CreateIndex(arg1 string, arg2 string, arg3 string, arg4 []string, arg5 bool) error {
+	var arg4Copy []string
+	if arg4 != nil {
+		arg4Copy = make([]string, len(arg4))
+		copy(arg4Copy, arg4)
+	}
+	fake.createIndexMutex.Lock()
+	ret, specificReturn := fake.createIndexReturnsOnCall[len(fake.createIndexArgsForCall)]
+	fake.createIndexArgsForCall = append(fake.createIndexArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 string
+		arg4 []string
+		arg5 bool
+	}{arg1, arg2, arg3, arg4Copy, arg5})
+	fake.recordInvocation("CreateIndex", []interface{}{arg1, arg2, arg3, arg4Copy, arg5})
+	fake.createIndexMutex.Unlock()
+	if fake.CreateIndexStub != nil {
+		return fake.CreateIndexStub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.createIndexReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeExecutiveInterface) CreateIndexCallCount() int {
+	fake.createIndexMutex.RLock()
+	defer fake.createIndexMutex.RUnlock()
+	return len(fake.createIndexArgsForCall)
+}
+
+func (fake *FakeExecutiveInterface) CreateIndexCalls(stub func(string, string, string, []string, bool) error) {
+	fake.createIndexMutex.Lock()
+	defer fake.createIndexMutex.Unlock()
+	fake.CreateIndexStub = stub
+}
+
+func (fake *FakeExecutiveInterface) CreateIndexArgsForCall(i int) (string, string, string, []string, bool) {
+	fake.createIndexMutex.RLock()
+	defer fake.createIndexMutex.RUnlock()
+	argsForCall := fake.createIndexArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeExecutiveInterface) CreateIndexReturns(result1 error) {
+	fake.createIndexMutex.Lock()
+	defer fake.createIndexMutex.Unlock()
+	fake.CreateIndexStub = nil
+	fake.createIndexReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeExecutiveInterface) CreateIndexReturnsOnCall(i int, result1 error) {
+	fake.createIndexMutex.Lock()
+	defer fake.createIndexMutex.Unlock()
+	fake.CreateIndexStub = nil
+	if fake.createIndexReturnsOnCall == nil {
+		fake.createIndexReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.createIndexReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeExecutiveInterface) CreateTable(arg1 string, arg2 string, arg3 []string, arg4 []schema.FieldType, arg5 []string) error {
 	var arg3Copy []string
 	if arg3 != nil {
@@ -672,7 +758,7 @@ func (fake *FakeExecutiveInterface) GetWriterCookieReturnsOnCall(i int, result1
 	}{result1, result2}
 }
 
-func (fake *FakeExecutiveInterface) Mutate(arg1 string, arg2 string, arg3 string, arg4 []byte, arg5 []byte, arg6 []executive.ExecutiveMutationRequest) error {
+func (fake *FakeExecutiveInterface) Mutate(arg1 string, arg2 string, arg3 string, arg4 []byte, arg5 []byte, arg6 []executive.ExecutiveMutationRequest) (executive.ExecutiveMutationResult, error) {
 	var arg4Copy []byte
 	if arg4 != nil {
 		arg4Copy = make([]byte, len(arg4))
@@ -704,10 +790,10 @@ func (fake *FakeExecutiveInterface) Mutate(arg1 string, arg2 string, arg3 string
 		return fake.MutateStub(arg1, arg2, arg3, arg4, arg5, arg6)
 	}
 	if specificReturn {
-		return ret.result1
+		return ret.result1, ret.result2
 	}
 	fakeReturns := fake.mutateReturns
-	return fakeReturns.result1
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeExecutiveInterface) MutateCallCount() int {
@@ -716,7 +802,7 @@ func (fake *FakeExecutiveInterface) MutateCallCount() int {
 	return len(fake.mutateArgsForCall)
 }
 
-func (fake *FakeExecutiveInterface) MutateCalls(stub func(string, string, string, []byte, []byte, []executive.ExecutiveMutationRequest) error) {
+func (fake *FakeExecutiveInterface) MutateCalls(stub func(string, string, string, []byte, []byte, []executive.ExecutiveMutationRequest) (executive.ExecutiveMutationResult, error)) {
 	fake.mutateMutex.Lock()
 	defer fake.mutateMutex.Unlock()
 	fake.MutateStub = stub
@@ -729,27 +815,30 @@ func (fake *FakeExecutiveInterface) MutateArgsForCall(i int) (string, string, st
 	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6
 }
 
-func (fake *FakeExecutiveInterface) MutateReturns(result1 error) {
+func (fake *FakeExecutiveInterface) MutateReturns(result1 executive.ExecutiveMutationResult, result2 error) {
 	fake.mutateMutex.Lock()
 	defer fake.mutateMutex.Unlock()
 	fake.MutateStub = nil
 	fake.mutateReturns = struct {
-		result1 error
-	}{result1}
+		result1 executive.ExecutiveMutationResult
+		result2 error
+	}{result1, result2}
 }
 
-func (fake *FakeExecutiveInterface) MutateReturnsOnCall(i int, result1 error) {
+func (fake *FakeExecutiveInterface) MutateReturnsOnCall(i int, result1 executive.ExecutiveMutationResult, result2 error) {
 	fake.mutateMutex.Lock()
 	defer fake.mutateMutex.Unlock()
 	fake.MutateStub = nil
 	if fake.mutateReturnsOnCall == nil {
 		fake.mutateReturnsOnCall = make(map[int]struct {
-			result1 error
+			result1 executive.ExecutiveMutationResult
+			result2 error
 		})
 	}
 	fake.mutateReturnsOnCall[i] = struct {
-		result1 error
-	}{result1}
+		result1 executive.ExecutiveMutationResult
+		result2 error
+	}{result1, result2}
 }
 
 func (fake *FakeExecutiveInterface) ReadFamilyTableNames(arg1 schema.FamilyName) ([]schema.FamilyTable, error) {
@@ -1247,6 +1336,8 @@ func (fake *FakeExecutiveInterface) Invocations() map[string][][]interface{} {
 	defer fake.clearTableMutex.RUnlock()
 	fake.createFamilyMutex.RLock()
 	defer fake.createFamilyMutex.RUnlock()
+	fake.createIndexMutex.RLock()
+	defer fake.createIndexMutex.RUnlock()
 	fake.createTableMutex.RLock()
 	defer fake.createTableMutex.RUnlock()
 	fake.deleteTableSizeLimitMutex.RLock()
@@ -103,7 +103,15 @@ func NextTestLdbTmpPath(testTmpDir string) string {
 
 // Gets current sequence from provided db
 func FetchSeqFromLdb(ctx context.Context, db *sql.DB) (schema.DMLSequence, error) {
-	row := db.QueryRowContext(ctx, ldbFetchSeqSQL)
+	return scanSeq(db.QueryRowContext(ctx, ldbFetchSeqSQL))
+}
+
+// Gets current sequence as seen by the provided transaction
+func FetchSeqFromLdbTx(ctx context.Context, tx *sql.Tx) (schema.DMLSequence, error) {
+	return scanSeq(tx.QueryRowContext(ctx, ldbFetchSeqSQL))
+}
+
+func scanSeq(row *sql.Row) (schema.DMLSequence, error) {
 	var seq int64
 	err := row.Scan(&seq)
 	if err == sql.ErrNoRows {
@@ -5,48 +5,28 @@ import (
 	"sync/atomic"
 
 	"github.com/segmentio/ctlstore/pkg/changelog"
-	"github.com/segmentio/ctlstore/pkg/schema"
 	"github.com/segmentio/events"
 )
 
 type ChangelogCallback struct {
 	ChangelogWriter *changelog.ChangelogWriter
 	Seq             int64
+	// RowValues has the values of changed rows written to the changelog
+	// along with their keys.
+	RowValues bool
 }
 
 func (c *ChangelogCallback) LDBWritten(ctx context.Context, data LDBWriteMetadata) {
-	for _, change := range data.Changes {
-		fam, tbl, err := schema.DecodeLDBTableName(change.TableName)
+	entries := changelogEntries(data.DB, data.Changes, c.RowValues)
+	for i, entry := range entries {
+		entry.Seq = atomic.AddInt64(&c.Seq, 1)
+		entry.LedgerSeq = data.Statement.Sequence.Int()
+		entry.LedgerIndex = i
+		entry.LedgerLast = i == len(entries)-1
+		err := c.ChangelogWriter.WriteChange(entry)
 		if err != nil {
-			// This is expected because it'll capture tables like ctlstore_dml_ledger,
-			// which aren't tables this cares about.
-			events.Debug("Skipped logging change to %{tableName}s, can't decode table: %{error}v",
-				change.TableName,
-				err)
-			continue
-		}
-
-		keys, err := change.ExtractKeys(data.DB)
-		if err != nil {
-			events.Log("Skipped logging change to %{tableName}, can't extract keys: %{error}v",
-				change.TableName,
-				err)
-			continue
-		}
-
-		for _, key := range keys {
-			seq := atomic.AddInt64(&c.Seq, 1)
-			err = c.ChangelogWriter.WriteChange(changelog.ChangelogEntry{
-				Seq:    seq,
-				Family: fam.Name,
-				Table:  tbl.Name,
-				Key:    key,
-			})
-			if err != nil {
-				events.Log("Skipped logging change to %{family}s.%{table}s:%{key}v: %{err}v",
-					fam, tbl, key, err)
-				continue
-			}
+			events.Log("Skipped logging change to %{family}s.%{table}s:%{key}v: %{err}v",
+				entry.Family, entry.Table, entry.Key, err)
 		}
 	}
 }
@@ -0,0 +1,93 @@
+package ldbwriter
+
+import (
+	"database/sql"
+	"reflect"
+
+	"github.com/segmentio/ctlstore/pkg/changelog"
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/segmentio/ctlstore/pkg/sqlite"
+	"github.com/segmentio/events"
+	"github.com/segmentio/go-sqlite3"
+)
+
+var changelogOps = map[int]string{
+	sqlite3.SQLITE_INSERT: changelog.OpInsert,
+	sqlite3.SQLITE_UPDATE: changelog.OpUpdate,
+	sqlite3.SQLITE_DELETE: changelog.OpDelete,
+}
+
+// changelogEntries converts the changes made by a statement into changelog
+// entries, which are returned without sequence numbers. Changes which can't
+// be converted are logged and skipped. Unless rowValues is set, the entries
+// only identify the changed rows, with an entry for each key a change had
+// before and after it, leaving out the op and the row values.
+//
+// The ledger upserts rows with REPLACE, which SQLite carries out by deleting
+// the existing row before inserting the new one. With rowValues set, a
+// delete that is directly followed by an insert of the same key is therefore
+// folded into an update.
+func changelogEntries(db *sql.DB, changes []sqlite.SQLiteWatchChange, rowValues bool) []changelog.ChangelogEntry {
+	var entries []changelog.ChangelogEntry
+	for _, change := range changes {
+		fam, tbl, err := schema.DecodeLDBTableName(change.TableName)
+		if err != nil {
+			// This is expected because it'll capture tables like ctlstore_dml_ledger,
+			// which aren't tables this cares about.
+			events.Debug("Skipped logging change to %{tableName}s, can't decode table: %{error}v",
+				change.TableName,
+				err)
+			continue
+		}
+
+		if !rowValues {
+			keys, err := change.ExtractKeys(db)
+			if err != nil {
+				events.Log("Skipped logging change to %{tableName}, can't extract keys: %{error}v",
+					change.TableName,
+					err)
+				continue
+			}
+			for _, key := range keys {
+				entries = append(entries, changelog.ChangelogEntry{
+					Family: fam.Name,
+					Table:  tbl.Name,
+					Key:    key,
+				})
+			}
+			continue
+		}
+
+		rowChanges, err := change.ExtractRowChanges(db)
+		if err != nil {
+			events.Log("Skipped logging change to %{tableName}, can't extract keys: %{error}v",
+				change.TableName,
+				err)
+			continue
+		}
+
+		for _, rc := range rowChanges {
+			entry := changelog.ChangelogEntry{
+				Family: fam.Name,
+				Table:  tbl.Name,
+				Key:    rc.Key,
+				Op:     changelogOps[rc.Op],
+				Old:    rc.OldRow,
+				New:    rc.NewRow,
+			}
+			if n := len(entries); n > 0 && entry.Op == changelog.OpInsert {
+				prev := &entries[n-1]
+				if prev.Op == changelog.OpDelete &&
+					prev.Family == entry.Family &&
+					prev.Table == entry.Table &&
+					reflect.DeepEqual(prev.Key, entry.Key) {
+					prev.Op = changelog.OpUpdate
+					prev.New = entry.New
+					continue
+				}
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
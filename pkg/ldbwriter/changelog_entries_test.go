@@ -0,0 +1,76 @@
+package ldbwriter
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/segmentio/ctlstore/pkg/ldb"
+	"github.com/segmentio/ctlstore/pkg/sqlite"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangelogEntries(t *testing.T) {
+	ctx := context.Background()
+	ldbPath, teardown := ldb.NewLDBTmpPath(t)
+	defer teardown()
+
+	changeBuffer := new(sqlite.SQLChangeBuffer)
+	driverName := fmt.Sprintf("%s_%d", ldb.LDBDatabaseDriver, time.Now().UnixNano())
+	require.NoError(t, sqlite.RegisterSQLiteWatch(driverName, changeBuffer))
+	db, err := sql.Open(driverName, "file:"+ldbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.ExecContext(ctx, "CREATE TABLE fam___foo (id INTEGER PRIMARY KEY NOT NULL, val VARCHAR)")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "INSERT INTO fam___foo VALUES (1, 'hello')")
+	require.NoError(t, err)
+	changeBuffer.Pop()
+
+	for _, test := range []struct {
+		name      string
+		rowValues bool
+		expected  []string
+	}{
+		{
+			// the delete and the insert which REPLACE is carried out with
+			// are both logged, as they were before row values were added
+			name: "keys only",
+			expected: []string{
+				`{"Family":"fam","Table":"foo","Key":[{"name":"id","type":"INTEGER","value":1}],"Op":"","Old":null,"New":null}`,
+				`{"Family":"fam","Table":"foo","Key":[{"name":"id","type":"INTEGER","value":1}],"Op":"","Old":null,"New":null}`,
+			},
+		},
+		{
+			name:      "row values",
+			rowValues: true,
+			expected: []string{
+				`{"Family":"fam","Table":"foo","Key":[{"name":"id","type":"INTEGER","value":1}],"Op":"update","Old":{"id":1,"val":"hello"},"New":{"id":1,"val":"world"}}`,
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := db.ExecContext(ctx, "UPDATE fam___foo SET val = 'hello'")
+			require.NoError(t, err)
+			changeBuffer.Pop()
+			_, err = db.ExecContext(ctx, "REPLACE INTO fam___foo VALUES (1, 'world')")
+			require.NoError(t, err)
+			var entries []string
+			for _, entry := range changelogEntries(db, changeBuffer.Pop(), test.rowValues) {
+				b, err := json.Marshal(struct {
+					Family, Table string
+					Key           []interface{}
+					Op            string
+					Old, New      map[string]interface{}
+				}{entry.Family, entry.Table, entry.Key, entry.Op, entry.Old, entry.New})
+				require.NoError(t, err)
+				entries = append(entries, string(b))
+			}
+			require.Equal(t, test.expected, entries)
+		})
+	}
+}
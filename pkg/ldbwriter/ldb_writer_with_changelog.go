@@ -17,6 +17,7 @@ type LDBWriterWithChangelog struct {
 	DB              *sql.DB
 	ChangeBuffer    *sqlite.SQLChangeBuffer
 	Seq             int64
+	RowValues       bool // see ChangelogCallback.RowValues
 }
 
 //
@@ -28,7 +29,8 @@ type LDBWriterWithChangelog struct {
 // is executed, the pre-update hook will get called, filling in the channel. Once
 // that ApplyDMLStatement returns, the DML statement is committed and the channel
 // contains the contents of the update. Then this function takes over, extracts
-// the keys from the update, and writes them to the changelogWriter.
+// the keys (and the row values, if RowValues is set) from the update, and
+// writes them to the changelogWriter.
 //
 // This is pretty complex, but after enumerating about 8 different options, it
 // ended up actually being the most simple. Other options involved not-so-great
@@ -40,38 +42,16 @@ func (w *LDBWriterWithChangelog) ApplyDMLStatement(ctx context.Context, statemen
 		return err
 	}
 
-	for _, change := range w.ChangeBuffer.Pop() {
-		fam, tbl, err := schema.DecodeLDBTableName(change.TableName)
+	entries := changelogEntries(w.DB, w.ChangeBuffer.Pop(), w.RowValues)
+	for i, entry := range entries {
+		entry.Seq = atomic.AddInt64(&w.Seq, 1)
+		entry.LedgerSeq = statement.Sequence.Int()
+		entry.LedgerIndex = i
+		entry.LedgerLast = i == len(entries)-1
+		err := w.ChangelogWriter.WriteChange(entry)
 		if err != nil {
-			// This is expected because it'll capture tables like ctlstore_dml_ledger,
-			// which aren't tables this cares about.
-			events.Debug("Skipped logging change to %{tableName}s, can't decode table: %{error}v",
-				change.TableName,
-				err)
-			continue
-		}
-
-		keys, err := change.ExtractKeys(w.DB)
-		if err != nil {
-			events.Log("Skipped logging change to %{tableName}, can't extract keys: %{error}v",
-				change.TableName,
-				err)
-			continue
-		}
-
-		for _, key := range keys {
-			seq := atomic.AddInt64(&w.Seq, 1)
-			err = w.ChangelogWriter.WriteChange(changelog.ChangelogEntry{
-				Seq:    seq,
-				Family: fam.Name,
-				Table:  tbl.Name,
-				Key:    key,
-			})
-			if err != nil {
-				events.Log("Skipped logging change to %{family}s.%{table}s:%{key}v: %{err}v",
-					fam, tbl, key, err)
-				continue
-			}
+			events.Log("Skipped logging change to %{family}s.%{table}s:%{key}v: %{err}v",
+				entry.Family, entry.Table, entry.Key, err)
 		}
 	}
 	return nil
@@ -0,0 +1,140 @@
+// Package metrics exposes the stats ctlstore reports through
+// segmentio/stats to Prometheus.
+package metrics
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/segmentio/errors-go"
+	"github.com/segmentio/events"
+	"github.com/segmentio/stats/v4"
+	"github.com/segmentio/stats/v4/prometheus"
+)
+
+// Path is where the metrics are served.
+const Path = "/metrics"
+
+var (
+	// durationBuckets are the buckets of histograms of durations, in
+	// seconds, such as the timings of reads.
+	durationBuckets = bucketValues(.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60)
+	// countBuckets are the buckets of the rest of the histograms, such as
+	// the number of rows read.
+	countBuckets = bucketValues(1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 100000)
+)
+
+// bucketValues returns the buckets, followed by the +Inf bucket Prometheus
+// expects every histogram to have.
+func bucketValues(buckets ...float64) []stats.Value {
+	res := make([]stats.Value, 0, len(buckets)+1)
+	for _, b := range buckets {
+		res = append(res, stats.ValueOf(b))
+	}
+	return append(res, stats.ValueOf(math.Inf(1)))
+}
+
+// PrometheusHandler is a stats.Handler which collects the measures it's
+// sent, and serves them to Prometheus as an http.Handler. Metrics keep the
+// names and tags they are reported with, so are named the same way as they
+// are in DogStatsD, except that invalid characters are replaced by '_'.
+//
+// The Prometheus handler of segmentio/stats leaves out histograms which it
+// doesn't have buckets for, so each histogram is given buckets for either
+// durations or counts the first time it's observed, depending on its values.
+type PrometheusHandler struct {
+	mutex   sync.RWMutex // guards the handler's buckets
+	handler *prometheus.Handler
+}
+
+// NewPrometheusHandler returns a handler without any metrics.
+func NewPrometheusHandler() *PrometheusHandler {
+	return &PrometheusHandler{
+		handler: &prometheus.Handler{Buckets: stats.HistogramBuckets{}},
+	}
+}
+
+// HandleMeasures satisfies the stats.Handler interface.
+func (h *PrometheusHandler) HandleMeasures(t time.Time, measures ...stats.Measure) {
+	h.mutex.RLock()
+	ok := h.hasBuckets(measures)
+	h.mutex.RUnlock()
+	if !ok {
+		h.mutex.Lock()
+		h.addBuckets(measures)
+		h.mutex.Unlock()
+	}
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	h.handler.HandleMeasures(t, measures...)
+}
+
+// hasBuckets returns whether all of the histograms of the measures already
+// have buckets.
+func (h *PrometheusHandler) hasBuckets(measures []stats.Measure) bool {
+	for _, m := range measures {
+		for _, f := range m.Fields {
+			if f.Type() != stats.Histogram {
+				continue
+			}
+			if _, ok := h.handler.Buckets[stats.Key{Measure: m.Name, Field: f.Name}]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (h *PrometheusHandler) addBuckets(measures []stats.Measure) {
+	for _, m := range measures {
+		for _, f := range m.Fields {
+			if f.Type() != stats.Histogram {
+				continue
+			}
+			key := stats.Key{Measure: m.Name, Field: f.Name}
+			if _, ok := h.handler.Buckets[key]; ok {
+				continue
+			}
+			h.handler.Buckets[key] = countBuckets
+			if f.Value.Type() == stats.Duration {
+				h.handler.Buckets[key] = durationBuckets
+			}
+		}
+	}
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (h *PrometheusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.ServeHTTP(w, r)
+}
+
+// ServePrometheus serves the metrics of the handler at Path on a listener
+// until the context is done. The listener is taken rather than an address
+// so that callers find out that the address can't be bound before they
+// start serving.
+func ServePrometheus(ctx context.Context, lis net.Listener, h http.Handler) error {
+	mux := http.NewServeMux()
+	mux.Handle(Path, h)
+	srv := &http.Server{
+		Handler:      mux,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(lis)
+	}()
+	events.Log("Serving Prometheus metrics on %{addr}s", lis.Addr().String())
+	select {
+	case err := <-errs:
+		return errors.Wrap(err, "serve prometheus metrics")
+	case <-ctx.Done():
+	}
+	sctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return errors.Wrap(srv.Shutdown(sctx), "shut down prometheus metrics")
+}
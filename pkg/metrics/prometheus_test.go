@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/segmentio/stats/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusHandler(t *testing.T) {
+	h := NewPrometheusHandler()
+	engine := stats.NewEngine("ctlstore.reflector", h, stats.T("shadow", "false"))
+	engine.Incr("shovel.apply_statement.success")
+	engine.Incr("shovel.apply_statement.success")
+	engine.Observe("get_row_by_key", 20*time.Millisecond, stats.T("family", "f"), stats.T("table", "t"))
+	engine.Observe("get-rows-by-key-prefix-num-rows", 3)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, Path, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	for _, line := range []string{
+		"# TYPE ctlstore_reflector_shovel_apply_statement_success counter\n",
+		`ctlstore_reflector_shovel_apply_statement_success{shadow="false"} 2 `,
+		"# TYPE ctlstore_reflector_get_row_by_key histogram\n",
+		`ctlstore_reflector_get_row_by_key_bucket{family="f",shadow="false",table="t",le="0.01"} 0 `,
+		`ctlstore_reflector_get_row_by_key_bucket{family="f",shadow="false",table="t",le="0.025"} 1 `,
+		`ctlstore_reflector_get_row_by_key_bucket{family="f",shadow="false",table="t",le="+Inf"} 1 `,
+		`ctlstore_reflector_get_row_by_key_sum{family="f",shadow="false",table="t"} 0.02 `,
+		"# TYPE ctlstore_reflector_get_rows_by_key_prefix_num_rows histogram\n",
+		`ctlstore_reflector_get_rows_by_key_prefix_num_rows_bucket{shadow="false",le="2"} 0 `,
+		`ctlstore_reflector_get_rows_by_key_prefix_num_rows_bucket{shadow="false",le="5"} 1 `,
+		`ctlstore_reflector_get_rows_by_key_prefix_num_rows_count{shadow="false"} 1 `,
+	} {
+		require.Contains(t, w.Body.String(), line)
+	}
+}
@@ -53,14 +53,17 @@ type UpstreamConfig struct {
 // ReflectorConfig is used to configure a Reflector instance that
 // is instantiated by ReflectorFromConfig
 type ReflectorConfig struct {
-	LDBPath          string
-	ChangelogPath    string
-	ChangelogSize    int
-	Upstream         UpstreamConfig
-	BootstrapURL     string
-	LedgerHealth     ledger.HealthConfig
-	IsSupervisor     bool
-	LDBWriteCallback ldbwriter.LDBWriteCallback // optional
+	LDBPath       string
+	ChangelogPath string
+	ChangelogSize int
+	// ChangelogRowValues has the values of changed rows written to the
+	// changelog, which otherwise only identifies them by key.
+	ChangelogRowValues bool
+	Upstream           UpstreamConfig
+	BootstrapURL       string
+	LedgerHealth       ledger.HealthConfig
+	IsSupervisor       bool
+	LDBWriteCallback   ldbwriter.LDBWriteCallback // optional
 }
 
 // Printable returns a "pretty" stringified version of the config
@@ -182,6 +185,7 @@ func ReflectorFromConfig(config ReflectorConfig) (*Reflector, error) {
 			clw := &changelog.ChangelogWriter{WriteLine: slw}
 			ldbWriteCallbacks = append(ldbWriteCallbacks, &ldbwriter.ChangelogCallback{
 				ChangelogWriter: clw,
+				RowValues:       config.ChangelogRowValues,
 			})
 			events.Log("Writing changelog to %{path}s", config.ChangelogPath)
 		}
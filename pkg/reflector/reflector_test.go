@@ -137,10 +137,11 @@ func TestReflector(t *testing.T) {
 	dataURI := "data:" + encodedEmpty
 
 	cfg := ReflectorConfig{
-		LDBPath:       ldbDbPath,
-		BootstrapURL:  dataURI,
-		ChangelogPath: changelogPath,
-		ChangelogSize: 1 * 1024 * 1024,
+		LDBPath:            ldbDbPath,
+		BootstrapURL:       dataURI,
+		ChangelogPath:      changelogPath,
+		ChangelogSize:      1 * 1024 * 1024,
+		ChangelogRowValues: true,
 		Upstream: UpstreamConfig{
 			Driver:         "sqlite3",
 			DSN:            upstreamDbPath,
@@ -175,6 +176,8 @@ func TestReflector(t *testing.T) {
 			field2 VARCHAR
 		);`,
 		`INSERT INTO family1___table1234 VALUES(1234, 'hello');`,
+		`REPLACE INTO family1___table1234 VALUES(1234, 'world');`,
+		`DELETE FROM family1___table1234 WHERE field1 = 1234;`,
 	}
 
 	for _, stmt := range ledgerStmts {
@@ -204,7 +207,10 @@ func TestReflector(t *testing.T) {
 	clBytes, err := ioutil.ReadFile(changelogPath)
 	require.NoError(t, err)
 
-	expectChangelog := "{\"seq\":1,\"family\":\"family1\",\"table\":\"table1234\",\"key\":[{\"name\":\"field1\",\"type\":\"INTEGER\",\"value\":1234}]}\n"
+	expectChangelog := `{"seq":1,"family":"family1","table":"table1234","key":[{"name":"field1","type":"INTEGER","value":1234}],"ledger_seq":2,"ledger_last":true,"op":"insert","new":{"field1":1234,"field2":"hello"}}
+{"seq":2,"family":"family1","table":"table1234","key":[{"name":"field1","type":"INTEGER","value":1234}],"ledger_seq":3,"ledger_last":true,"op":"update","old":{"field1":1234,"field2":"hello"},"new":{"field1":1234,"field2":"world"}}
+{"seq":3,"family":"family1","table":"table1234","key":[{"name":"field1","type":"INTEGER","value":1234}],"ledger_seq":4,"ledger_last":true,"op":"delete","old":{"field1":1234,"field2":"world"}}
+`
 	if diff := cmp.Diff(expectChangelog, string(clBytes)); diff != "" {
 		t.Errorf("Changelog contents differ\n%s", diff)
 	}
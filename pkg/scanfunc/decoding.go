@@ -0,0 +1,126 @@
+package scanfunc
+
+import (
+	"database/sql"
+	"encoding"
+	"encoding/json"
+	"reflect"
+)
+
+// FieldDecoding is how a column value is decoded into a struct field.
+type FieldDecoding int
+
+const (
+	// DecodeDefault leaves decoding to Scan, which also handles fields that
+	// implement sql.Scanner.
+	DecodeDefault FieldDecoding = iota
+	// DecodeText decodes text values with the field's
+	// encoding.TextUnmarshaler. Other values, such as those of binary or
+	// integer columns, are left to Scan.
+	DecodeText
+	// DecodeJSON decodes the column as JSON, for fields tagged with the
+	// json option, e.g. `ctlstore:"col,json"`.
+	DecodeJSON
+)
+
+var (
+	scannerType         = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// fieldDecoding works out how to decode into a field of type typ, given
+// the options from its tag.
+func fieldDecoding(typ reflect.Type, opts []string) FieldDecoding {
+	for _, opt := range opts {
+		if opt == "json" {
+			return DecodeJSON
+		}
+	}
+	implements := func(iface reflect.Type) bool {
+		// a pointer field is allocated before its value is decoded
+		return reflect.PtrTo(typ).Implements(iface) ||
+			(typ.Kind() == reflect.Ptr && typ.Implements(iface))
+	}
+	if !implements(scannerType) && implements(textUnmarshalerType) {
+		return DecodeText
+	}
+	return DecodeDefault
+}
+
+// textScanner implements sql.Scanner for a pointer to a field which
+// implements encoding.TextUnmarshaler. Only text is unmarshaled, which is
+// a string, or a []byte from a column which isn't binary. Other values are
+// converted the same way Scan converts them. NULL sets the field to its
+// zero value.
+type textScanner struct {
+	dest   interface{}
+	binary bool // whether the column is binary
+}
+
+func (s *textScanner) Scan(src interface{}) error {
+	field := reflect.ValueOf(s.dest).Elem()
+	if src == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	var b []byte
+	switch src := src.(type) {
+	case string:
+		b = []byte(src)
+	case []byte:
+		if s.binary {
+			return convertAssign(s.dest, src)
+		}
+		b = src
+	default:
+		return convertAssign(s.dest, src)
+	}
+	u, ok := s.dest.(encoding.TextUnmarshaler)
+	if !ok {
+		// the field is a pointer to a TextUnmarshaler
+		ptr := reflect.New(field.Type().Elem())
+		field.Set(ptr)
+		u = ptr.Interface().(encoding.TextUnmarshaler)
+	}
+	return u.UnmarshalText(b)
+}
+
+// jsonScanner implements sql.Scanner for a pointer to a field which holds
+// a JSON encoded column. NULL sets the field to its zero value.
+type jsonScanner struct {
+	dest interface{}
+}
+
+func (s *jsonScanner) Scan(src interface{}) error {
+	// reset the field, since json.Unmarshal merges into maps and structs
+	field := reflect.ValueOf(s.dest).Elem()
+	field.Set(reflect.Zero(field.Type()))
+	if src == nil {
+		return nil
+	}
+	b, err := textBytes(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, s.dest)
+}
+
+// textBytes returns the text of a non-NULL value, converting it to a string
+// the same way sql.Rows.Scan does.
+func textBytes(src interface{}) ([]byte, error) {
+	if b, ok := src.([]byte); ok {
+		return b, nil
+	}
+	var s sql.NullString
+	if err := s.Scan(src); err != nil {
+		return nil, err
+	}
+	return []byte(s.String), nil
+}
+
+// fieldAddr returns a pointer to the field of the struct target points at.
+// Unlike PtrToStructField, going through reflect lets the compiler see that
+// target escapes, so the pointer stays valid while a decoder holds onto it.
+func fieldAddr(target interface{}, field reflect.StructField) interface{} {
+	return reflect.ValueOf(target).Elem().FieldByIndex(field.Index).Addr().Interface()
+}
@@ -0,0 +1,152 @@
+package scanfunc
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/stretchr/testify/require"
+)
+
+type testColor int
+
+func (c *testColor) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "red":
+		*c = 1
+	case "blue":
+		*c = 2
+	default:
+		return fmt.Errorf("unknown color %q", text)
+	}
+	return nil
+}
+
+type testUpper string
+
+func (u *testUpper) UnmarshalText(text []byte) error {
+	*u = testUpper(strings.ToUpper(string(text)))
+	return nil
+}
+
+// testScanned implements both interfaces, so Scan should be preferred.
+type testScanned string
+
+func (s *testScanned) Scan(src interface{}) error {
+	*s = testScanned(fmt.Sprintf("scanned:%s", src))
+	return nil
+}
+
+func (s *testScanned) UnmarshalText(text []byte) error {
+	*s = "unmarshaled"
+	return nil
+}
+
+type testDecodingRow struct {
+	Color    testColor              `ctlstore:"color"`
+	ColorPtr *testColor             `ctlstore:"color_ptr"`
+	Upper    testUpper              `ctlstore:"upper"`
+	Scanned  testScanned            `ctlstore:"scanned"`
+	Attrs    map[string]interface{} `ctlstore:"attrs,json"`
+	Tags     []string               `ctlstore:"TAGS,json"`
+	Null     sql.NullString         `ctlstore:"null"`
+}
+
+func TestFieldDecoding(t *testing.T) {
+	rowType := reflect.TypeOf(testDecodingRow{})
+	for _, test := range []struct {
+		field    string
+		opts     []string
+		expected FieldDecoding
+	}{
+		{"Color", nil, DecodeText},
+		{"ColorPtr", nil, DecodeText},
+		{"Upper", nil, DecodeText},
+		{"Scanned", nil, DecodeDefault},
+		{"Scanned", []string{"json"}, DecodeJSON},
+		{"Attrs", []string{"json"}, DecodeJSON},
+		{"Attrs", nil, DecodeDefault},
+		{"Null", nil, DecodeDefault},
+	} {
+		field, ok := rowType.FieldByName(test.field)
+		require.True(t, ok)
+		require.Equal(t, test.expected, fieldDecoding(field.Type, test.opts), "%s %v", test.field, test.opts)
+	}
+}
+
+func TestStructPlanDecoding(t *testing.T) {
+	cols := []schema.DBColumnMeta{
+		{Name: "color"}, {Name: "color_ptr"}, {Name: "upper"}, {Name: "scanned"},
+		{Name: "attrs"}, {Name: "tags"}, {Name: "null"},
+	}
+	plan, err := NewStructPlan(reflect.TypeOf(testDecodingRow{}), cols)
+	require.NoError(t, err)
+
+	var row testDecodingRow
+	err = Values{
+		[]byte("red"), "blue", "abc", "x",
+		[]byte(`{"a":1}`), `["x","y"]`, nil,
+	}.Scan(plan.Targets(&row)...)
+	require.NoError(t, err)
+	blue := testColor(2)
+	require.Equal(t, testDecodingRow{
+		Color:    1,
+		ColorPtr: &blue,
+		Upper:    "ABC",
+		Scanned:  "scanned:x",
+		Attrs:    map[string]interface{}{"a": float64(1)},
+		Tags:     []string{"x", "y"},
+	}, row)
+
+	// reusing the target replaces previous values rather than merging them
+	err = Values{
+		"blue", nil, nil, "y",
+		`{"b":2}`, nil, "z",
+	}.Scan(plan.Targets(&row)...)
+	require.NoError(t, err)
+	require.Equal(t, testDecodingRow{
+		Color:   2,
+		Upper:   "",
+		Scanned: "scanned:y",
+		Attrs:   map[string]interface{}{"b": float64(2)},
+		Null:    sql.NullString{String: "z", Valid: true},
+	}, row)
+
+	for _, vals := range []Values{
+		{"green", nil, nil, nil, nil, nil, nil},
+		{"red", nil, nil, nil, "{", nil, nil},
+	} {
+		err := vals.Scan(plan.Targets(&row)...)
+		require.Error(t, err, "%v", vals)
+	}
+}
+
+func TestStructPlanDecodesOnlyText(t *testing.T) {
+	type row struct {
+		Addr  net.IP     `ctlstore:"addr"`
+		Color testColor  `ctlstore:"color"`
+		Ptr   *testColor `ctlstore:"ptr"`
+	}
+	cols := []schema.DBColumnMeta{
+		{Name: "addr", Type: "BLOB"},
+		{Name: "color", Type: "INTEGER"},
+		{Name: "ptr", Type: "INTEGER"},
+	}
+	plan, err := NewStructPlan(reflect.TypeOf(row{}), cols)
+	require.NoError(t, err)
+
+	var r row
+	err = Values{[]byte{10, 0, 0, 1}, int64(2), int64(1)}.Scan(plan.Targets(&r)...)
+	require.NoError(t, err)
+	red := testColor(1)
+	require.Equal(t, row{Addr: net.IP{10, 0, 0, 1}, Color: 2, Ptr: &red}, r)
+
+	// text is still unmarshaled, whatever the column's type
+	err = Values{[]byte{10, 0, 0, 2}, "blue", []byte("red")}.Scan(plan.Targets(&r)...)
+	require.NoError(t, err)
+	require.Equal(t, row{Addr: net.IP{10, 0, 0, 2}, Color: 2, Ptr: &red}, r)
+}
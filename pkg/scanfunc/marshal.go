@@ -17,8 +17,9 @@ type (
 		Fields map[string]UnmarshalTypeMetaField
 	}
 	UnmarshalTypeMetaField struct {
-		Field   reflect.StructField
-		Factory unsafe.InterfaceFactory
+		Field    reflect.StructField
+		Factory  unsafe.InterfaceFactory
+		Decoding FieldDecoding
 	}
 	UtmGetterFunc func(reflect.Type) (UnmarshalTypeMeta, error)
 )
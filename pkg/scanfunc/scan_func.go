@@ -1,7 +1,6 @@
 package scanfunc
 
 import (
-	"database/sql"
 	"reflect"
 	"strings"
 
@@ -20,17 +19,18 @@ type (
 		Val interface{}
 	}
 	// scanFunc deserializes rows from the ldb into another data structure
-	ScanFunc func(rows *sql.Rows) error
+	ScanFunc func(rows RowScanner) error
+	// RowScanner supplies the values of the current row, e.g. *sql.Rows
+	RowScanner interface {
+		Scan(dest ...interface{}) error
+	}
 )
 
 func (s *Placeholder) Scan(src interface{}) error {
 	switch src := src.(type) {
 	case []byte:
-		colType := strings.ToUpper(s.Col.Type)
 		switch {
-		case strings.HasPrefix(colType, "BLOB"):
-			s.Val = src
-		case strings.HasPrefix(colType, "VARBINARY"):
+		case s.Col.IsBinary():
 			s.Val = src
 		default:
 			// sqlite returns a []byte for string columns :-\
@@ -56,36 +56,49 @@ func New(target interface{}, cols []schema.DBColumnMeta) (ScanFunc, error) {
 }
 
 func scanFuncMap(target interface{}, cols []schema.DBColumnMeta) ScanFunc {
-	return func(rows *sql.Rows) error {
+	return func(rows RowScanner) error {
 		m, ok := target.(map[string]interface{})
 		if m == nil || !ok {
 			return ErrUnmarshalUnsupportedType
 		}
-		targets := make([]interface{}, 0, len(cols))
+		return ScanMap(rows, m, cols)
+	}
+}
 
-		// populate the targets with zero value types
-		for _, col := range cols {
-			targets = append(targets, &Placeholder{Col: col})
-		}
-		if err := rows.Scan(targets...); err != nil {
-			return err
-		}
-		// look at each target and populate the map
-		for i, target := range targets {
-			value := target.(*Placeholder).Val
-			m[cols[i].Name] = value
-		}
-		return nil
+// ScanMap scans the current row into m, keyed by column name.
+func ScanMap(rows RowScanner, m map[string]interface{}, cols []schema.DBColumnMeta) error {
+	targets := make([]interface{}, 0, len(cols))
+
+	// populate the targets with zero value types
+	for _, col := range cols {
+		targets = append(targets, &Placeholder{Col: col})
+	}
+	if err := rows.Scan(targets...); err != nil {
+		return err
+	}
+	// look at each target and populate the map
+	for i, target := range targets {
+		value := target.(*Placeholder).Val
+		m[cols[i].Name] = value
 	}
+	return nil
 }
 
 func scanFuncStruct(target interface{}, cols []schema.DBColumnMeta) ScanFunc {
-	return func(rows *sql.Rows) error {
-		targets, err := NewUnmarshalTargetSlice(target, cols)
-		if err != nil {
-			return err
+	// the plan is built on the first call, and reused for the rest
+	var plan *StructPlan
+	return func(rows RowScanner) error {
+		if plan == nil {
+			if reflect.TypeOf(target).Kind() != reflect.Ptr {
+				return ErrUnmarshalUnsupportedType
+			}
+			p, err := NewStructPlan(reflect.TypeOf(target).Elem(), cols)
+			if err != nil {
+				return err
+			}
+			plan = p
 		}
-		return rows.Scan(targets...)
+		return rows.Scan(plan.Targets(target)...)
 	}
 }
 
@@ -98,10 +111,24 @@ func NewUnmarshalTargetSlice(target interface{}, cols []schema.DBColumnMeta) ([]
 	if reflect.TypeOf(target).Kind() != reflect.Ptr {
 		return nil, ErrUnmarshalUnsupportedType
 	}
+	plan, err := NewStructPlan(reflect.TypeOf(target).Elem(), cols)
+	if err != nil {
+		return nil, err
+	}
+	return plan.Targets(target), nil
+}
 
-	targetVal := reflect.ValueOf(target).Elem()
-	targetType := targetVal.Type()
+// StructPlan maps the columns of a result set onto the tagged fields of a
+// struct type, so that many rows can be scanned into values of that type
+// while only looking its fields up once.
+type StructPlan struct {
+	cols   []schema.DBColumnMeta
+	fields []*UnmarshalTypeMetaField // in column order, nil if there's no field
+}
 
+// NewStructPlan builds the plan for scanning cols into values of typ, which
+// must be a struct type.
+func NewStructPlan(targetType reflect.Type, cols []schema.DBColumnMeta) (*StructPlan, error) {
 	// TODO: check for unexported FIELDS, not types
 
 	buildMeta := func(typ reflect.Type) (UnmarshalTypeMeta, error) {
@@ -118,10 +145,12 @@ func NewUnmarshalTargetSlice(target interface{}, cols []schema.DBColumnMeta) ([]
 			field := targetType.Field(i)
 			tagVal, found := field.Tag.Lookup(ctlTagString)
 			if found {
-				tagVal = strings.ToLower(tagVal)
-				fields[tagVal] = UnmarshalTypeMetaField{
-					Field:   field,
-					Factory: unsafe.NewInterfaceFactory(field.Type),
+				// e.g. `ctlstore:"col,json"`
+				opts := strings.Split(strings.ToLower(tagVal), ",")
+				fields[opts[0]] = UnmarshalTypeMetaField{
+					Field:    field,
+					Factory:  unsafe.NewInterfaceFactory(field.Type),
+					Decoding: fieldDecoding(field.Type, opts[1:]),
 				}
 			}
 		}
@@ -135,6 +164,18 @@ func NewUnmarshalTargetSlice(target interface{}, cols []schema.DBColumnMeta) ([]
 		return nil, err
 	}
 
+	plan := &StructPlan{cols: cols, fields: make([]*UnmarshalTypeMetaField, len(cols))}
+	for i, col := range cols {
+		if fieldMeta, ok := meta.Fields[col.Name]; ok {
+			plan.fields[i] = &fieldMeta
+		}
+	}
+	return plan, nil
+}
+
+// Targets returns the slice of pointers to pass to Scan in order to fill
+// out target, which must be a pointer to a value of the plan's type.
+func (p *StructPlan) Targets(target interface{}) []interface{} {
 	//
 	// Construct a slice of pointers which point to the fields in the struct
 	// itself. It uses cached metadata for the type information on each field.
@@ -150,14 +191,23 @@ func NewUnmarshalTargetSlice(target interface{}, cols []schema.DBColumnMeta) ([]
 	// to the value of a type which implements Scanner, but does nothing,
 	// or the "no-op" scanner.
 	//
-	targets := make([]interface{}, len(cols))
-	for i, col := range cols {
-		colName := col.Name
+	targets := make([]interface{}, len(p.fields))
+	for i, fieldMeta := range p.fields {
 		var elem interface{} = &UtcNoopScanner
-		if fieldMeta, ok := meta.Fields[colName]; ok {
-			elem = fieldMeta.Factory.PtrToStructField(target, fieldMeta.Field)
+		if fieldMeta != nil {
+			switch fieldMeta.Decoding {
+			case DecodeText:
+				elem = &textScanner{
+					dest:   fieldAddr(target, fieldMeta.Field),
+					binary: p.cols[i].IsBinary(),
+				}
+			case DecodeJSON:
+				elem = &jsonScanner{dest: fieldAddr(target, fieldMeta.Field)}
+			default:
+				elem = fieldMeta.Factory.PtrToStructField(target, fieldMeta.Field)
+			}
 		}
 		targets[i] = elem
 	}
-	return targets, nil
+	return targets
 }
@@ -0,0 +1,66 @@
+package scanfunc
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValuesScan(t *testing.T) {
+	var (
+		s   string
+		b   []byte
+		i   int
+		i64 int64
+		f   float64
+		ns  sql.NullString
+		ps  *string
+		ifc interface{}
+	)
+	src := []byte("raw")
+	err := Values{"str", src, int64(1), "2", 1.5, nil, "ptr", src}.Scan(&s, &b, &i, &i64, &f, &ns, &ps, &ifc)
+	require.NoError(t, err)
+	require.Equal(t, "str", s)
+	require.Equal(t, []byte("raw"), b)
+	require.Equal(t, 1, i)
+	require.EqualValues(t, 2, i64)
+	require.Equal(t, 1.5, f)
+	require.False(t, ns.Valid)
+	require.Equal(t, "ptr", *ps)
+	require.Equal(t, []byte("raw"), ifc)
+
+	// byte slices are copied
+	b[0] = 'R'
+	require.Equal(t, []byte("raw"), src)
+
+	var (
+		bl  bool
+		ts  string
+		u8  uint8
+		ptr *int64
+	)
+	when := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	err = Values{int64(1), when, "255", nil}.Scan(&bl, &ts, &u8, &ptr)
+	require.NoError(t, err)
+	require.True(t, bl)
+	require.Equal(t, "2020-01-02T03:04:05Z", ts)
+	require.EqualValues(t, 255, u8)
+	require.Nil(t, ptr)
+
+	for _, test := range []struct {
+		vals Values
+		dest interface{}
+	}{
+		{Values{nil}, &s},
+		{Values{"x"}, &i},
+		{Values{1.5}, &i64},
+		{Values{"a", "b"}, &s},
+		{Values{"256"}, &u8},
+		{Values{int64(2)}, &bl},
+	} {
+		err := test.vals.Scan(test.dest)
+		require.Error(t, err, "%v", test.vals)
+	}
+}
@@ -1,12 +1,22 @@
 package schema
 
-import "database/sql"
+import (
+	"database/sql"
+	"strings"
+)
 
 type DBColumnMeta struct {
 	Name string
 	Type string
 }
 
+// IsBinary returns whether the column holds binary values, rather than
+// text, since sqlite reads both as []byte.
+func (m DBColumnMeta) IsBinary() bool {
+	colType := strings.ToUpper(m.Type)
+	return strings.HasPrefix(colType, "BLOB") || strings.HasPrefix(colType, "VARBINARY")
+}
+
 func DBColumnMetaFromRows(rows *sql.Rows) ([]DBColumnMeta, error) {
 	typs, err := rows.ColumnTypes()
 	if err != nil {
@@ -0,0 +1,55 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	MinIndexNameLength = 3
+	MaxIndexNameLength = 30
+)
+
+// use NewIndexName to construct an IndexName
+type IndexName struct {
+	Name string
+}
+
+var indexNameChars = regexp.MustCompile("^$|^[a-z][a-z0-9_]*$")
+
+var (
+	ErrIndexNameInvalid  = errors.New("Index names must be only letters, numbers, and single underscore")
+	ErrIndexNameTooLong  = fmt.Errorf("Index names can only be up to %d characters", MaxIndexNameLength)
+	ErrIndexNameTooShort = fmt.Errorf("Index names must be at least %d characters", MinIndexNameLength)
+)
+
+func NewIndexName(name string) (IndexName, error) {
+	normalized, err := normalizeIndexName(name)
+	if err != nil {
+		return IndexName{}, err
+	}
+	return IndexName{normalized}, nil
+}
+
+func (in IndexName) String() string {
+	return in.Name
+}
+
+func normalizeIndexName(indexName string) (string, error) {
+	lowered := strings.ToLower(indexName)
+	if strings.Contains(lowered, "__") {
+		return "", ErrIndexNameInvalid
+	}
+	if !indexNameChars.MatchString(lowered) {
+		return "", ErrIndexNameInvalid
+	}
+	if len(lowered) > MaxIndexNameLength {
+		return "", ErrIndexNameTooLong
+	}
+	if len(lowered) < MinIndexNameLength {
+		return "", ErrIndexNameTooShort
+	}
+	return lowered, nil
+}
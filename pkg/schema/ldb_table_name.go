@@ -18,6 +18,15 @@ func LDBTableName(famName FamilyName, tblName TableName) string {
 		ldbTableNameDelimiter)
 }
 
+// Converts a family/table/index name triple to the name of the index in the
+// LDB. SQLite index names share a namespace with tables, so the index name is
+// scoped by the table it belongs to.
+func LDBIndexName(famName FamilyName, tblName TableName, idxName IndexName) string {
+	return strings.Join(
+		[]string{famName.Name, tblName.Name, idxName.Name},
+		ldbTableNameDelimiter)
+}
+
 // The opposite of ldbTableName()
 func DecodeLDBTableName(tableName string) (fn FamilyName, tn TableName, err error) {
 	splitted := strings.Split(tableName, ldbTableNameDelimiter)
@@ -92,6 +92,35 @@ func TestNormalizeFieldName(t *testing.T) {
 	}
 }
 
+func TestNormalizeIndexName(t *testing.T) {
+	suite := []struct {
+		desc      string
+		input     string
+		expectStr string
+		expectErr error
+	}{
+		{"Lowers", "LOWER", "lower", nil},
+		{"Too short", "ab", "", ErrIndexNameTooShort},
+		{"Too long", strings.Repeat("a", 31), "", ErrIndexNameTooLong},
+		{"Invalid chars", "abc-123", "", ErrIndexNameInvalid},
+		{"Starts with number", "1abc", "", ErrIndexNameInvalid},
+		{"Contains multi-underscore", "a__b", "", ErrIndexNameInvalid},
+	}
+
+	for i, testCase := range suite {
+		testName := fmt.Sprintf("%d %s", i, testCase.desc)
+		t.Run(testName, func(t *testing.T) {
+			gotStr, gotErr := normalizeIndexName(testCase.input)
+			if want, got := testCase.expectErr, gotErr; want != got {
+				t.Errorf("Expected error %v, got %v", want, got)
+			}
+			if want, got := testCase.expectStr, gotStr; want != got {
+				t.Errorf("Expected %v, got %v", want, got)
+			}
+		})
+	}
+}
+
 func TestValidateWriterName(t *testing.T) {
 	suite := []struct {
 		desc      string
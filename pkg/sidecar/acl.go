@@ -0,0 +1,193 @@
+package sidecar
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/segmentio/errors-go"
+	"github.com/segmentio/stats/v4"
+)
+
+// ACL is an access control policy, which limits the families and tables
+// each client of the sidecar may read. A client has an identity for each
+// way it has authenticated:
+//
+//   - the identity its bearer token is mapped to by Tokens
+//   - "cn:<common name>" of its certificate, if the sidecar requires them
+//   - "uid:<user id>" of its process, if it connected over a Unix socket
+//
+// A client may read a table if it's granted to any of its identities, or
+// to "*", which is every client. Each grant is either "*", a family, or a
+// single table as "family.table". Names are normalized the way the LDB's
+// are, so they're matched regardless of case. Reads of tables that aren't
+// granted are denied, but clients may always check the sidecar's health and
+// latency.
+type ACL struct {
+	// Tokens maps bearer tokens to the identities of the clients which
+	// send them.
+	Tokens map[string]string `json:"tokens"`
+	// Grants maps identities to the families and tables they may read.
+	Grants map[string][]string `json:"grants"`
+}
+
+// LoadACL reads an ACL from a JSON file.
+func LoadACL(path string) (*ACL, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read acl")
+	}
+	var acl ACL
+	if err := json.Unmarshal(b, &acl); err != nil {
+		return nil, errors.Wrap(err, "decode acl")
+	}
+	return acl.normalize()
+}
+
+// normalize returns a copy of the ACL with the family and table names of
+// its grants normalized, or an error if any of them are invalid.
+func (a *ACL) normalize() (*ACL, error) {
+	res := &ACL{Tokens: a.Tokens, Grants: make(map[string][]string, len(a.Grants))}
+	for id, grants := range a.Grants {
+		normalized := make([]string, 0, len(grants))
+		for _, grant := range grants {
+			if grant == "*" {
+				normalized = append(normalized, grant)
+				continue
+			}
+			family, table := grant, ""
+			i := strings.IndexByte(grant, '.')
+			if i >= 0 {
+				family, table = grant[:i], grant[i+1:]
+			}
+			family, table, err := normalizeNames(family, table)
+			if err == nil && (family == "" || i >= 0 && table == "") {
+				err = errors.New("missing name")
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid grant '%s' of '%s'", grant, id)
+			}
+			if table != "" {
+				family += "." + table
+			}
+			normalized = append(normalized, family)
+		}
+		res.Grants[id] = normalized
+	}
+	return res, nil
+}
+
+// normalizeNames normalizes the names of a family and a table, either of
+// which may be left empty.
+func normalizeNames(family string, table string) (string, string, error) {
+	if family != "" {
+		fn, err := schema.NewFamilyName(family)
+		if err != nil {
+			return "", "", errors.Wrapf(err, "family '%s'", family)
+		}
+		family = fn.Name
+	}
+	if table != "" {
+		tn, err := schema.NewTableName(table)
+		if err != nil {
+			return "", "", errors.Wrapf(err, "table '%s'", table)
+		}
+		table = tn.Name
+	}
+	return family, table, nil
+}
+
+type peerUIDKey struct{}
+
+// withPeerUID is used as the ConnContext of the sidecar's server, to record
+// the user ID of the process on the other end of Unix socket connections.
+func withPeerUID(ctx context.Context, conn net.Conn) context.Context {
+	if uid, ok := peerUID(conn); ok {
+		ctx = context.WithValue(ctx, peerUIDKey{}, uid)
+	}
+	return ctx
+}
+
+// copyPeerUID copies the peer user ID recorded in a connection's context to
+// the context of a request served on it.
+func copyPeerUID(ctx context.Context, conn context.Context) context.Context {
+	if uid, ok := conn.Value(peerUIDKey{}).(uint32); ok {
+		ctx = context.WithValue(ctx, peerUIDKey{}, uid)
+	}
+	return ctx
+}
+
+// identities returns the identities of a client, given the context of its
+// request, its authorization header, and the state of its TLS connection,
+// if any.
+func (a *ACL) identities(ctx context.Context, authorization string, state *tls.ConnectionState) []string {
+	var res []string
+	if token := strings.TrimPrefix(authorization, "Bearer "); token != authorization {
+		if id, ok := a.Tokens[token]; ok {
+			res = append(res, id)
+		}
+	}
+	if state != nil && len(state.VerifiedChains) > 0 {
+		res = append(res, "cn:"+state.VerifiedChains[0][0].Subject.CommonName)
+	}
+	if uid, ok := ctx.Value(peerUIDKey{}).(uint32); ok {
+		res = append(res, "uid:"+strconv.FormatUint(uint64(uid), 10))
+	}
+	return append(res, "*")
+}
+
+// allows returns whether any of the identities may read the table.
+func (a *ACL) allows(identities []string, family string, table string) bool {
+	family, table, err := normalizeNames(family, table)
+	if err != nil || family == "" || table == "" {
+		return false
+	}
+	for _, id := range identities {
+		for _, grant := range a.Grants[id] {
+			if grant == "*" || grant == family || grant == family+"."+table {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allowsFamily returns whether any of the identities may read any of the
+// tables of the family.
+func (a *ACL) allowsFamily(identities []string, family string) bool {
+	family, _, err := normalizeNames(family, "")
+	if err != nil || family == "" {
+		return false
+	}
+	for _, id := range identities {
+		for _, grant := range a.Grants[id] {
+			if grant == "*" || grant == family || strings.HasPrefix(grant, family+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// authorize returns a "forbidden" error if the sidecar has an ACL which
+// doesn't allow the client to read the table, or any of the family's
+// tables if table is empty.
+func (s *Sidecar) authorize(identities []string, userAgent string, family string, table string) error {
+	if s.acl == nil {
+		return nil
+	}
+	if table == "" && s.acl.allowsFamily(identities, family) || table != "" && s.acl.allows(identities, family, table) {
+		return nil
+	}
+	stats.Incr("denied-requests-by-user-agent", stats.T("user-agent", orUnknown(userAgent)))
+	name := family
+	if table != "" {
+		name += "." + table
+	}
+	return errors.WithTypes(errors.Errorf("access to '%s' is denied", name), "forbidden")
+}
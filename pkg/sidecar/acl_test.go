@@ -0,0 +1,285 @@
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/segmentio/ctlstore"
+	"github.com/segmentio/ctlstore/pkg/sidecar/sidecarpb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestACLAllows(t *testing.T) {
+	acl, err := (&ACL{Grants: map[string][]string{
+		"all":    {"*"},
+		"family": {"foo"},
+		"table":  {"Bar.BAZ"},
+	}}).normalize()
+	require.NoError(t, err)
+	for _, test := range []struct {
+		identity string
+		family   string
+		table    string
+		allowed  bool
+		anyTable bool // whether any table of the family is allowed
+	}{
+		{identity: "all", family: "foo", table: "bar", allowed: true, anyTable: true},
+		{identity: "family", family: "foo", table: "bar", allowed: true, anyTable: true},
+		{identity: "family", family: "bar", table: "baz", allowed: false, anyTable: false},
+		{identity: "table", family: "bar", table: "baz", allowed: true, anyTable: true},
+		{identity: "table", family: "BAR", table: "Baz", allowed: true, anyTable: true},
+		{identity: "family", family: "Foo", table: "bar", allowed: true, anyTable: true},
+		{identity: "all", family: "foo__bar", table: "baz", allowed: false, anyTable: false},
+		{identity: "table", family: "bar", table: "qux", allowed: false, anyTable: true},
+		{identity: "table", family: "foo", table: "baz", allowed: false, anyTable: false},
+		{identity: "unknown", family: "foo", table: "bar", allowed: false, anyTable: false},
+	} {
+		t.Run(test.identity+"/"+test.family+"."+test.table, func(t *testing.T) {
+			require.Equal(t, test.allowed, acl.allows([]string{test.identity}, test.family, test.table))
+			require.Equal(t, test.anyTable, acl.allowsFamily([]string{test.identity}, test.family))
+		})
+	}
+}
+
+func TestACLNormalize(t *testing.T) {
+	for _, test := range []struct {
+		grant      string
+		normalized string // empty if the grant is invalid
+	}{
+		{grant: "*", normalized: "*"},
+		{grant: "Foo", normalized: "foo"},
+		{grant: "foo.BAR", normalized: "foo.bar"},
+		{grant: "foo__bar"},
+		{grant: "foo."},
+		{grant: ".bar"},
+		{grant: "foo.bar.baz"},
+	} {
+		t.Run(test.grant, func(t *testing.T) {
+			acl, err := (&ACL{Grants: map[string][]string{"id": {test.grant}}}).normalize()
+			if test.normalized == "" {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, []string{test.normalized}, acl.Grants["id"])
+		})
+	}
+}
+
+func TestACLIdentities(t *testing.T) {
+	acl := &ACL{Tokens: map[string]string{"secret": "service"}}
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "client"}}
+	state := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	uidCtx := context.WithValue(context.Background(), peerUIDKey{}, uint32(1000))
+
+	for _, test := range []struct {
+		name          string
+		ctx           context.Context
+		authorization string
+		state         *tls.ConnectionState
+		expected      []string
+	}{
+		{name: "anonymous", ctx: context.Background(), expected: []string{"*"}},
+		{name: "token", ctx: context.Background(), authorization: "Bearer secret", expected: []string{"service", "*"}},
+		{name: "unknown token", ctx: context.Background(), authorization: "Bearer bogus", expected: []string{"*"}},
+		{name: "not a bearer token", ctx: context.Background(), authorization: "secret", expected: []string{"*"}},
+		{name: "certificate", ctx: context.Background(), state: state, expected: []string{"cn:client", "*"}},
+		{name: "unverified certificate", ctx: context.Background(), state: &tls.ConnectionState{}, expected: []string{"*"}},
+		{name: "peer uid", ctx: uidCtx, expected: []string{"uid:1000", "*"}},
+		{name: "everything", ctx: uidCtx, authorization: "Bearer secret", state: state, expected: []string{"service", "cn:client", "uid:1000", "*"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, acl.identities(test.ctx, test.authorization, test.state))
+		})
+	}
+}
+
+func TestSidecarACL(t *testing.T) {
+	tu, teardown := ctlstore.NewLDBTestUtil(t)
+	defer teardown()
+	for _, family := range []string{"public", "private"} {
+		for _, table := range []string{"foo", "bar"} {
+			tu.CreateTable(ctlstore.LDBTestTableDef{
+				Family:    family,
+				Name:      table,
+				Fields:    [][]string{{"key", "string"}},
+				KeyFields: []string{"key"},
+				Rows:      [][]interface{}{{"a"}},
+			})
+		}
+	}
+	sc, err := New(Config{
+		Reader: ctlstore.NewLDBReaderFromDB(tu.DB),
+		ACL: &ACL{
+			Tokens: map[string]string{"secret": "service"},
+			Grants: map[string][]string{
+				"*":       {"public"},
+				"service": {"private.foo"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		token  string
+		status int
+		result interface{}
+	}{
+		{name: "public read", method: "POST", path: "/get-row-by-key/public/foo", body: `{"key":[{"value":"a"}]}`, status: http.StatusOK},
+		{name: "denied read", method: "POST", path: "/get-row-by-key/private/foo", body: `{"key":[{"value":"a"}]}`, status: http.StatusForbidden},
+		{name: "granted read", method: "POST", path: "/get-row-by-key/private/foo", body: `{"key":[{"value":"a"}]}`, token: "secret", status: http.StatusOK},
+		{name: "denied table", method: "POST", path: "/get-row-by-key/private/bar", body: `{"key":[{"value":"a"}]}`, token: "secret", status: http.StatusForbidden},
+		{name: "denied scan", method: "POST", path: "/get-rows-by-key-prefix/private/foo", body: `{"key":[]}`, status: http.StatusForbidden},
+		{name: "denied batch", method: "POST", path: "/batch-get-row-by-key", token: "secret",
+			body:   `{"reads":[{"family":"private","table":"foo","key":[{"value":"a"}]},{"family":"private","table":"bar","key":[{"value":"a"}]}]}`,
+			status: http.StatusForbidden},
+		{name: "listed families", method: "GET", path: "/list-families", status: http.StatusOK, result: []interface{}{"public"}},
+		{name: "listed families with token", method: "GET", path: "/list-families", token: "secret", status: http.StatusOK, result: []interface{}{"private", "public"}},
+		{name: "denied tables", method: "GET", path: "/list-tables/private", status: http.StatusForbidden},
+		{name: "listed tables", method: "GET", path: "/list-tables/private", token: "secret", status: http.StatusOK, result: []interface{}{"foo"}},
+		{name: "denied description", method: "GET", path: "/describe-table/private/bar", token: "secret", status: http.StatusForbidden},
+		{name: "latency", method: "GET", path: "/get-ledger-latency", status: http.StatusOK},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(test.method, test.path, bytes.NewBufferString(test.body))
+			if test.token != "" {
+				r.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			sc.ServeHTTP(w, r)
+			require.Equal(t, test.status, w.Code, w.Body.String())
+			if test.result != nil {
+				var res interface{}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+				require.Equal(t, test.result, res)
+			}
+		})
+	}
+
+	// the client sends its token
+	srv := httptest.NewServer(sc)
+	defer srv.Close()
+	ctx := context.Background()
+	for _, token := range []string{"", "secret"} {
+		client, err := NewClient(ClientConfig{URL: srv.URL, Token: token})
+		require.NoError(t, err)
+		_, err = client.GetRowByKey(ctx, map[string]interface{}{}, "private", "foo", "a")
+		if token == "" {
+			require.Error(t, err)
+			require.Equal(t, http.StatusForbidden, err.(*ResponseError).StatusCode)
+		} else {
+			require.NoError(t, err)
+		}
+	}
+
+	// as do gRPC clients
+	g := &grpcService{sidecar: sc}
+	req := &sidecarpb.GetRowByKeyRequest{
+		Family: "private",
+		Table:  "foo",
+		Key:    []*sidecarpb.Key{{Value: &sidecarpb.Key_String_{String_: "a"}}},
+	}
+	_, err = g.GetRowByKey(ctx, req)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	res, err := g.GetRowByKey(metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer secret")), req)
+	require.NoError(t, err)
+	require.True(t, res.Found)
+}
+
+func TestSidecarACLPeerUID(t *testing.T) {
+	if !peerUIDSupported(t) {
+		t.Skip("peer credentials aren't supported on this platform")
+	}
+	tu, teardown := ctlstore.NewLDBTestUtil(t)
+	defer teardown()
+	tu.CreateTable(ctlstore.LDBTestTableDef{
+		Family:    "family",
+		Name:      "table",
+		Fields:    [][]string{{"key", "string"}},
+		KeyFields: []string{"key"},
+		Rows:      [][]interface{}{{"a"}},
+	})
+
+	dir, err := ioutil.TempDir("", "sidecar_acl")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sidecar.sock")
+
+	for _, test := range []struct {
+		name   string
+		uid    int
+		status int
+	}{
+		{name: "granted", uid: os.Getuid(), status: http.StatusOK},
+		{name: "denied", uid: os.Getuid() + 1, status: http.StatusForbidden},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			sc, err := New(Config{
+				Reader: ctlstore.NewLDBReaderFromDB(tu.DB),
+				ACL: &ACL{Grants: map[string][]string{
+					"uid:" + strconv.Itoa(test.uid): {"family"},
+				}},
+			})
+			require.NoError(t, err)
+			lis, err := net.Listen("unix", path)
+			require.NoError(t, err)
+			srv := httptest.NewUnstartedServer(sc)
+			srv.Listener = lis
+			srv.Config.ConnContext = withPeerUID
+			srv.Start()
+			defer srv.Close()
+
+			client := &http.Client{Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, "unix", path)
+				},
+			}}
+			res, err := client.Post("http://sidecar/get-row-by-key/family/table", "application/json",
+				bytes.NewBufferString(`{"key":[{"value":"a"}]}`))
+			require.NoError(t, err)
+			res.Body.Close()
+			require.Equal(t, test.status, res.StatusCode)
+		})
+	}
+}
+
+// peerUIDSupported checks whether the user ID of a Unix socket peer can be
+// read, which depends on the platform.
+func peerUIDSupported(t *testing.T) bool {
+	dir, err := ioutil.TempDir("", "peer_uid")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	lis, err := net.Listen("unix", filepath.Join(dir, "test.sock"))
+	require.NoError(t, err)
+	defer lis.Close()
+	go func() {
+		conn, err := net.Dial("unix", filepath.Join(dir, "test.sock"))
+		if err == nil {
+			defer conn.Close()
+		}
+	}()
+	conn, err := lis.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	_, ok := peerUID(conn)
+	return ok
+}
@@ -0,0 +1,133 @@
+package sidecar
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// groupBatchReads returns the indexes of the reads of each table in a batch,
+// in the order the tables first appear in.
+func groupBatchReads(reads []BatchRead) [][]int {
+	var groups [][]int
+	byTable := make(map[string]int)
+	for i, read := range reads {
+		table := read.Family + "\x00" + read.Table
+		g, ok := byTable[table]
+		if !ok {
+			g = len(groups)
+			byTable[table] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups
+}
+
+// readBatch reads the rows of a group of reads of the same table at once,
+// filling in their results, if the reader supports it. It returns false if
+// the reads have to be made one at a time instead, which is also the case
+// if reading them together failed, so that each read gets its own error.
+func (s *Sidecar) readBatch(ctx context.Context, reads []BatchRead, group []int, res []BatchReadResult) bool {
+	batcher, ok := s.reader.(BatchReader)
+	if !ok || len(group) < 2 {
+		return false
+	}
+	schemas, ok := s.reader.(SchemaReader)
+	if !ok {
+		return false
+	}
+	family, table := reads[group[0]].Family, reads[group[0]].Table
+	ts, err := schemas.DescribeTable(ctx, family, table)
+	if err != nil {
+		return false
+	}
+	keyFields := ts.PrimaryKey.Strings()
+
+	keys := make([][]interface{}, len(group))
+	byKey := make(map[string][]int, len(group))
+	for j, i := range group {
+		keys[j] = keysToInterface(reads[i].Key)
+		if len(keys[j]) != len(keyFields) {
+			return false
+		}
+		id := batchKeyID(keys[j])
+		byKey[id] = append(byKey[id], i)
+	}
+
+	rows, err := batcher.GetRowsByKeys(ctx, family, table, keys)
+	if err != nil {
+		return false
+	}
+	defer rows.Close()
+	found := make(map[int]map[string]interface{}, len(group))
+	key := make([]interface{}, len(keyFields))
+	for rows.Next() {
+		out := make(map[string]interface{})
+		if err := rows.Scan(out); err != nil {
+			return false
+		}
+		for f, name := range keyFields {
+			key[f] = out[name]
+		}
+		for _, i := range byKey[batchKeyID(key)] {
+			found[i] = out
+		}
+	}
+	if rows.Err() != nil {
+		return false
+	}
+	for _, i := range group {
+		if row, ok := found[i]; ok {
+			res[i] = BatchReadResult{Found: true, Row: row}
+		}
+	}
+	return true
+}
+
+// readOne reads the row of a single read of a batch.
+func (s *Sidecar) readOne(ctx context.Context, read BatchRead) BatchReadResult {
+	out := make(map[string]interface{})
+	found, err := s.reader.GetRowByKey(ctx, out, read.Family, read.Table, keysToInterface(read.Key)...)
+	switch {
+	case err != nil:
+		return BatchReadResult{Error: err.Error()}
+	case found:
+		return BatchReadResult{Found: true, Row: out}
+	}
+	return BatchReadResult{}
+}
+
+// batchKeyID identifies a primary key whether its values were decoded from
+// a request or read from the LDB. The values are compared as text, the way
+// SQLite compares a value with a column of another type: a JSON number
+// matches an integer column, and a string matches a binary one.
+func batchKeyID(key []interface{}) string {
+	var b strings.Builder
+	for _, v := range key {
+		var text string
+		switch v := v.(type) {
+		case string:
+			text = v
+		case []byte:
+			text = string(v)
+		case int64:
+			text = strconv.FormatInt(v, 10)
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<63 {
+				text = strconv.FormatInt(int64(v), 10)
+			} else {
+				text = strconv.FormatFloat(v, 'g', -1, 64)
+			}
+		default:
+			text = fmt.Sprint(v)
+		}
+		// length prefixed, so that values can't run into each other
+		b.WriteString(strconv.Itoa(len(text)))
+		b.WriteByte(':')
+		b.WriteString(text)
+	}
+	return b.String()
+}
@@ -0,0 +1,240 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/ctlstore/pkg/event"
+	"github.com/segmentio/events"
+	"github.com/segmentio/stats/v4"
+)
+
+const changesPath = "/changes"
+
+var (
+	// changeStreamKeepAliveInterval is how often a comment is sent to idle
+	// change streams, so that proxies don't time them out.
+	changeStreamKeepAliveInterval = 15 * time.Second
+	// writeTimeout bounds how long the sidecar takes to respond to requests,
+	// other than streams.
+	writeTimeout = 5 * time.Second
+)
+
+// Change is the data of each change event sent by the changes endpoint,
+// which is the ledger sequence of the statement which made the change, and
+// the index of the change among those the statement made, along with the
+// row update.
+type Change struct {
+	Sequence int64 `json:"seq"`
+	Index    int   `json:"index,omitempty"`
+	event.RowUpdate
+}
+
+// changeID is the ID of a change event. It's formatted as the ledger
+// sequence of the change, followed by "-<index>" if it isn't the first
+// change made by its statement. Since it's based on the ledger, it's still
+// valid after the reflector writing the changelog restarts.
+type changeID struct {
+	seq   int64
+	index int
+}
+
+func parseChangeID(s string) (changeID, error) {
+	var id changeID
+	seq, index := s, ""
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		seq, index = s[:i], s[i+1:]
+	}
+	var err error
+	if id.seq, err = strconv.ParseInt(seq, 10, 64); err != nil {
+		return id, err
+	}
+	if index != "" {
+		if id.index, err = strconv.Atoi(index); err != nil {
+			return id, err
+		}
+	}
+	return id, nil
+}
+
+func (id changeID) String() string {
+	if id.index == 0 {
+		return strconv.FormatInt(id.seq, 10)
+	}
+	return strconv.FormatInt(id.seq, 10) + "-" + strconv.Itoa(id.index)
+}
+
+func (id changeID) less(other changeID) bool {
+	return id.seq < other.seq || id.seq == other.seq && id.index < other.index
+}
+
+// withChanges serves change streams from the changelog, and everything else
+// from the supplied handler.
+func (s *Sidecar) withChanges(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != changesPath {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		ua := orUnknown(r.UserAgent())
+		stats.Incr("requests-by-user-agent", stats.T("user-agent", ua))
+		s.streamChanges(w, r)
+	})
+}
+
+// streamChanges streams the changes read from the changelog as server-sent
+// events, optionally only those to a single family or table. Each event's
+// ID is its changeID, so a stream can be resumed after the last event that
+// was received by sending the Last-Event-ID header, or the last_event_id
+// query parameter. If changes were missed, because they are
+// no longer in the changelog or it couldn't be kept up with, an
+// "out-of-sync" event is sent before the next change. Changes to tables
+// the client may not read are left out.
+func (s *Sidecar) streamChanges(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	family := query.Get("family")
+	table := query.Get("table")
+	// the changelog has the LDB's names, which are normalized
+	family, table, err := normalizeNames(family, table)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	identities := s.identities(r)
+	if family != "" {
+		if err := s.authorize(identities, r.UserAgent(), family, table); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+	var lastID changeID
+	resumed := false
+	if v := r.Header.Get("Last-Event-ID"); v != "" || query.Get("last_event_id") != "" {
+		if v == "" {
+			v = query.Get("last_event_id")
+		}
+		id, err := parseChangeID(v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid last event id '%s'", v), http.StatusBadRequest)
+			return
+		}
+		lastID, resumed = id, true
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	iter, err := event.NewIterator(ctx, s.changelogPath)
+	if err != nil {
+		http.Error(w, "could not read changelog: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer iter.Close()
+
+	type result struct {
+		event event.Event
+		err   error
+	}
+	results := make(chan result)
+	go func() {
+		for {
+			e, err := iter.Next(ctx)
+			select {
+			case results <- result{e, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil && err != event.ErrOutOfSync {
+				return
+			}
+		}
+	}()
+
+	// the stream outlasts the server's write timeout
+	setWriteDeadline(w, r, time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	stats.Incr("change-streams", stats.T("family", orUnknown(family)), stats.T("table", orUnknown(table)))
+
+	keepAlive := time.NewTicker(changeStreamKeepAliveInterval)
+	defer keepAlive.Stop()
+	// the changelog is read from the start, so resuming only works if the
+	// last event received is still in it
+	checkResume := resumed
+	for {
+		var res result
+		select {
+		case res = <-results:
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+			continue
+		case <-ctx.Done():
+			return
+		case <-s.closing:
+			return
+		}
+
+		outOfSync := false
+		switch {
+		case res.err == event.ErrOutOfSync:
+			outOfSync = true
+		case res.err != nil:
+			if ctx.Err() == nil {
+				events.Log("Change stream failed: %{error}+v", res.err)
+				data, _ := json.Marshal(map[string]string{"error": res.err.Error()})
+				writeEvent(w, "", "error", string(data))
+				flusher.Flush()
+			}
+			return
+		}
+		id := changeID{seq: res.event.LedgerSequence, index: res.event.LedgerIndex}
+		if checkResume {
+			checkResume = false
+			outOfSync = outOfSync || lastID.less(id)
+		}
+		if resumed && !lastID.less(id) {
+			continue
+		}
+		if outOfSync {
+			writeEvent(w, "", "out-of-sync", "{}")
+		}
+		update := res.event.RowUpdate
+		if (family == "" || update.FamilyName == family) && (table == "" || update.TableName == table) &&
+			(s.acl == nil || s.acl.allows(identities, update.FamilyName, update.TableName)) {
+			data, err := json.Marshal(Change{Sequence: id.seq, Index: id.index, RowUpdate: update})
+			if err != nil {
+				events.Log("Could not encode change: %{error}+v", err)
+				return
+			}
+			writeEvent(w, id.String(), "change", string(data))
+			stats.Incr("change-stream-events", stats.T("family", update.FamilyName), stats.T("table", update.TableName))
+		} else if !outOfSync {
+			continue
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes a server-sent event. data must not contain newlines.
+func writeEvent(w http.ResponseWriter, id string, name string, data string) {
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+}
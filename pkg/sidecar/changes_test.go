@@ -0,0 +1,199 @@
+package sidecar
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/ctlstore"
+	"github.com/segmentio/ctlstore/pkg/changelog"
+	"github.com/segmentio/ctlstore/pkg/logwriter"
+	"github.com/segmentio/ctlstore/pkg/tests"
+	"github.com/stretchr/testify/require"
+)
+
+type sseEvent struct {
+	id   string
+	name string
+	data string
+}
+
+// readEvents reads n server-sent events from the stream, skipping comments.
+func readEvents(t *testing.T, scanner *bufio.Scanner, n int) []sseEvent {
+	var res []sseEvent
+	var cur sseEvent
+	for len(res) < n && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if cur.name != "" {
+				res = append(res, cur)
+			}
+			cur = sseEvent{}
+		case strings.HasPrefix(line, "id: "):
+			cur.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			cur.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			cur.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	require.NoError(t, scanner.Err())
+	require.Len(t, res, n)
+	return res
+}
+
+func TestStreamChanges(t *testing.T) {
+	f, teardown := tests.WithTmpFile(t, "changelog")
+	defer teardown()
+	w := &changelog.ChangelogWriter{
+		WriteLine: &logwriter.SizedLogWriter{Path: f.Name(), FileMode: 0644, RotateSize: 1024 * 1024},
+	}
+	for _, entry := range []changelog.ChangelogEntry{
+		{Seq: 3, LedgerSeq: 30, Family: "foo", Table: "bar", Op: changelog.OpInsert,
+			New: map[string]interface{}{"key": "a", "value": "one"}},
+		{Seq: 4, LedgerSeq: 40, Family: "foo", Table: "baz", Op: changelog.OpInsert,
+			New: map[string]interface{}{"key": "a"}},
+		{Seq: 5, LedgerSeq: 40, LedgerIndex: 1, Family: "qux", Table: "bar", Op: changelog.OpInsert,
+			New: map[string]interface{}{"key": "a"}},
+		{Seq: 6, LedgerSeq: 60, Family: "foo", Table: "bar", Op: changelog.OpUpdate,
+			Old: map[string]interface{}{"key": "a", "value": "one"},
+			New: map[string]interface{}{"key": "a", "value": "two"}},
+		// the reflector restarted, so the changelog's own sequence started
+		// over, but the ledger's didn't
+		{Seq: 1, LedgerSeq: 70, Family: "foo", Table: "bar", Op: changelog.OpDelete,
+			Old: map[string]interface{}{"key": "a", "value": "two"}},
+	} {
+		require.NoError(t, w.WriteChange(entry))
+	}
+
+	tu, teardownLDB := ctlstore.NewLDBTestUtil(t)
+	defer teardownLDB()
+	sc, err := New(Config{
+		Reader:        ctlstore.NewLDBReaderFromDB(tu.DB),
+		ChangelogPath: f.Name(),
+	})
+	require.NoError(t, err)
+	srv := httptest.NewServer(sc)
+	defer srv.Close()
+
+	for _, test := range []struct {
+		name        string
+		query       string
+		lastEventID string
+		expected    []string // the ids of the events, or the names of others
+	}{
+		{name: "everything", expected: []string{"30", "40", "40-1", "60", "out-of-sync", "70"}},
+		{name: "family", query: "family=foo", expected: []string{"30", "40", "60", "out-of-sync", "70"}},
+		{name: "table", query: "family=foo&table=bar", expected: []string{"30", "60"}},
+		{name: "table in another case", query: "family=FOO&table=Bar", expected: []string{"30", "60"}},
+		{name: "resumed", query: "family=foo", lastEventID: "40", expected: []string{"60"}},
+		{name: "resumed by query", query: "last_event_id=40-1", expected: []string{"60"}},
+		{name: "resumed after a gap", lastEventID: "20", expected: []string{"out-of-sync", "30", "40"}},
+		{name: "resumed after a restart", lastEventID: "60", expected: []string{"out-of-sync", "70"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			req, err := http.NewRequest(http.MethodGet, srv.URL+"/changes?"+test.query, nil)
+			require.NoError(t, err)
+			req = req.WithContext(ctx)
+			if test.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", test.lastEventID)
+			}
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			require.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+			var got []string
+			for _, e := range readEvents(t, bufio.NewScanner(res.Body), len(test.expected)) {
+				if e.name != "change" {
+					got = append(got, e.name)
+					continue
+				}
+				var change Change
+				require.NoError(t, json.Unmarshal([]byte(e.data), &change))
+				require.Equal(t, e.id, changeID{seq: change.Sequence, index: change.Index}.String())
+				got = append(got, e.id)
+				if change.Sequence == 60 {
+					require.Equal(t, "foo", change.FamilyName)
+					require.Equal(t, "bar", change.TableName)
+					require.Equal(t, changelog.OpUpdate, change.Op)
+					require.Equal(t, "two", change.NewRow["value"])
+				}
+			}
+			require.Equal(t, test.expected, got)
+		})
+	}
+
+	// other requests still work
+	r := httptest.NewRequest(http.MethodGet, "/list-families", nil)
+	rec := httptest.NewRecorder()
+	sc.ServeHTTP(rec, r)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "/changes", nil)
+	r.Header.Set("Last-Event-ID", "bogus")
+	rec = httptest.NewRecorder()
+	sc.ServeHTTP(rec, r)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "/changes?family=foo__bar", nil)
+	rec = httptest.NewRecorder()
+	sc.ServeHTTP(rec, r)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestStreamChangesNotConfigured(t *testing.T) {
+	tu, teardown := ctlstore.NewLDBTestUtil(t)
+	defer teardown()
+	sc, err := New(Config{Reader: ctlstore.NewLDBReaderFromDB(tu.DB)})
+	require.NoError(t, err)
+	r := httptest.NewRequest(http.MethodGet, "/changes", nil)
+	w := httptest.NewRecorder()
+	sc.ServeHTTP(w, r)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestStreamChangesOutlastsWriteTimeout(t *testing.T) {
+	f, teardown := tests.WithTmpFile(t, "changelog")
+	defer teardown()
+	defer func(interval time.Duration) { changeStreamKeepAliveInterval = interval }(changeStreamKeepAliveInterval)
+	changeStreamKeepAliveInterval = 200 * time.Millisecond
+
+	tu, teardownLDB := ctlstore.NewLDBTestUtil(t)
+	defer teardownLDB()
+	sc, err := New(Config{
+		Reader:        ctlstore.NewLDBReaderFromDB(tu.DB),
+		ChangelogPath: f.Name(),
+	})
+	require.NoError(t, err)
+	srv := httptest.NewUnstartedServer(sc)
+	srv.Config.WriteTimeout = 50 * time.Millisecond
+	srv.Config.ConnContext = connContext
+	srv.Start()
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/changes", nil)
+	require.NoError(t, err)
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	// the keep-alive is only sent after the write timeout has passed
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() && scanner.Text() != ": keep-alive" {
+	}
+	require.NoError(t, scanner.Err())
+	require.Equal(t, ": keep-alive", scanner.Text())
+}
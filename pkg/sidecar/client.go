@@ -0,0 +1,424 @@
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/ctlstore"
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/segmentio/errors-go"
+)
+
+const (
+	DefaultClientTimeout      = 5 * time.Second
+	DefaultClientRetryBackoff = 50 * time.Millisecond
+)
+
+type (
+	// ClientConfig configures a Client.
+	ClientConfig struct {
+		// URL is the address of the sidecar, e.g. http://localhost:1331,
+		// or unix:///path/to.sock if it's listening on a Unix socket.
+		URL string
+		// Timeout bounds each attempt at a request, and defaults to
+		// DefaultClientTimeout.
+		Timeout time.Duration
+		// Retries is how many more times a request is attempted after it
+		// fails to reach the sidecar, or the sidecar responds with a 502,
+		// 503 or 504.
+		Retries int
+		// RetryBackoff is how long to wait before the first retry, doubling
+		// for each one after that. Defaults to DefaultClientRetryBackoff.
+		RetryBackoff time.Duration
+		// HTTPClient makes the requests, and defaults to http.DefaultClient.
+		HTTPClient *http.Client
+		// Application is sent as the user agent, which the sidecar breaks
+		// its request metrics down by.
+		Application string
+		// Token, if set, is sent as a bearer token to identify the client to
+		// the sidecar's ACL.
+		Token string
+	}
+
+	// Client reads from a ctlstore sidecar over HTTP, for processes which
+	// don't have access to an LDB of their own. Rows are decoded into
+	// structs and maps the same way that an LDBReader decodes them.
+	Client struct {
+		baseURL     string
+		timeout     time.Duration
+		retries     int
+		backoff     time.Duration
+		httpClient  *http.Client
+		application string
+		token       string
+
+		schemasMu sync.Mutex
+		schemas   map[string]ctlstore.TableSchema // keyed by family and table
+	}
+
+	// ResponseError is returned by Client when the sidecar responds with an
+	// unexpected status.
+	ResponseError struct {
+		StatusCode int
+		Message    string
+	}
+)
+
+var (
+	_ Reader         = (*Client)(nil)
+	_ SequenceReader = (*Client)(nil)
+)
+
+func (e *ResponseError) Error() string {
+	return "sidecar responded with " + http.StatusText(e.StatusCode) + ": " + e.Message
+}
+
+// NewClient returns a Client for the sidecar at the configured URL.
+func NewClient(config ClientConfig) (*Client, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse sidecar url")
+	}
+	baseURL := strings.TrimSuffix(config.URL, "/")
+	httpClient := config.HTTPClient
+	switch {
+	case u.Scheme == "unix" && u.Path != "":
+		baseURL = "http://sidecar"
+		if httpClient == nil {
+			httpClient = &http.Client{Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, "unix", u.Path)
+				},
+			}}
+		}
+	case u.Scheme == "" || u.Host == "":
+		return nil, errors.Errorf("invalid sidecar url '%s'", config.URL)
+	}
+	c := &Client{
+		baseURL:     baseURL,
+		timeout:     config.Timeout,
+		retries:     config.Retries,
+		backoff:     config.RetryBackoff,
+		httpClient:  httpClient,
+		application: config.Application,
+		token:       config.Token,
+		schemas:     make(map[string]ctlstore.TableSchema),
+	}
+	if c.timeout <= 0 {
+		c.timeout = DefaultClientTimeout
+	}
+	if c.backoff <= 0 {
+		c.backoff = DefaultClientRetryBackoff
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+	return c, nil
+}
+
+// GetRowByKey fetches a row through the sidecar, see
+// ctlstore.LDBReader.GetRowByKey.
+func (c *Client) GetRowByKey(ctx context.Context, out interface{}, familyName string, tableName string, key ...interface{}) (found bool, err error) {
+	body, err := json.Marshal(ReadRequest{Key: interfaceToKeys(key)})
+	if err != nil {
+		return false, errors.Wrap(err, "encode request")
+	}
+	res, _, err := c.request(ctx, http.MethodPost, "/get-row-by-key/"+pathEscape(familyName, tableName), body)
+	if isNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var row map[string]interface{}
+	if err := decodeJSON(res, &row); err != nil {
+		return false, err
+	}
+	rows, err := c.newRows(ctx, familyName, tableName, []map[string]interface{}{row})
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	rows.Next()
+	if err := rows.Scan(out); err != nil {
+		return true, errors.Wrap(err, "target row scan error")
+	}
+	return true, nil
+}
+
+// GetRowsByKeyPrefix fetches the rows matching the key prefix through the
+// sidecar, see ctlstore.LDBReader.GetRowsByKeyPrefix. All of the rows are
+// read before returning. If there are more of them than the sidecar
+// returns at once, they're read again a page at a time.
+func (c *Client) GetRowsByKeyPrefix(ctx context.Context, familyName string, tableName string, key ...interface{}) (*ctlstore.Rows, error) {
+	body, err := json.Marshal(ReadRequest{Key: interfaceToKeys(key)})
+	if err != nil {
+		return nil, errors.Wrap(err, "encode request")
+	}
+	path := "/get-rows-by-key-prefix/" + pathEscape(familyName, tableName)
+	var rows []map[string]interface{}
+	pagePath := path
+	for {
+		res, header, err := c.request(ctx, http.MethodPost, pagePath, body)
+		if errors.Is("limit-exceeded", err) && pagePath == path {
+			pagePath = path + "?page_size=" + strconv.Itoa(clientPageSize)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var page []map[string]interface{}
+		if err := decodeJSON(res, &page); err != nil {
+			return nil, err
+		}
+		rows = append(rows, page...)
+		token := header.Get(NextPageTokenHeader)
+		if token == "" {
+			break
+		}
+		pagePath = path + "?page_size=" + strconv.Itoa(clientPageSize) + "&page_token=" + url.QueryEscape(token)
+	}
+	return c.newRows(ctx, familyName, tableName, rows)
+}
+
+// clientPageSize is the number of rows the client asks for in each page of
+// a prefix scan which has more rows than the sidecar returns at once. The
+// sidecar caps it at its own max row count.
+const clientPageSize = 1000
+
+// GetLedgerLatency returns the ledger latency of the sidecar's LDB.
+func (c *Client) GetLedgerLatency(ctx context.Context) (time.Duration, error) {
+	res, _, err := c.request(ctx, http.MethodGet, "/get-ledger-latency", nil)
+	if err != nil {
+		return 0, err
+	}
+	var latency struct {
+		Value float64 `json:"value"`
+	}
+	if err := json.Unmarshal(res, &latency); err != nil {
+		return 0, errors.Wrap(err, "decode response")
+	}
+	return time.Duration(latency.Value * float64(time.Second)), nil
+}
+
+// GetLastSequence returns the last ledger sequence applied to the sidecar's
+// LDB.
+func (c *Client) GetLastSequence(ctx context.Context) (schema.DMLSequence, error) {
+	res, _, err := c.request(ctx, http.MethodGet, "/get-ledger-sequence", nil)
+	if err != nil {
+		return 0, err
+	}
+	var seq struct {
+		Value int64 `json:"value"`
+	}
+	if err := json.Unmarshal(res, &seq); err != nil {
+		return 0, errors.Wrap(err, "decode response")
+	}
+	return schema.DMLSequence(seq.Value), nil
+}
+
+// ListFamilies lists the families in the sidecar's LDB.
+func (c *Client) ListFamilies(ctx context.Context) ([]string, error) {
+	res, _, err := c.request(ctx, http.MethodGet, "/list-families", nil)
+	if err != nil {
+		return nil, err
+	}
+	var families []string
+	return families, errors.Wrap(json.Unmarshal(res, &families), "decode response")
+}
+
+// ListTables lists the tables of a family in the sidecar's LDB.
+func (c *Client) ListTables(ctx context.Context, familyName string) ([]string, error) {
+	res, _, err := c.request(ctx, http.MethodGet, "/list-tables/"+url.PathEscape(familyName), nil)
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	return tables, errors.Wrap(json.Unmarshal(res, &tables), "decode response")
+}
+
+// DescribeTable describes a table in the sidecar's LDB.
+// ctlstore.ErrTableNotFound is returned if the table does not exist.
+func (c *Client) DescribeTable(ctx context.Context, familyName string, tableName string) (ctlstore.TableSchema, error) {
+	res, _, err := c.request(ctx, http.MethodGet, "/describe-table/"+pathEscape(familyName, tableName), nil)
+	if isNotFound(err) {
+		return ctlstore.TableSchema{}, ctlstore.ErrTableNotFound
+	}
+	if err != nil {
+		return ctlstore.TableSchema{}, err
+	}
+	var desc TableDescription
+	if err := json.Unmarshal(res, &desc); err != nil {
+		return ctlstore.TableSchema{}, errors.Wrap(err, "decode response")
+	}
+	return desc.toTableSchema()
+}
+
+// newRows converts rows from the sidecar into their LDB types, describing
+// the table again if they don't match the schema that was cached for it.
+func (c *Client) newRows(ctx context.Context, familyName string, tableName string, rows []map[string]interface{}) (*ctlstore.Rows, error) {
+	cacheKey := familyName + "\x00" + tableName
+	c.schemasMu.Lock()
+	ts, ok := c.schemas[cacheKey]
+	c.schemasMu.Unlock()
+	if ok {
+		if res, err := ctlstore.NewRowsFromJSON(ts, rows); err == nil {
+			return res, nil
+		}
+	}
+	ts, err := c.DescribeTable(ctx, familyName, tableName)
+	if err != nil {
+		return nil, errors.Wrap(err, "describe table")
+	}
+	c.schemasMu.Lock()
+	c.schemas[cacheKey] = ts
+	c.schemasMu.Unlock()
+	return ctlstore.NewRowsFromJSON(ts, rows)
+}
+
+// request makes a request to the sidecar, retrying it if it fails with an
+// error that could be temporary, and returns the response body and headers.
+func (c *Client) request(ctx context.Context, method string, path string, body []byte) ([]byte, http.Header, error) {
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		res, header, err := c.attempt(ctx, method, path, body)
+		if err == nil || attempt >= c.retries || !isRetryable(err) || ctx.Err() != nil {
+			return res, header, err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, method string, path string, body []byte) ([]byte, http.Header, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "build request")
+	}
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.application != "" {
+		req.Header.Set("User-Agent", c.application)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if seq, ok := ctx.Value(minSequenceKey{}).(schema.DMLSequence); ok {
+		req.Header.Set(MinSequenceHeader, strconv.FormatInt(seq.Int(), 10))
+	}
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, errors.WithTypes(errors.Wrap(err, "sidecar request"), "transport")
+	}
+	defer res.Body.Close()
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, errors.WithTypes(errors.Wrap(err, "read response"), "transport")
+	}
+	switch {
+	case res.StatusCode == http.StatusOK:
+		return resBody, res.Header, nil
+	case res.StatusCode == http.StatusNotFound && res.Header.Get("X-Ctlstore") == "Not Found":
+		return nil, nil, errNotFound
+	case res.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		return nil, nil, errors.WithTypes(errors.New("max row count exceeded"), "limit-exceeded")
+	default:
+		return nil, nil, &ResponseError{
+			StatusCode: res.StatusCode,
+			Message:    strings.TrimSpace(string(resBody)),
+		}
+	}
+}
+
+// errNotFound is returned by attempt when the sidecar reports that a row or
+// table wasn't found, as opposed to the route not existing.
+var errNotFound = errors.New("not found")
+
+func isNotFound(err error) bool {
+	return err == errNotFound
+}
+
+// isRetryable returns true for errors which may not happen again, i.e.
+// failures to reach the sidecar at all, and responses from a proxy or a
+// sidecar which isn't ready to serve yet. Other errors would most likely
+// happen again.
+func isRetryable(err error) bool {
+	if err, ok := err.(*ResponseError); ok {
+		switch err.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	return errors.Is("transport", err)
+}
+
+func decodeJSON(body []byte, out interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	// keeps integers from losing precision
+	dec.UseNumber()
+	return errors.Wrap(dec.Decode(out), "decode response")
+}
+
+func pathEscape(familyName string, tableName string) string {
+	return url.PathEscape(familyName) + "/" + url.PathEscape(tableName)
+}
+
+func interfaceToKeys(key []interface{}) []Key {
+	keys := make([]Key, len(key))
+	for i, k := range key {
+		if b, ok := k.([]byte); ok {
+			keys[i] = Key{Binary: b}
+		} else {
+			keys[i] = Key{Value: k}
+		}
+	}
+	return keys
+}
+
+// toTableSchema converts a table description back into a TableSchema.
+func (d TableDescription) toTableSchema() (ctlstore.TableSchema, error) {
+	ts := ctlstore.TableSchema{
+		FamilyName: d.Family,
+		TableName:  d.Table,
+		Fields:     make([]schema.NamedFieldType, len(d.Fields)),
+	}
+	fieldTypes := schema.FieldTypeMap()
+	for i, field := range d.Fields {
+		if len(field) != 2 {
+			return ctlstore.TableSchema{}, errors.Errorf("invalid field description %v", field)
+		}
+		ft, ok := fieldTypes[field[1]]
+		if !ok {
+			return ctlstore.TableSchema{}, errors.Errorf("unknown type '%s' of field '%s'", field[1], field[0])
+		}
+		ts.Fields[i] = schema.NamedFieldType{Name: schema.FieldName{Name: field[0]}, FieldType: ft}
+	}
+	for _, name := range d.KeyFields {
+		for _, field := range ts.Fields {
+			if field.Name.Name == name {
+				ts.PrimaryKey.Fields = append(ts.PrimaryKey.Fields, field.Name)
+				ts.PrimaryKey.Types = append(ts.PrimaryKey.Types, field.FieldType)
+			}
+		}
+	}
+	return ts, nil
+}
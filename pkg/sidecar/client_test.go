@@ -0,0 +1,309 @@
+package sidecar
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/segmentio/ctlstore"
+	"github.com/segmentio/ctlstore/pkg/ldb"
+	"github.com/segmentio/errors-go"
+	"github.com/segmentio/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+type clientTestRow struct {
+	Key   string  `ctlstore:"key"`
+	ID    int64   `ctlstore:"id"`
+	Data  []byte  `ctlstore:"data"`
+	Score float64 `ctlstore:"score"`
+}
+
+func newClientTestSidecar(t *testing.T) (*httptest.Server, *ctlstore.LDBTestUtil, func()) {
+	tu, teardown := ctlstore.NewLDBTestUtil(t)
+	tu.CreateTable(ctlstore.LDBTestTableDef{
+		Family: "family",
+		Name:   "table",
+		Fields: [][]string{
+			{"key", "string"},
+			{"id", "integer"},
+			{"data", "binary"},
+			{"score", "decimal"},
+		},
+		KeyFields: []string{"key", "id"},
+		Rows: [][]interface{}{
+			{"a", 1 << 60, []byte{0, 1, 2}, 1.5},
+			{"a", 2, nil, 2.5},
+		},
+	})
+	sc, err := New(Config{Reader: ctlstore.NewLDBReaderFromDB(tu.DB)})
+	require.NoError(t, err)
+	srv := httptest.NewServer(sc)
+	return srv, tu, func() {
+		srv.Close()
+		teardown()
+	}
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	srv, tu, teardown := newClientTestSidecar(t)
+	defer teardown()
+	client, err := NewClient(ClientConfig{URL: srv.URL})
+	require.NoError(t, err)
+
+	var row clientTestRow
+	found, err := client.GetRowByKey(ctx, &row, "family", "table", "a", 1<<60)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, clientTestRow{"a", 1 << 60, []byte{0, 1, 2}, 1.5}, row)
+
+	found, err = client.GetRowByKey(ctx, &row, "family", "table", "b", 1)
+	require.NoError(t, err)
+	require.False(t, found)
+
+	m := map[string]interface{}{}
+	found, err = client.GetRowByKey(ctx, m, "family", "table", "a", 2)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, map[string]interface{}{"key": "a", "id": int64(2), "data": nil, "score": 2.5}, m)
+
+	rows, err := client.GetRowsByKeyPrefix(ctx, "family", "table", "a")
+	require.NoError(t, err)
+	var all []clientTestRow
+	require.NoError(t, rows.ScanAll(&all))
+	require.Equal(t, []clientTestRow{
+		{"a", 2, nil, 2.5},
+		{"a", 1 << 60, []byte{0, 1, 2}, 1.5},
+	}, all)
+
+	// rows beyond the sidecar's row limit are read a page at a time
+	srv.Config.Handler, err = New(Config{Reader: ctlstore.NewLDBReaderFromDB(tu.DB), MaxRows: 1})
+	require.NoError(t, err)
+	rows, err = client.GetRowsByKeyPrefix(ctx, "family", "table", "a")
+	require.NoError(t, err)
+	all = nil
+	require.NoError(t, rows.ScanAll(&all))
+	require.Len(t, all, 2)
+
+	families, err := client.ListFamilies(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"family"}, families)
+	tables, err := client.ListTables(ctx, "family")
+	require.NoError(t, err)
+	require.Equal(t, []string{"table"}, tables)
+	ts, err := client.DescribeTable(ctx, "family", "table")
+	require.NoError(t, err)
+	require.Equal(t, []string{"key", "id"}, ts.PrimaryKey.Strings())
+	_, err = client.DescribeTable(ctx, "family", "missing")
+	require.Equal(t, ctlstore.ErrTableNotFound, err)
+
+	latency, err := client.GetLedgerLatency(ctx)
+	require.NoError(t, err)
+	require.True(t, latency >= 0 && latency < 5*time.Second, "weird latency: %v", latency)
+	_, err = client.GetLastSequence(ctx)
+	require.NoError(t, err)
+}
+
+func TestClientRetries(t *testing.T) {
+	ctx := context.Background()
+	srv, _, teardown := newClientTestSidecar(t)
+	defer teardown()
+
+	var failures, requests, status int32
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			status := int(atomic.LoadInt32(&status))
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		srv.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer flaky.Close()
+
+	for _, test := range []struct {
+		name     string
+		status   int32 // defaults to 503
+		failures int32
+		retries  int
+		requests int32 // defaults to retries+1
+		err      bool
+	}{
+		{name: "no failures", failures: 0, retries: 0},
+		{name: "retried", failures: 2, retries: 2},
+		{name: "bad gateway", status: http.StatusBadGateway, failures: 1, retries: 1},
+		{name: "too many failures", failures: 2, retries: 1, err: true},
+		{name: "not retried", status: http.StatusInternalServerError, failures: 1, retries: 1, requests: 1, err: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if test.status == 0 {
+				test.status = http.StatusServiceUnavailable
+			}
+			if test.requests == 0 {
+				test.requests = int32(test.retries + 1)
+			}
+			atomic.StoreInt32(&status, test.status)
+			atomic.StoreInt32(&failures, test.failures)
+			atomic.StoreInt32(&requests, 0)
+			client, err := NewClient(ClientConfig{
+				URL:          flaky.URL,
+				Retries:      test.retries,
+				RetryBackoff: time.Millisecond,
+			})
+			require.NoError(t, err)
+			_, err = client.ListFamilies(ctx)
+			if test.err {
+				require.Error(t, err)
+				require.EqualValues(t, test.status, err.(*ResponseError).StatusCode)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.requests, atomic.LoadInt32(&requests))
+		})
+	}
+
+	// client errors aren't retried
+	atomic.StoreInt32(&requests, 0)
+	client, err := NewClient(ClientConfig{URL: flaky.URL, Retries: 3})
+	require.NoError(t, err)
+	_, err = client.GetRowByKey(ctx, map[string]interface{}{}, "family", "missing", "a", 1)
+	require.Error(t, err)
+	_, err = client.ListFamilies(ctx)
+	require.NoError(t, err)
+}
+
+func TestClientTimeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+
+	client, err := NewClient(ClientConfig{
+		URL:          slow.URL,
+		Timeout:      10 * time.Millisecond,
+		Retries:      1,
+		RetryBackoff: time.Millisecond,
+	})
+	require.NoError(t, err)
+	start := time.Now()
+	_, err = client.ListFamilies(context.Background())
+	require.Error(t, err)
+	require.True(t, time.Since(start) < time.Second, "request wasn't timed out")
+}
+
+func TestFallbackReader(t *testing.T) {
+	oldInterval := fallbackOpenInterval
+	defer func() { fallbackOpenInterval = oldInterval }()
+	fallbackOpenInterval = 0
+
+	ctx := context.Background()
+	srv, _, teardown := newClientTestSidecar(t)
+	defer teardown()
+	client, err := NewClient(ClientConfig{URL: srv.URL})
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "fallback_reader")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ldb.DefaultLDBFilename)
+
+	reader := NewFallbackReader(path, client)
+	defer reader.Close()
+
+	var row clientTestRow
+	found, err := reader.GetRowByKey(ctx, &row, "family", "table", "a", 1<<60)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, 1.5, row.Score)
+
+	// once there's a local LDB, it's read from instead
+	db, err := ldb.OpenLDB(path, "rwc")
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, ldb.EnsureLdbInitialized(ctx, db))
+	_, err = db.Exec(`
+		CREATE TABLE family___table (key VARCHAR, id INTEGER, data BLOB, score REAL, PRIMARY KEY (key, id));
+		INSERT INTO family___table VALUES ('a', 1152921504606846976, x'00', 9.5);
+	`)
+	require.NoError(t, err)
+
+	found, err = reader.GetRowByKey(ctx, &row, "family", "table", "a", 1<<60)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, 9.5, row.Score)
+}
+
+func TestFallbackReaderWhenStale(t *testing.T) {
+	ctx := context.Background()
+	srv, _, teardown := newClientTestSidecar(t)
+	defer teardown()
+	client, err := NewClient(ClientConfig{URL: srv.URL})
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "fallback_reader")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ldb.DefaultLDBFilename)
+	db, err := ldb.OpenLDB(path, "rwc")
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, ldb.EnsureLdbInitialized(ctx, db))
+	_, err = db.Exec(`CREATE TABLE family___table (key VARCHAR, id INTEGER, data BLOB, score REAL, PRIMARY KEY (key, id))`)
+	require.NoError(t, err)
+
+	// the local LDB has never received a ledger update, so it's stale
+	reader := NewFallbackReader(path, client, ctlstore.WithMaxLatency(time.Minute))
+	defer reader.Close()
+	var row clientTestRow
+	found, err := reader.GetRowByKey(ctx, &row, "family", "table", "a", 1<<60)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, 1.5, row.Score)
+
+	rows, err := reader.GetRowsByKeyPrefix(ctx, "family", "table", "a")
+	require.NoError(t, err)
+	maps, err := rows.ScanAllMaps()
+	require.NoError(t, err)
+	require.Len(t, maps, 2)
+}
+
+func TestUseRemote(t *testing.T) {
+	for _, test := range []struct {
+		err    error
+		remote bool
+	}{
+		{err: nil},
+		{err: ctlstore.ErrTableNotFound},
+		{err: ctlstore.ErrStale, remote: true},
+		{err: errors.Wrap(ctlstore.ErrNoLedgerUpdates, "get ledger latency"), remote: true},
+		{err: sqlite3.Error{Code: sqlite3.ErrCorrupt}, remote: true},
+		{err: errors.Wrap(sqlite3.Error{Code: sqlite3.ErrCantOpen}, "query"), remote: true},
+		{err: sqlite3.Error{Code: sqlite3.ErrConstraint}},
+	} {
+		require.Equal(t, test.remote, useRemote(test.err), "%v", test.err)
+	}
+}
+
+func TestTableDescriptionToTableSchema(t *testing.T) {
+	_, err := TableDescription{Fields: [][]string{{"key", "bogus"}}}.toTableSchema()
+	require.Error(t, err)
+	ts, err := TableDescription{
+		Family:    "family",
+		Table:     "table",
+		Fields:    [][]string{{"key", "string"}, {"n", "integer"}},
+		KeyFields: []string{"key"},
+	}.toTableSchema()
+	require.NoError(t, err)
+	require.Equal(t, []string{"key"}, ts.PrimaryKey.Strings())
+	require.Len(t, ts.Fields, 2)
+}
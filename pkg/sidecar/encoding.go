@@ -0,0 +1,360 @@
+package sidecar
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/segmentio/errors-go"
+)
+
+// The content types prefix scans may be encoded as, which clients choose
+// between with the Accept header. JSON is the default.
+const (
+	JSONContentType    = "application/json"
+	NDJSONContentType  = "application/x-ndjson"
+	MsgpackContentType = "application/msgpack"
+	CSVContentType     = "text/csv"
+)
+
+// rowFormat is a format the rows of a prefix scan can be encoded in.
+type rowFormat struct {
+	contentType string
+	// streamed formats can be written as the rows are read, which unpaged
+	// scans are if the sidecar is configured with StreamAllRows.
+	streamed   bool
+	newEncoder func(w io.Writer, cols []schema.DBColumnMeta) rowEncoder
+}
+
+// rowEncoder encodes the rows of a prefix scan, which are keyed by column
+// name the way rows.Scan fills out maps.
+type rowEncoder interface {
+	encode(row map[string]interface{}) error
+	// close finishes encoding once all of the rows have been encoded.
+	close() error
+}
+
+var (
+	jsonFormat = &rowFormat{
+		contentType: JSONContentType,
+		newEncoder: func(w io.Writer, cols []schema.DBColumnMeta) rowEncoder {
+			return &jsonRowEncoder{w: w}
+		},
+	}
+	rowFormats = map[string]*rowFormat{
+		JSONContentType: jsonFormat,
+		NDJSONContentType: {
+			contentType: NDJSONContentType,
+			streamed:    true,
+			newEncoder: func(w io.Writer, cols []schema.DBColumnMeta) rowEncoder {
+				return ndjsonRowEncoder{json.NewEncoder(w)}
+			},
+		},
+		MsgpackContentType: {
+			contentType: MsgpackContentType,
+			newEncoder: func(w io.Writer, cols []schema.DBColumnMeta) rowEncoder {
+				return &msgpackRowEncoder{w: w, cols: cols}
+			},
+		},
+		CSVContentType: {
+			contentType: CSVContentType,
+			newEncoder: func(w io.Writer, cols []schema.DBColumnMeta) rowEncoder {
+				return &csvRowEncoder{w: csv.NewWriter(w), cols: cols}
+			},
+		},
+	}
+	// rowFormatAliases are other names clients use for the formats.
+	rowFormatAliases = map[string]string{
+		"*/*":                     JSONContentType,
+		"application/*":           JSONContentType,
+		"application/ndjson":      NDJSONContentType,
+		"application/x-msgpack":   MsgpackContentType,
+		"application/vnd.msgpack": MsgpackContentType,
+		"text/*":                  CSVContentType,
+	}
+)
+
+// negotiateRowFormat returns the format preferred by an Accept header, or
+// JSON if there isn't one. A "not-acceptable" error is returned if none of
+// the accepted types are supported.
+func negotiateRowFormat(accept string) (*rowFormat, error) {
+	if strings.TrimSpace(accept) == "" {
+		return jsonFormat, nil
+	}
+	var res *rowFormat
+	bestQ := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if alias, ok := rowFormatAliases[mediaType]; ok {
+			mediaType = alias
+		}
+		format, ok := rowFormats[mediaType]
+		if !ok {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > bestQ {
+			res, bestQ = format, q
+		}
+	}
+	if res == nil {
+		err := errors.Errorf("none of the accepted types '%s' are supported", accept)
+		return nil, errors.WithTypes(err, "not-acceptable")
+	}
+	return res, nil
+}
+
+// DefaultMaxStreamDuration bounds how long streamed prefix scans take,
+// unless the sidecar is configured otherwise.
+const DefaultMaxStreamDuration = time.Minute
+
+// streams returns whether prefix scans in the format are written as their
+// rows are read, rather than buffered.
+func (s *Sidecar) streams(format *rowFormat, paged bool) bool {
+	return format.streamed && !paged && (s.streamAll || s.maxRows <= 0)
+}
+
+// streamedScan returns whether a request is for a prefix scan which is
+// streamed, so is bounded by the MaxStreamDuration rather than the write
+// timeout.
+func (s *Sidecar) streamedScan(r *http.Request) bool {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.URL.Path, "/get-rows-by-key-prefix/") {
+		return false
+	}
+	format, err := negotiateRowFormat(r.Header.Get("Accept"))
+	if err != nil {
+		return false
+	}
+	query := r.URL.Query()
+	return s.streams(format, query.Get("page_size") != "" || query.Get("page_token") != "")
+}
+
+// jsonRowEncoder encodes the rows as a single JSON array.
+type jsonRowEncoder struct {
+	w    io.Writer
+	rows int
+}
+
+func (e *jsonRowEncoder) encode(row map[string]interface{}) error {
+	b, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	sep := ","
+	if e.rows == 0 {
+		sep = "["
+	}
+	e.rows++
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	_, err = e.w.Write(b)
+	return err
+}
+
+func (e *jsonRowEncoder) close() error {
+	end := "]\n"
+	if e.rows == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+// ndjsonRowEncoder encodes each row as a JSON object on a line of its own.
+type ndjsonRowEncoder struct {
+	enc *json.Encoder
+}
+
+func (e ndjsonRowEncoder) encode(row map[string]interface{}) error {
+	return e.enc.Encode(row)
+}
+
+func (e ndjsonRowEncoder) close() error {
+	return nil
+}
+
+// csvRowEncoder encodes the rows as CSV, with a header of the column names.
+// Binary values are base64 encoded, the same as they are in JSON, and NULL
+// values are left empty.
+type csvRowEncoder struct {
+	w      *csv.Writer
+	cols   []schema.DBColumnMeta
+	record []string // nil until the header has been written
+}
+
+func (e *csvRowEncoder) writeHeader() error {
+	e.record = make([]string, len(e.cols))
+	for i, col := range e.cols {
+		e.record[i] = col.Name
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvRowEncoder) encode(row map[string]interface{}) error {
+	if e.record == nil {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+	for i, col := range e.cols {
+		switch v := row[col.Name].(type) {
+		case nil:
+			e.record[i] = ""
+		case []byte:
+			if col.IsBinary() {
+				e.record[i] = base64.StdEncoding.EncodeToString(v)
+			} else {
+				e.record[i] = string(v)
+			}
+		case string:
+			e.record[i] = v
+		case int64:
+			e.record[i] = strconv.FormatInt(v, 10)
+		case float64:
+			e.record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			e.record[i] = strconv.FormatBool(v)
+		default:
+			return errors.Errorf("column '%s' has unsupported value type %T", col.Name, v)
+		}
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvRowEncoder) close() error {
+	if e.record == nil {
+		// the header is written even if there aren't any rows
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// msgpackRowEncoder encodes the rows as a MessagePack array of maps, with
+// the fields of each map in column order. Values of binary columns are
+// encoded as bin, and those of text columns as str.
+type msgpackRowEncoder struct {
+	w    io.Writer
+	cols []schema.DBColumnMeta
+	rows []map[string]interface{}
+}
+
+func (e *msgpackRowEncoder) encode(row map[string]interface{}) error {
+	// the array's length comes first, so the rows are encoded on close
+	e.rows = append(e.rows, row)
+	return nil
+}
+
+func (e *msgpackRowEncoder) close() error {
+	b := appendMsgpackHeader(nil, 0x90, 0xdc, 0xdd, len(e.rows))
+	for _, row := range e.rows {
+		b = appendMsgpackHeader(b, 0x80, 0xde, 0xdf, len(e.cols))
+		for _, col := range e.cols {
+			b = appendMsgpackString(b, col.Name)
+			switch v := row[col.Name].(type) {
+			case nil:
+				b = append(b, 0xc0)
+			case []byte:
+				if col.IsBinary() {
+					b = appendMsgpackBinary(b, v)
+				} else {
+					b = appendMsgpackString(b, string(v))
+				}
+			case string:
+				b = appendMsgpackString(b, v)
+			case int64:
+				b = appendMsgpackInt(b, v)
+			case float64:
+				b = append(b, 0xcb)
+				b = appendUint(b, math.Float64bits(v), 8)
+			case bool:
+				if v {
+					b = append(b, 0xc3)
+				} else {
+					b = append(b, 0xc2)
+				}
+			default:
+				return errors.Errorf("column '%s' has unsupported value type %T", col.Name, v)
+			}
+		}
+	}
+	_, err := e.w.Write(b)
+	return err
+}
+
+// appendMsgpackHeader appends the header of an array or map with n
+// elements, given the fixed, 16 bit and 32 bit prefixes of its type.
+func appendMsgpackHeader(b []byte, fixed byte, prefix16 byte, prefix32 byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, fixed|byte(n))
+	case n <= math.MaxUint16:
+		return appendUint(append(b, prefix16), uint64(n), 2)
+	default:
+		return appendUint(append(b, prefix32), uint64(n), 4)
+	}
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = appendUint(append(b, 0xda), uint64(n), 2)
+	default:
+		b = appendUint(append(b, 0xdb), uint64(n), 4)
+	}
+	return append(b, s...)
+}
+
+func appendMsgpackBinary(b []byte, v []byte) []byte {
+	switch n := len(v); {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = appendUint(append(b, 0xc5), uint64(n), 2)
+	default:
+		b = appendUint(append(b, 0xc6), uint64(n), 4)
+	}
+	return append(b, v...)
+}
+
+func appendMsgpackInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v <= math.MaxInt8:
+		return append(b, byte(v))
+	case v < 0 && v >= -32:
+		return append(b, byte(v))
+	default:
+		return appendUint(append(b, 0xd3), uint64(v), 8)
+	}
+}
+
+// appendUint appends the n low bytes of v in big-endian order.
+func appendUint(b []byte, v uint64, n int) []byte {
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, byte(v>>(8*uint(i))))
+	}
+	return b
+}
@@ -0,0 +1,195 @@
+package sidecar
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/segmentio/ctlstore"
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateRowFormat(t *testing.T) {
+	for _, test := range []struct {
+		accept      string
+		contentType string // empty if not acceptable
+	}{
+		{accept: "", contentType: JSONContentType},
+		{accept: "*/*", contentType: JSONContentType},
+		{accept: "application/json", contentType: JSONContentType},
+		{accept: "application/x-ndjson", contentType: NDJSONContentType},
+		{accept: "application/ndjson", contentType: NDJSONContentType},
+		{accept: "application/msgpack", contentType: MsgpackContentType},
+		{accept: "application/x-msgpack", contentType: MsgpackContentType},
+		{accept: "text/csv; charset=utf-8", contentType: CSVContentType},
+		{accept: "text/html, text/csv", contentType: CSVContentType},
+		{accept: "application/json;q=0.5, text/csv;q=0.9", contentType: CSVContentType},
+		{accept: "text/csv;q=0, */*;q=0.1", contentType: JSONContentType},
+		{accept: "text/html"},
+		{accept: "text/csv;q=0"},
+	} {
+		t.Run(test.accept, func(t *testing.T) {
+			format, err := negotiateRowFormat(test.accept)
+			if test.contentType == "" {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.contentType, format.contentType)
+		})
+	}
+}
+
+func TestMsgpackRowEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := rowFormats[MsgpackContentType].newEncoder(&buf, []schema.DBColumnMeta{
+		{Name: "s", Type: "VARCHAR"},
+		{Name: "b", Type: "BLOB"},
+		{Name: "i", Type: "INTEGER"},
+		{Name: "n", Type: "INTEGER"},
+	})
+	require.NoError(t, enc.encode(map[string]interface{}{
+		"s": "x",
+		"b": []byte{1},
+		"i": int64(-300),
+		"n": nil,
+	}))
+	require.NoError(t, enc.close())
+	require.Equal(t, []byte{
+		0x91,                 // array of 1
+		0x84,                 // map of 4
+		0xa1, 's', 0xa1, 'x', // str
+		0xa1, 'b', 0xc4, 1, 1, // bin
+		0xa1, 'i', 0xd3, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe, 0xd4, // int 64
+		0xa1, 'n', 0xc0, // nil
+	}, buf.Bytes())
+}
+
+func TestGetRowsByKeyPrefixFormats(t *testing.T) {
+	tu, teardown := ctlstore.NewLDBTestUtil(t)
+	defer teardown()
+	tu.CreateTable(ctlstore.LDBTestTableDef{
+		Family: "test_family",
+		Name:   "test_table",
+		Fields: [][]string{
+			{"key", "string"},
+			{"num", "integer"},
+			{"text", "text"},
+			{"bin", "binary"},
+		},
+		KeyFields: []string{"key"},
+		Rows: [][]interface{}{
+			{"a", 1, "hello, world", []byte{0, 1}},
+			{"b", 2, "bye", []byte("b")},
+		},
+	})
+	body, err := json.Marshal(ReadRequest{})
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name        string
+		accept      string
+		maxRows     int
+		streamAll   bool
+		status      int
+		contentType string
+		body        string
+	}{
+		{
+			name:        "json by default",
+			status:      http.StatusOK,
+			contentType: JSONContentType,
+			body:        `[{"bin":"AAE=","key":"a","num":1,"text":"hello, world"},{"bin":"Yg==","key":"b","num":2,"text":"bye"}]` + "\n",
+		},
+		{
+			name:    "json is limited",
+			maxRows: 1,
+			status:  http.StatusRequestedRangeNotSatisfiable,
+		},
+		{
+			name:    "ndjson is limited",
+			accept:  NDJSONContentType,
+			maxRows: 1,
+			status:  http.StatusRequestedRangeNotSatisfiable,
+		},
+		{
+			name:        "ndjson is streamed",
+			accept:      NDJSONContentType,
+			maxRows:     1,
+			streamAll:   true,
+			status:      http.StatusOK,
+			contentType: NDJSONContentType,
+			body: `{"bin":"AAE=","key":"a","num":1,"text":"hello, world"}` + "\n" +
+				`{"bin":"Yg==","key":"b","num":2,"text":"bye"}` + "\n",
+		},
+		{
+			name:        "csv",
+			accept:      CSVContentType,
+			status:      http.StatusOK,
+			contentType: CSVContentType,
+			body:        "key,num,text,bin\na,1,\"hello, world\",AAE=\nb,2,bye,Yg==\n",
+		},
+		{
+			name:   "not acceptable",
+			accept: "text/html",
+			status: http.StatusNotAcceptable,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			sc, err := New(Config{Reader: ctlstore.NewLDBReaderFromDB(tu.DB), MaxRows: test.maxRows, StreamAllRows: test.streamAll})
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/get-rows-by-key-prefix/test_family/test_table", bytes.NewReader(body))
+			if test.accept != "" {
+				r.Header.Set("Accept", test.accept)
+			}
+			sc.ServeHTTP(w, r)
+			require.Equal(t, test.status, w.Code, w.Body.String())
+			if test.contentType == "" {
+				return
+			}
+			require.Equal(t, test.contentType, w.Header().Get("Content-Type"))
+			require.Equal(t, test.body, w.Body.String())
+		})
+	}
+}
+
+// failingWriter fails to write anything after its first write.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+	writes int
+}
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	w.writes++
+	if w.writes > 1 {
+		return 0, errors.New("connection reset")
+	}
+	return w.ResponseRecorder.Write(b)
+}
+
+func TestStreamedScanAborted(t *testing.T) {
+	tu, teardown := ctlstore.NewLDBTestUtil(t)
+	defer teardown()
+	tu.CreateTable(ctlstore.LDBTestTableDef{
+		Family:    "test_family",
+		Name:      "test_table",
+		Fields:    [][]string{{"key", "string"}},
+		KeyFields: []string{"key"},
+		Rows:      [][]interface{}{{"a"}, {"b"}},
+	})
+	sc, err := New(Config{Reader: ctlstore.NewLDBReaderFromDB(tu.DB), StreamAllRows: true})
+	require.NoError(t, err)
+	w := &failingWriter{ResponseRecorder: httptest.NewRecorder()}
+	r := httptest.NewRequest(http.MethodPost, "/get-rows-by-key-prefix/test_family/test_table", bytes.NewReader([]byte(`{"key":[]}`)))
+	r.Header.Set("Accept", NDJSONContentType)
+
+	// the first row was written, so the error can't be reported in the
+	// response any more
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() { sc.ServeHTTP(w, r) })
+	require.Equal(t, `{"key":"a"}`+"\n", w.Body.String())
+}
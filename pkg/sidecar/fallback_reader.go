@@ -0,0 +1,155 @@
+package sidecar
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/segmentio/ctlstore"
+	"github.com/segmentio/errors-go"
+	"github.com/segmentio/events"
+	"github.com/segmentio/go-sqlite3"
+)
+
+// fallbackOpenInterval is how often a FallbackReader tries to open the
+// local LDB while it isn't available.
+var fallbackOpenInterval = 10 * time.Second
+
+// FallbackReader reads from the local LDB when there is one, and falls back
+// to reading through a sidecar when there isn't, when it can't be read, or
+// when it's staler than the maximum latency it was opened with, see
+// ctlstore.WithMaxLatency. It keeps trying to open the local LDB, so reads
+// move over to it once it becomes available.
+type FallbackReader struct {
+	path   string
+	opts   []ctlstore.ReaderOpt
+	remote *Client
+
+	mu           sync.Mutex
+	local        *ctlstore.LDBReader
+	lastOpenedAt time.Time
+}
+
+var (
+	_ Reader       = (*FallbackReader)(nil)
+	_ SchemaReader = (*FallbackReader)(nil)
+)
+
+// NewFallbackReader returns a FallbackReader which prefers the LDB at
+// ldbPath, opened with the supplied options, over the sidecar client. Make
+// sure to Close() the reader when done with it.
+func NewFallbackReader(ldbPath string, remote *Client, opts ...ctlstore.ReaderOpt) *FallbackReader {
+	r := &FallbackReader{
+		path:   ldbPath,
+		opts:   opts,
+		remote: remote,
+	}
+	r.localReader()
+	return r
+}
+
+// localReader returns the reader of the local LDB, or nil if it isn't
+// available.
+func (r *FallbackReader) localReader() *ctlstore.LDBReader {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.local != nil || time.Since(r.lastOpenedAt) < fallbackOpenInterval {
+		return r.local
+	}
+	r.lastOpenedAt = time.Now()
+	local, err := ctlstore.ReaderForPath(r.path, r.opts...)
+	if err != nil {
+		events.Log("Reading through the sidecar, could not open LDB: %{error}v", err)
+		return nil
+	}
+	events.Log("Reading from LDB at %{path}s", r.path)
+	r.local = local
+	return local
+}
+
+// useRemote decides whether a read from the local LDB should be retried
+// through the sidecar, which it should be if the LDB is stale, hasn't
+// received any ledger updates yet, or couldn't be opened or read.
+func useRemote(err error) bool {
+	cause := errors.Cause(err)
+	if cause == ctlstore.ErrStale || cause == ctlstore.ErrNoLedgerUpdates {
+		return true
+	}
+	if sqliteErr, ok := cause.(sqlite3.Error); ok {
+		switch sqliteErr.Code {
+		case sqlite3.ErrCantOpen, sqlite3.ErrCorrupt, sqlite3.ErrNotADB, sqlite3.ErrIoErr:
+			return true
+		}
+	}
+	return false
+}
+
+func (r *FallbackReader) GetRowByKey(ctx context.Context, out interface{}, familyName string, tableName string, key ...interface{}) (found bool, err error) {
+	if local := r.localReader(); local != nil {
+		found, err = local.GetRowByKey(ctx, out, familyName, tableName, key...)
+		if !useRemote(err) {
+			return found, err
+		}
+	}
+	return r.remote.GetRowByKey(ctx, out, familyName, tableName, key...)
+}
+
+func (r *FallbackReader) GetRowsByKeyPrefix(ctx context.Context, familyName string, tableName string, key ...interface{}) (*ctlstore.Rows, error) {
+	if local := r.localReader(); local != nil {
+		rows, err := local.GetRowsByKeyPrefix(ctx, familyName, tableName, key...)
+		if !useRemote(err) {
+			return rows, err
+		}
+	}
+	return r.remote.GetRowsByKeyPrefix(ctx, familyName, tableName, key...)
+}
+
+func (r *FallbackReader) GetLedgerLatency(ctx context.Context) (time.Duration, error) {
+	if local := r.localReader(); local != nil {
+		latency, err := local.GetLedgerLatency(ctx)
+		if !useRemote(err) {
+			return latency, err
+		}
+	}
+	return r.remote.GetLedgerLatency(ctx)
+}
+
+func (r *FallbackReader) ListFamilies(ctx context.Context) ([]string, error) {
+	if local := r.localReader(); local != nil {
+		families, err := local.ListFamilies(ctx)
+		if !useRemote(err) {
+			return families, err
+		}
+	}
+	return r.remote.ListFamilies(ctx)
+}
+
+func (r *FallbackReader) ListTables(ctx context.Context, familyName string) ([]string, error) {
+	if local := r.localReader(); local != nil {
+		tables, err := local.ListTables(ctx, familyName)
+		if !useRemote(err) {
+			return tables, err
+		}
+	}
+	return r.remote.ListTables(ctx, familyName)
+}
+
+func (r *FallbackReader) DescribeTable(ctx context.Context, familyName string, tableName string) (ctlstore.TableSchema, error) {
+	if local := r.localReader(); local != nil {
+		ts, err := local.DescribeTable(ctx, familyName, tableName)
+		if !useRemote(err) {
+			return ts, err
+		}
+	}
+	return r.remote.DescribeTable(ctx, familyName, tableName)
+}
+
+// Close closes the local LDB, if it was opened.
+func (r *FallbackReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.local == nil {
+		return nil
+	}
+	return r.local.Close()
+}
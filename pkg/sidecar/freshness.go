@@ -0,0 +1,196 @@
+package sidecar
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/segmentio/events"
+	"github.com/segmentio/stats/v4"
+)
+
+const (
+	// MinSequenceHeader may be sent with a request to have the sidecar
+	// wait until its LDB has applied at least that ledger sequence before
+	// serving it, such as the last sequence of a mutation made through the
+	// executive. If the sequence isn't applied within the sidecar's
+	// MinSequenceTimeout, the request fails with 503 Service Unavailable.
+	MinSequenceHeader = "X-Ctlstore-Min-Sequence"
+	// SequenceHeader is set on responses to the ledger sequence the LDB had
+	// applied when the request was served.
+	SequenceHeader = "X-Ctlstore-Sequence"
+	// LedgerLatencyHeader is set on responses to the ledger latency of the
+	// LDB when the request was served, in seconds.
+	LedgerLatencyHeader = "X-Ctlstore-Ledger-Latency"
+)
+
+// DefaultMinSequenceTimeout is how long the sidecar waits for the sequence
+// of the MinSequenceHeader, unless it's configured otherwise.
+const DefaultMinSequenceTimeout = time.Second
+
+// WaitingReader is implemented by readers which can wait for the LDB to
+// apply a ledger sequence, such as *ctlstore.LDBReader.
+type WaitingReader interface {
+	WaitForSequence(ctx context.Context, seq schema.DMLSequence) error
+}
+
+type minSequenceKey struct{}
+
+// WithMinSequence returns a context which has a Client send the
+// MinSequenceHeader with its requests, so that they are served once the
+// sidecar's LDB has applied at least the sequence.
+func WithMinSequence(ctx context.Context, seq schema.DMLSequence) context.Context {
+	return context.WithValue(ctx, minSequenceKey{}, seq)
+}
+
+// unreadPaths are the endpoints which don't read the LDB's data, so whose
+// responses don't have the sequence and ledger latency headers.
+var unreadPaths = map[string]bool{
+	"/ping":                true,
+	"/healthcheck":         true,
+	"/get-ledger-latency":  true,
+	"/get-ledger-sequence": true,
+}
+
+// freshness is the state of the LDB which responses report.
+type freshness struct {
+	seq        schema.DMLSequence
+	hasSeq     bool
+	lastUpdate time.Time // of the ledger, zero if unknown
+}
+
+// freshnessCache caches the freshness of the LDB, which is refreshed each
+// time the LDB applies a new sequence rather than read for each request.
+type freshnessCache struct {
+	once  sync.Once
+	mu    sync.RWMutex
+	value freshness
+}
+
+func (c *freshnessCache) load() freshness {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.value
+}
+
+func (c *freshnessCache) store(f freshness) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.value = f
+}
+
+// withFreshness waits for the sequence of the MinSequenceHeader, if sent,
+// and sets the sequence and ledger latency headers on the response before
+// serving the request with the supplied handler.
+func (s *Sidecar) withFreshness(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var minSeq schema.DMLSequence
+		if v := r.Header.Get(MinSequenceHeader); v != "" {
+			seq, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || seq < 0 {
+				http.Error(w, fmt.Sprintf("invalid %s '%s'", MinSequenceHeader, v), http.StatusBadRequest)
+				return
+			}
+			reader, ok := s.reader.(WaitingReader)
+			if !ok {
+				http.Error(w, "this sidecar cannot wait for sequences", http.StatusNotImplemented)
+				return
+			}
+			waitCtx, cancel := context.WithTimeout(ctx, s.minSeqTimeout)
+			err = reader.WaitForSequence(waitCtx, schema.DMLSequence(seq))
+			cancel()
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				stats.Incr("min-sequence-timeouts")
+				http.Error(w, fmt.Sprintf("sequence %d was not applied in time", seq), http.StatusServiceUnavailable)
+				return
+			}
+			minSeq = schema.DMLSequence(seq)
+		}
+		if !unreadPaths[r.URL.Path] {
+			f := s.cachedFreshness(ctx)
+			if f.seq < minSeq {
+				// the cache hasn't caught up with the sequence waited for
+				f = s.readFreshness(ctx)
+			}
+			if f.hasSeq {
+				w.Header().Set(SequenceHeader, strconv.FormatInt(f.seq.Int(), 10))
+			}
+			if !f.lastUpdate.IsZero() {
+				latency := time.Since(f.lastUpdate)
+				w.Header().Set(LedgerLatencyHeader, strconv.FormatFloat(latency.Seconds(), 'f', -1, 64))
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// readFreshness reads the freshness of the LDB.
+func (s *Sidecar) readFreshness(ctx context.Context) freshness {
+	var f freshness
+	if reader, ok := s.reader.(SequenceReader); ok {
+		if seq, err := reader.GetLastSequence(ctx); err == nil {
+			f.seq, f.hasSeq = seq, true
+		}
+	}
+	if latency, err := s.reader.GetLedgerLatency(ctx); err == nil {
+		f.lastUpdate = time.Now().Add(-latency)
+	}
+	return f
+}
+
+// cachedFreshness returns the freshness of the LDB from the sidecar's
+// cache, which starts being refreshed on the first call. It's only cached
+// for readers which can wait for sequences, and is read for each request
+// otherwise.
+func (s *Sidecar) cachedFreshness(ctx context.Context) freshness {
+	waiter, ok := s.reader.(WaitingReader)
+	if _, tracked := s.reader.(SequenceReader); !ok || !tracked {
+		return s.readFreshness(ctx)
+	}
+	s.freshness.once.Do(func() {
+		s.freshness.store(s.readFreshness(ctx))
+		go s.refreshFreshness(waiter)
+	})
+	return s.freshness.load()
+}
+
+// refreshFreshness refreshes the cached freshness each time the LDB applies
+// a new sequence, until the sidecar shuts down.
+func (s *Sidecar) refreshFreshness(reader WaitingReader) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	for {
+		err := reader.WaitForSequence(ctx, s.freshness.load().seq+1)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			events.Log("Could not wait for the next sequence: %{error}+v", err)
+			select {
+			case <-time.After(freshnessRetryInterval):
+			case <-ctx.Done():
+				return
+			}
+		}
+		s.freshness.store(s.readFreshness(ctx))
+	}
+}
+
+// freshnessRetryInterval is how long the sidecar waits to refresh the
+// cached freshness after failing to wait for the next sequence.
+var freshnessRetryInterval = time.Second
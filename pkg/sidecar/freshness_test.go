@@ -0,0 +1,150 @@
+package sidecar
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/segmentio/ctlstore"
+	"github.com/segmentio/ctlstore/pkg/ldbwriter"
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreshness(t *testing.T) {
+	ctx := context.Background()
+	tu, teardown := ctlstore.NewLDBTestUtil(t)
+	defer teardown()
+	writer := ldbwriter.SqlLdbWriter{Db: tu.DB}
+	apply := func(seq int64, statement string) error {
+		return writer.ApplyDMLStatement(ctx, schema.DMLStatement{
+			Sequence:  schema.DMLSequence(seq),
+			Timestamp: time.Now(),
+			Statement: statement,
+		})
+	}
+	require.NoError(t, apply(1, "CREATE TABLE family___table (key VARCHAR PRIMARY KEY)"))
+
+	for _, test := range []struct {
+		name        string
+		reader      Reader
+		minSequence string
+		applied     int64 // applied while the request waits
+		status      int
+		sequence    string
+	}{
+		{name: "no minimum", status: http.StatusOK, sequence: "1"},
+		{name: "already applied", minSequence: "1", status: http.StatusOK, sequence: "1"},
+		{name: "applied while waiting", minSequence: "2", applied: 2, status: http.StatusOK, sequence: "2"},
+		{name: "never applied", minSequence: "10", status: http.StatusServiceUnavailable},
+		{name: "invalid", minSequence: "x", status: http.StatusBadRequest},
+		{name: "unsupported", reader: prefixOnlyReader{ctlstore.NewLDBReaderFromDB(tu.DB)}, minSequence: "1", status: http.StatusNotImplemented},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			reader := test.reader
+			if reader == nil {
+				reader = ctlstore.NewLDBReaderFromDB(tu.DB)
+			}
+			sc, err := New(Config{Reader: reader, MinSequenceTimeout: 500 * time.Millisecond})
+			require.NoError(t, err)
+			defer close(sc.closing)
+			applied := make(chan error, 1)
+			if test.applied > 0 {
+				go func() {
+					time.Sleep(50 * time.Millisecond)
+					applied <- apply(test.applied, "INSERT INTO family___table VALUES ('"+strconv.FormatInt(test.applied, 10)+"')")
+				}()
+				defer func() { require.NoError(t, <-applied) }()
+			}
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/get-rows-by-key-prefix/family/table", strings.NewReader(`{"key":[]}`))
+			if test.minSequence != "" {
+				r.Header.Set(MinSequenceHeader, test.minSequence)
+			}
+			sc.ServeHTTP(w, r)
+			require.Equal(t, test.status, w.Code, w.Body.String())
+			if test.status != http.StatusOK {
+				return
+			}
+			require.Equal(t, test.sequence, w.Header().Get(SequenceHeader))
+			latency, err := strconv.ParseFloat(w.Header().Get(LedgerLatencyHeader), 64)
+			require.NoError(t, err)
+			require.True(t, latency >= 0 && latency < 5, "weird latency: %v", latency)
+		})
+	}
+
+	// clients send the minimum sequence of their context
+	sc, err := New(Config{Reader: ctlstore.NewLDBReaderFromDB(tu.DB), MinSequenceTimeout: 10 * time.Millisecond})
+	require.NoError(t, err)
+	srv := httptest.NewServer(sc)
+	defer srv.Close()
+	client, err := NewClient(ClientConfig{URL: srv.URL})
+	require.NoError(t, err)
+	_, err = client.ListFamilies(WithMinSequence(ctx, 2))
+	require.NoError(t, err)
+	_, err = client.ListFamilies(WithMinSequence(ctx, 10))
+	require.Error(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, err.(*ResponseError).StatusCode)
+}
+
+// sequenceCountingReader counts the sequences read through it, other than
+// those read while waiting for sequences.
+type sequenceCountingReader struct {
+	*ctlstore.LDBReader
+	sequenceReads int32
+}
+
+func (r *sequenceCountingReader) GetLastSequence(ctx context.Context) (schema.DMLSequence, error) {
+	atomic.AddInt32(&r.sequenceReads, 1)
+	return r.LDBReader.GetLastSequence(ctx)
+}
+
+func TestFreshnessCached(t *testing.T) {
+	ctx := context.Background()
+	tu, teardown := ctlstore.NewLDBTestUtil(t)
+	defer teardown()
+	writer := ldbwriter.SqlLdbWriter{Db: tu.DB}
+	apply := func(seq int64, statement string) {
+		require.NoError(t, writer.ApplyDMLStatement(ctx, schema.DMLStatement{
+			Sequence:  schema.DMLSequence(seq),
+			Timestamp: time.Now(),
+			Statement: statement,
+		}))
+	}
+	apply(1, "CREATE TABLE family___table (key VARCHAR PRIMARY KEY)")
+
+	reader := &sequenceCountingReader{LDBReader: ctlstore.NewLDBReaderFromDB(tu.DB)}
+	sc, err := New(Config{Reader: reader})
+	require.NoError(t, err)
+	defer close(sc.closing)
+	sequence := func(path string) string {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"key":[]}`))
+		if path == "/ping" {
+			r.Method = http.MethodGet
+		}
+		sc.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return w.Header().Get(SequenceHeader)
+	}
+
+	for i := 0; i < 3; i++ {
+		require.Equal(t, "1", sequence("/get-rows-by-key-prefix/family/table"))
+	}
+	require.EqualValues(t, 1, atomic.LoadInt32(&reader.sequenceReads))
+
+	// the cache is refreshed once the LDB applies the next sequence
+	apply(2, "INSERT INTO family___table VALUES ('a')")
+	require.Eventually(t, func() bool {
+		return sequence("/get-rows-by-key-prefix/family/table") == "2"
+	}, 5*time.Second, 10*time.Millisecond)
+	require.EqualValues(t, 2, atomic.LoadInt32(&reader.sequenceReads))
+
+	// endpoints which don't read data don't report it
+	require.Equal(t, "", sequence("/ping"))
+}
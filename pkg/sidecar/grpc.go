@@ -0,0 +1,267 @@
+package sidecar
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/segmentio/ctlstore"
+	"github.com/segmentio/ctlstore/pkg/sidecar/sidecarpb"
+	"github.com/segmentio/errors-go"
+	"github.com/segmentio/stats/v4"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// grpcService implements the sidecar's gRPC service on top of its reader.
+type grpcService struct {
+	sidecar *Sidecar
+}
+
+var _ sidecarpb.SidecarServer = (*grpcService)(nil)
+
+// newGRPCServer creates the gRPC server of the sidecar.
+func (s *Sidecar) newGRPCServer() *grpc.Server {
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			countGRPCRequest(ctx, info.FullMethod)
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			countGRPCRequest(ss.Context(), info.FullMethod)
+			return handler(srv, ss)
+		}),
+	}
+	if s.grpcBindAddr != s.bindAddr {
+		switch {
+		case s.tlsConfig != nil:
+			opts = append(opts, grpc.Creds(credentials.NewTLS(s.tlsConfig)))
+		case strings.HasPrefix(s.grpcBindAddr, unixScheme):
+			opts = append(opts, grpc.Creds(peerUIDCredentials{}))
+		}
+	}
+	srv := grpc.NewServer(opts...)
+	sidecarpb.RegisterSidecarServer(srv, &grpcService{sidecar: s})
+	return srv
+}
+
+func countGRPCRequest(ctx context.Context, method string) {
+	stats.Incr("requests-by-user-agent", stats.T("user-agent", orUnknown(grpcMetadata(ctx, "user-agent"))))
+	stats.Incr("grpc-requests", stats.T("method", method[strings.LastIndex(method, "/")+1:]))
+}
+
+func grpcMetadata(ctx context.Context, key string) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(key)) > 0 {
+		return md.Get(key)[0]
+	}
+	return ""
+}
+
+// withGRPC serves gRPC requests from the sidecar's gRPC server, and
+// everything else from the supplied handler. gRPC needs HTTP/2, which
+// clients connecting in plaintext use without negotiating it first, so
+// that's accepted here too.
+//
+// Plaintext HTTP/2 connections are hijacked from the server, which clears
+// their deadlines. Over TLS, HTTP/2 is served by net/http itself, which
+// applies the server's write timeout to each stream, so that's cleared for
+// gRPC requests. Either way, gRPC calls are bound by their own contexts
+// rather than by the server's timeouts.
+//
+// This goes through grpc.Server.ServeHTTP, which grpc-go considers
+// experimental, and which is slower than serving gRPC from a listener of
+// its own, so a separate GRPCBindAddr is recommended.
+func (s *Sidecar) withGRPC(h http.Handler) http.Handler {
+	h2s := &http2.Server{}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// plaintext HTTP/2 connections are taken over from the server, and
+		// their requests don't have its connection context, so the peer's
+		// credentials are carried over from the request which started them
+		conn := r.Context()
+		h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(copyPeerUID(r.Context(), conn))
+			if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+				setWriteDeadline(w, r, time.Time{})
+				s.grpcServer.ServeHTTP(w, r)
+				return
+			}
+			h.ServeHTTP(w, r)
+		}), h2s).ServeHTTP(w, r)
+	})
+}
+
+// authorize is the gRPC equivalent of Sidecar.authorize.
+func (g *grpcService) authorize(ctx context.Context, family string, table string) error {
+	if g.sidecar.acl == nil {
+		return nil
+	}
+	var state *tls.ConnectionState
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state = &info.State
+		}
+		ctx = withPeerUIDInfo(ctx, p.AuthInfo)
+	}
+	identities := g.sidecar.acl.identities(ctx, grpcMetadata(ctx, "authorization"), state)
+	err := g.sidecar.authorize(identities, grpcMetadata(ctx, "user-agent"), family, table)
+	if err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return nil
+}
+
+func (g *grpcService) GetRowByKey(ctx context.Context, req *sidecarpb.GetRowByKeyRequest) (*sidecarpb.GetRowByKeyResponse, error) {
+	if err := g.authorize(ctx, req.Family, req.Table); err != nil {
+		return nil, err
+	}
+	key, err := keysFromProto(req.Key)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]interface{})
+	found, err := g.sidecar.reader.GetRowByKey(ctx, out, req.Family, req.Table, key...)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	res := &sidecarpb.GetRowByKeyResponse{Found: found}
+	if found {
+		if res.Row, err = rowToProto(out); err != nil {
+			return nil, grpcError(err)
+		}
+	}
+	return res, nil
+}
+
+// GetRowsByKeyPrefix streams rows as they are read. It's bounded the same
+// way as the HTTP endpoint: scans take at most the sidecar's max stream
+// duration, and unless it streams all rows, scans of more rows than its max
+// row count fail with OutOfRange once the rows up to it have been sent.
+func (g *grpcService) GetRowsByKeyPrefix(req *sidecarpb.GetRowsByKeyPrefixRequest, stream sidecarpb.Sidecar_GetRowsByKeyPrefixServer) error {
+	ctx := stream.Context()
+	if err := g.authorize(ctx, req.Family, req.Table); err != nil {
+		return err
+	}
+	key, err := keysFromProto(req.Key)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, g.sidecar.maxStream)
+	defer cancel()
+	var maxRows int64
+	if !g.sidecar.streamAll {
+		maxRows = int64(g.sidecar.maxRows)
+	}
+	rows, err := g.sidecar.reader.GetRowsByKeyPrefix(ctx, req.Family, req.Table, key...)
+	if err != nil {
+		return grpcError(err)
+	}
+	defer rows.Close()
+	var sent int64
+	for (req.Limit <= 0 || sent < req.Limit) && rows.Next() {
+		if maxRows > 0 && sent == maxRows {
+			err := errors.Errorf("max row count (%d) exceeded", maxRows)
+			return grpcError(errors.WithTypes(err, "limit-exceeded"))
+		}
+		out := make(map[string]interface{})
+		if err := rows.Scan(out); err != nil {
+			return grpcError(errors.Wrap(err, "scan"))
+		}
+		row, err := rowToProto(out)
+		if err != nil {
+			return grpcError(err)
+		}
+		if err := stream.Send(row); err != nil {
+			return err
+		}
+		sent++
+	}
+	if err := rows.Err(); err != nil {
+		return grpcError(err)
+	}
+	stats.Observe("get-rows-by-key-prefix-num-rows", sent, stats.T("family", req.Family), stats.T("table", req.Table))
+	return nil
+}
+
+func (g *grpcService) GetLedgerLatency(ctx context.Context, req *sidecarpb.GetLedgerLatencyRequest) (*sidecarpb.GetLedgerLatencyResponse, error) {
+	latency, err := g.sidecar.reader.GetLedgerLatency(ctx)
+	if err != nil {
+		return nil, grpcError(errors.Wrap(err, "get ledger latency"))
+	}
+	return &sidecarpb.GetLedgerLatencyResponse{Seconds: latency.Seconds()}, nil
+}
+
+func (g *grpcService) Healthcheck(ctx context.Context, req *sidecarpb.HealthcheckRequest) (*sidecarpb.HealthcheckResponse, error) {
+	if _, err := g.sidecar.reader.GetLedgerLatency(ctx); err != nil {
+		return nil, grpcError(errors.Wrap(err, "healthcheck"))
+	}
+	return &sidecarpb.HealthcheckResponse{}, nil
+}
+
+// grpcError converts an error returned by the reader to a gRPC status.
+func grpcError(err error) error {
+	code := codes.Internal
+	switch errors.Cause(err) {
+	case ctlstore.ErrTableNotFound:
+		code = codes.NotFound
+	case ctlstore.ErrNeedFullKey, ctlstore.ErrTableHasNoPrimaryKey:
+		code = codes.InvalidArgument
+	case ctlstore.ErrNoLedgerUpdates, ctlstore.ErrStale:
+		code = codes.Unavailable
+	case context.DeadlineExceeded:
+		code = codes.DeadlineExceeded
+	default:
+		if errors.Is("limit-exceeded", err) {
+			code = codes.OutOfRange
+		}
+	}
+	return status.Error(code, err.Error())
+}
+
+func keysFromProto(keys []*sidecarpb.Key) ([]interface{}, error) {
+	res := make([]interface{}, 0, len(keys))
+	for i, k := range keys {
+		switch v := k.GetValue().(type) {
+		case *sidecarpb.Key_String_:
+			res = append(res, v.String_)
+		case *sidecarpb.Key_Int:
+			res = append(res, v.Int)
+		case *sidecarpb.Key_Binary:
+			res = append(res, v.Binary)
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "key segment %d has no value", i)
+		}
+	}
+	return res, nil
+}
+
+func rowToProto(row map[string]interface{}) (*sidecarpb.Row, error) {
+	res := &sidecarpb.Row{Fields: make(map[string]*sidecarpb.Value, len(row))}
+	for name, value := range row {
+		v := &sidecarpb.Value{}
+		switch value := value.(type) {
+		case nil:
+		case string:
+			v.Value = &sidecarpb.Value_String_{String_: value}
+		case int64:
+			v.Value = &sidecarpb.Value_Int{Int: value}
+		case float64:
+			v.Value = &sidecarpb.Value_Float{Float: value}
+		case []byte:
+			v.Value = &sidecarpb.Value_Binary{Binary: value}
+		case bool:
+			v.Value = &sidecarpb.Value_Bool{Bool: value}
+		default:
+			return nil, errors.Errorf("unsupported type %T of column '%s'", value, name)
+		}
+		res.Fields[name] = v
+	}
+	return res, nil
+}
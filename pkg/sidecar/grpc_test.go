@@ -0,0 +1,270 @@
+package sidecar
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/ctlstore"
+	"github.com/segmentio/ctlstore/pkg/sidecar/sidecarpb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPC(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		sameAddr bool
+	}{
+		{name: "separate address", sameAddr: false},
+		{name: "same address", sameAddr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			tu, teardown := ctlstore.NewLDBTestUtil(t)
+			defer teardown()
+			tu.CreateTable(ctlstore.LDBTestTableDef{
+				Family: "family",
+				Name:   "table",
+				Fields: [][]string{
+					{"key", "bytestring"},
+					{"id", "integer"},
+					{"name", "string"},
+					{"score", "decimal"},
+				},
+				KeyFields: []string{"key", "id"},
+				Rows: [][]interface{}{
+					{[]byte{0, 1}, 1, "one", 1.5},
+					{[]byte{0, 1}, 2, nil, 2.5},
+					{[]byte{0, 2}, 1, "three", 3.5},
+				},
+			})
+
+			config := Config{
+				Reader:       ctlstore.NewLDBReaderFromDB(tu.DB),
+				BindAddr:     "127.0.0.1:0",
+				GRPCBindAddr: "127.0.0.1:0",
+				MaxRows:      1,
+			}
+			if !test.sameAddr {
+				config.GRPCBindAddr = "127.0.0.1:1"
+			}
+			sc, err := New(config)
+			require.NoError(t, err)
+			srv := httptest.NewUnstartedServer(sc)
+			srv.Config.ReadTimeout = 200 * time.Millisecond
+			srv.Config.WriteTimeout = 200 * time.Millisecond
+			srv.Config.ConnContext = connContext
+			srv.Start()
+			defer srv.Close()
+			addr := strings.TrimPrefix(srv.URL, "http://")
+			if !test.sameAddr {
+				lis, err := net.Listen("tcp", "127.0.0.1:0")
+				require.NoError(t, err)
+				go sc.grpcServer.Serve(lis)
+				defer sc.grpcServer.Stop()
+				addr = lis.Addr().String()
+			}
+
+			conn, err := grpc.DialContext(ctx, addr, grpc.WithInsecure(), grpc.WithBlock())
+			require.NoError(t, err)
+			defer conn.Close()
+			client := sidecarpb.NewSidecarClient(conn)
+
+			binaryKey := func(b ...byte) *sidecarpb.Key {
+				return &sidecarpb.Key{Value: &sidecarpb.Key_Binary{Binary: b}}
+			}
+			intKey := func(i int64) *sidecarpb.Key {
+				return &sidecarpb.Key{Value: &sidecarpb.Key_Int{Int: i}}
+			}
+
+			res, err := client.GetRowByKey(ctx, &sidecarpb.GetRowByKeyRequest{
+				Family: "family",
+				Table:  "table",
+				Key:    []*sidecarpb.Key{binaryKey(0, 1), intKey(1)},
+			})
+			require.NoError(t, err)
+			require.True(t, res.Found)
+			fields := res.Row.Fields
+			require.Equal(t, []byte{0, 1}, fields["key"].GetBinary())
+			require.EqualValues(t, 1, fields["id"].GetInt())
+			require.Equal(t, "one", fields["name"].GetString_())
+			require.Equal(t, 1.5, fields["score"].GetFloat())
+
+			res, err = client.GetRowByKey(ctx, &sidecarpb.GetRowByKeyRequest{
+				Family: "family",
+				Table:  "table",
+				Key:    []*sidecarpb.Key{binaryKey(0, 1), intKey(3)},
+			})
+			require.NoError(t, err)
+			require.False(t, res.Found)
+
+			_, err = client.GetRowByKey(ctx, &sidecarpb.GetRowByKeyRequest{
+				Family: "family",
+				Table:  "missing",
+				Key:    []*sidecarpb.Key{binaryKey(0, 1), intKey(1)},
+			})
+			require.Equal(t, codes.NotFound, status.Code(err))
+
+			_, err = client.GetRowByKey(ctx, &sidecarpb.GetRowByKeyRequest{
+				Family: "family",
+				Table:  "table",
+				Key:    []*sidecarpb.Key{{}},
+			})
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+			// prefix scans are bound by the row limit unless all rows are
+			// streamed, and by the max stream duration
+			for _, scan := range []struct {
+				name      string
+				limit     int64
+				streamAll bool
+				maxStream time.Duration
+				ids       []int64
+				code      codes.Code
+			}{
+				{name: "row limit exceeded", limit: 0, ids: []int64{1}, code: codes.OutOfRange},
+				{name: "within row limit", limit: 1, ids: []int64{1}},
+				{name: "all rows streamed", limit: 0, streamAll: true, ids: []int64{1, 2}},
+				{name: "stream duration exceeded", limit: 0, streamAll: true, maxStream: time.Nanosecond, code: codes.DeadlineExceeded},
+			} {
+				sc.streamAll = scan.streamAll
+				sc.maxStream = DefaultMaxStreamDuration
+				if scan.maxStream > 0 {
+					sc.maxStream = scan.maxStream
+				}
+				stream, err := client.GetRowsByKeyPrefix(ctx, &sidecarpb.GetRowsByKeyPrefixRequest{
+					Family: "family",
+					Table:  "table",
+					Key:    []*sidecarpb.Key{binaryKey(0, 1)},
+					Limit:  scan.limit,
+				})
+				require.NoError(t, err)
+				var ids []int64
+				var code codes.Code
+				for {
+					row, err := stream.Recv()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						code = status.Code(err)
+						break
+					}
+					ids = append(ids, row.Fields["id"].GetInt())
+					if row.Fields["id"].GetInt() == 2 {
+						require.Nil(t, row.Fields["name"].GetValue())
+					}
+				}
+				require.Equal(t, scan.code, code, scan.name)
+				require.Equal(t, scan.ids, ids, scan.name)
+			}
+			sc.streamAll = false
+			sc.maxStream = DefaultMaxStreamDuration
+
+			latency, err := client.GetLedgerLatency(ctx, &sidecarpb.GetLedgerLatencyRequest{})
+			require.NoError(t, err)
+			require.True(t, latency.Seconds >= 0 && latency.Seconds < 5, "weird latency: %v", latency.Seconds)
+			_, err = client.Healthcheck(ctx, &sidecarpb.HealthcheckRequest{})
+			require.NoError(t, err)
+
+			// the connection outlasts the server's timeouts
+			time.Sleep(500 * time.Millisecond)
+			require.Equal(t, connectivity.Ready, conn.GetState())
+
+			// HTTP requests are still served
+			resp, err := http.Get(srv.URL + "/list-families")
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+		})
+	}
+}
+
+func TestGRPCHealthcheckWithoutLedgerUpdates(t *testing.T) {
+	ctx := context.Background()
+	tu, teardown := ctlstore.NewLDBTestUtil(t)
+	defer teardown()
+	_, err := tu.DB.Exec("DELETE FROM _ldb_last_update")
+	require.NoError(t, err)
+
+	g := &grpcService{sidecar: &Sidecar{reader: ctlstore.NewLDBReaderFromDB(tu.DB)}}
+	_, err = g.Healthcheck(ctx, &sidecarpb.HealthcheckRequest{})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestGRPCSameAddressOverTLS(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	tu, teardown := ctlstore.NewLDBTestUtil(t)
+	defer teardown()
+	// enough data that the stream is held up by flow control while the
+	// client isn't reading it
+	rows := make([][]interface{}, 200)
+	for i := range rows {
+		rows[i] = []interface{}{i, strings.Repeat("x", 10000)}
+	}
+	tu.CreateTable(ctlstore.LDBTestTableDef{
+		Family:    "family",
+		Name:      "table",
+		Fields:    [][]string{{"id", "integer"}, {"data", "string"}},
+		KeyFields: []string{"id"},
+		Rows:      rows,
+	})
+
+	sc, err := New(Config{
+		Reader:        ctlstore.NewLDBReaderFromDB(tu.DB),
+		BindAddr:      "127.0.0.1:0",
+		GRPCBindAddr:  "127.0.0.1:0",
+		StreamAllRows: true,
+	})
+	require.NoError(t, err)
+	srv := httptest.NewUnstartedServer(sc)
+	srv.Config.ReadTimeout = 200 * time.Millisecond
+	srv.Config.WriteTimeout = 200 * time.Millisecond
+	srv.Config.ConnContext = connContext
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	conn, err := grpc.DialContext(ctx, strings.TrimPrefix(srv.URL, "https://"),
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: pool})), grpc.WithBlock())
+	require.NoError(t, err)
+	defer conn.Close()
+	client := sidecarpb.NewSidecarClient(conn)
+
+	stream, err := client.GetRowsByKeyPrefix(ctx, &sidecarpb.GetRowsByKeyPrefixRequest{
+		Family: "family",
+		Table:  "table",
+	})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	// the stream outlasts the server's write timeout
+	time.Sleep(500 * time.Millisecond)
+	n := 1
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		n++
+	}
+	require.Equal(t, len(rows), n)
+}
@@ -0,0 +1,96 @@
+package sidecar
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/segmentio/errors-go"
+	"google.golang.org/grpc/credentials"
+)
+
+const unixScheme = "unix://"
+
+// DefaultSocketMode is the file mode of the sidecar's Unix sockets, unless
+// it's configured otherwise.
+const DefaultSocketMode os.FileMode = 0660
+
+// listen listens on a TCP address, or on a Unix socket if the address is a
+// unix:// path. A socket left behind by a sidecar that's no longer running
+// is replaced, and the socket is removed again when the listener is closed.
+func listen(addr string, mode os.FileMode) (net.Listener, error) {
+	if !strings.HasPrefix(addr, unixScheme) {
+		lis, err := net.Listen("tcp", addr)
+		return lis, errors.Wrap(err, "listen")
+	}
+	path := strings.TrimPrefix(addr, unixScheme)
+	if fi, err := os.Stat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, errors.Errorf("'%s' exists and is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, errors.Errorf("socket '%s' is in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, errors.Wrap(err, "remove stale socket")
+		}
+	}
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, errors.Wrap(err, "listen")
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		lis.Close()
+		return nil, errors.Wrap(err, "chmod socket")
+	}
+	return lis, nil
+}
+
+// peerUIDCredentials are the transport credentials of the gRPC server when
+// it listens on a Unix socket, which record the user ID of each client for
+// the ACL, the same way the HTTP server does.
+type peerUIDCredentials struct{}
+
+// peerUIDInfo is the auth info of a client connected over a Unix socket.
+type peerUIDInfo struct {
+	uid uint32
+}
+
+func (peerUIDInfo) AuthType() string {
+	return "peer-uid"
+}
+
+func (c peerUIDCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.New("peer-uid credentials are only used by servers")
+}
+
+func (c peerUIDCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	uid, ok := peerUID(conn)
+	if !ok {
+		return conn, nil, nil
+	}
+	return conn, peerUIDInfo{uid}, nil
+}
+
+func (c peerUIDCredentials) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "peer-uid"}
+}
+
+func (c peerUIDCredentials) Clone() credentials.TransportCredentials {
+	return c
+}
+
+func (c peerUIDCredentials) OverrideServerName(string) error {
+	return nil
+}
+
+// withPeerUIDInfo records the user ID of a gRPC client connected over a Unix
+// socket in the context, the same way withPeerUID does.
+func withPeerUIDInfo(ctx context.Context, info credentials.AuthInfo) context.Context {
+	if info, ok := info.(peerUIDInfo); ok {
+		ctx = context.WithValue(ctx, peerUIDKey{}, info.uid)
+	}
+	return ctx
+}
@@ -0,0 +1,171 @@
+package sidecar
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/segmentio/ctlstore"
+	"github.com/segmentio/ctlstore/pkg/sidecar/sidecarpb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestListen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sidecar_listen")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "sidecar.sock")
+	lis, err := listen(unixScheme+path, 0600)
+	require.NoError(t, err)
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	// a socket in use isn't replaced
+	_, err = listen(unixScheme+path, 0600)
+	require.EqualError(t, err, "socket '"+path+"' is in use")
+
+	// but one left behind is
+	lis.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, lis.Close())
+	lis, err = listen(unixScheme+path, 0600)
+	require.NoError(t, err)
+	require.NoError(t, lis.Close())
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err), "socket wasn't removed")
+
+	// other files aren't
+	require.NoError(t, ioutil.WriteFile(path, nil, 0644))
+	_, err = listen(unixScheme+path, 0600)
+	require.EqualError(t, err, "'"+path+"' exists and is not a socket")
+
+	lis, err = listen("127.0.0.1:0", 0600)
+	require.NoError(t, err)
+	require.Equal(t, "tcp", lis.Addr().Network())
+	require.NoError(t, lis.Close())
+}
+
+func TestStartOnUnixSocket(t *testing.T) {
+	tu, teardown := ctlstore.NewLDBTestUtil(t)
+	defer teardown()
+	tu.CreateTable(ctlstore.LDBTestTableDef{
+		Family:    "family",
+		Name:      "table",
+		Fields:    [][]string{{"key", "string"}},
+		KeyFields: []string{"key"},
+		Rows:      [][]interface{}{{"a"}},
+	})
+	dir, err := ioutil.TempDir("", "sidecar_start")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sidecar.sock")
+	grpcPath := filepath.Join(dir, "grpc.sock")
+
+	sc, err := New(Config{
+		BindAddr:     unixScheme + path,
+		GRPCBindAddr: unixScheme + grpcPath,
+		Reader:       ctlstore.NewLDBReaderFromDB(tu.DB),
+		ACL: &ACL{Grants: map[string][]string{
+			"uid:" + strconv.Itoa(os.Getuid()): {"family"},
+		}},
+	})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error)
+	go func() {
+		stopped <- sc.Start(ctx)
+	}()
+
+	client, err := NewClient(ClientConfig{URL: unixScheme + path, Retries: 10, RetryBackoff: 10 * time.Millisecond})
+	require.NoError(t, err)
+	found, err := client.GetRowByKey(ctx, map[string]interface{}{}, "family", "table", "a")
+	require.NoError(t, err)
+	require.True(t, found)
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, DefaultSocketMode, fi.Mode().Perm())
+
+	conn, err := grpc.DialContext(ctx, unixScheme+grpcPath, grpc.WithInsecure(), grpc.WithBlock(),
+		grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout("unix", grpcPath, timeout)
+		}))
+	require.NoError(t, err)
+	defer conn.Close()
+	res, err := sidecarpb.NewSidecarClient(conn).GetRowByKey(ctx, &sidecarpb.GetRowByKeyRequest{
+		Family: "family",
+		Table:  "table",
+		Key:    []*sidecarpb.Key{{Value: &sidecarpb.Key_String_{String_: "a"}}},
+	})
+	if peerUIDSupported(t) {
+		require.NoError(t, err)
+		require.True(t, res.Found)
+	} else {
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+	}
+
+	cancel()
+	select {
+	case err := <-stopped:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("sidecar didn't shut down")
+	}
+	for _, p := range []string{path, grpcPath} {
+		_, err = os.Stat(p)
+		require.True(t, os.IsNotExist(err), "%s wasn't removed", p)
+	}
+}
+
+func TestStartShutsDownGracefully(t *testing.T) {
+	tu, teardown := ctlstore.NewLDBTestUtil(t)
+	defer teardown()
+	f, err := ioutil.TempFile("", "changelog")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.Close()
+
+	sc, err := New(Config{
+		BindAddr:      "127.0.0.1:0",
+		Reader:        ctlstore.NewLDBReaderFromDB(tu.DB),
+		ChangelogPath: f.Name(),
+	})
+	require.NoError(t, err)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	sc.bindAddr = lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error)
+	go func() {
+		stopped <- sc.Start(ctx)
+	}()
+
+	// an open change stream doesn't hold up the shutdown
+	client, err := NewClient(ClientConfig{URL: "http://" + sc.bindAddr, Retries: 10, RetryBackoff: 10 * time.Millisecond})
+	require.NoError(t, err)
+	_, err = client.ListFamilies(ctx)
+	require.NoError(t, err)
+	res, err := client.httpClient.Get("http://" + sc.bindAddr + "/changes")
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	cancel()
+	select {
+	case err := <-stopped:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("sidecar didn't shut down")
+	}
+}
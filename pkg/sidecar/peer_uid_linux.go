@@ -0,0 +1,28 @@
+package sidecar
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerUID returns the user ID of the process on the other end of a Unix
+// socket connection.
+func peerUID(conn net.Conn) (uint32, bool) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return 0, false
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return 0, false
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil || credErr != nil {
+		return 0, false
+	}
+	return cred.Uid, true
+}
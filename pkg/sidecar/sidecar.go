@@ -2,40 +2,141 @@ package sidecar
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/segmentio/ctlstore"
+	"github.com/segmentio/ctlstore/pkg/schema"
 	"github.com/segmentio/errors-go"
+	"github.com/segmentio/events"
 	"github.com/segmentio/stats/v4"
 	"github.com/segmentio/stats/v4/httpstats"
+	"google.golang.org/grpc"
 )
 
+// NextPageTokenHeader is set on the response to a prefix scan when there
+// are more rows to read. Passing its value as the page_token query
+// parameter of the same scan reads the next page of rows.
+const NextPageTokenHeader = "X-Ctlstore-Next-Page-Token"
+
 type (
 	Sidecar struct {
-		bindAddr string
-		reader   Reader
-		maxRows  int
-		handler  http.Handler
+		bindAddr      string
+		reader        Reader
+		maxRows       int
+		changelogPath string
+		grpcBindAddr  string
+		grpcServer    *grpc.Server
+		acl           *ACL
+		tlsConfig     *tls.Config
+		socketMode    os.FileMode
+		minSeqTimeout time.Duration
+		maxStream     time.Duration
+		streamAll     bool
+		handler       http.Handler
+		freshness     freshnessCache
+		closing       chan struct{} // closed when the sidecar shuts down
 	}
 	Config struct {
+		// BindAddr is the address the sidecar is served on, which is either
+		// a TCP address, or a Unix socket as unix:///path/to.sock
 		BindAddr    string
 		Reader      Reader
 		MaxRows     int
 		Application string
+		// ChangelogPath, if set, is the changelog which the /changes endpoint
+		// streams changes from.
+		ChangelogPath string
+		// GRPCBindAddr, if set, is the address the sidecar's gRPC service is
+		// served on. It may be the same as BindAddr, in which case gRPC and
+		// HTTP requests are both served from it, but gRPC is then served
+		// more slowly, so a separate address is recommended.
+		GRPCBindAddr string
+		// ACL, if set, limits the families and tables each client may read.
+		ACL *ACL
+		// TLSConfig, if set, is used to serve the sidecar over TLS. Client
+		// certificates which it verifies identify clients to the ACL.
+		TLSConfig *tls.Config
+		// SocketMode is the file mode of the sidecar's Unix sockets, and
+		// defaults to DefaultSocketMode.
+		SocketMode os.FileMode
+		// MinSequenceTimeout bounds how long requests wait for the sequence
+		// of their MinSequenceHeader, and defaults to
+		// DefaultMinSequenceTimeout.
+		MinSequenceTimeout time.Duration
+		// MaxStreamDuration bounds how long prefix scans streamed as NDJSON
+		// or over gRPC take, and defaults to DefaultMaxStreamDuration.
+		MaxStreamDuration time.Duration
+		// StreamAllRows has prefix scans in streamed formats return all of
+		// their rows as they're read. Otherwise they're buffered and
+		// limited by MaxRows like other scans. gRPC scans are always
+		// streamed, but are also limited by MaxRows unless this is set.
+		StreamAllRows bool
 	}
 	Reader interface {
 		GetRowByKey(ctx context.Context, out interface{}, familyName string, tableName string, key ...interface{}) (found bool, err error)
 		GetRowsByKeyPrefix(ctx context.Context, familyName string, tableName string, key ...interface{}) (*ctlstore.Rows, error)
 		GetLedgerLatency(ctx context.Context) (time.Duration, error)
 	}
+	// SchemaReader is implemented by readers which can describe the
+	// families and tables of the LDB, such as *ctlstore.LDBReader.
+	SchemaReader interface {
+		ListFamilies(ctx context.Context) ([]string, error)
+		ListTables(ctx context.Context, familyName string) ([]string, error)
+		DescribeTable(ctx context.Context, familyName string, tableName string) (ctlstore.TableSchema, error)
+	}
+	// PagingReader is implemented by readers whose prefix scans can be
+	// paged through, such as *ctlstore.LDBReader.
+	PagingReader interface {
+		GetRowsByKeyRange(ctx context.Context, familyName string, tableName string, from []interface{}, to []interface{}, opts ctlstore.KeyRangeOpts) (*ctlstore.Rows, error)
+	}
+	// BatchReader is implemented by readers which can read many rows of a
+	// table by key at once, such as *ctlstore.LDBReader.
+	BatchReader interface {
+		GetRowsByKeys(ctx context.Context, familyName string, tableName string, keys [][]interface{}) (*ctlstore.Rows, error)
+	}
+	// SequenceReader is implemented by readers which can report the last
+	// ledger sequence applied to the LDB, such as *ctlstore.LDBReader.
+	SequenceReader interface {
+		GetLastSequence(ctx context.Context) (schema.DMLSequence, error)
+	}
 	ReadRequest struct {
 		Key []Key
 	}
+	// BatchReadRequest is the request body of the batch-get-row-by-key
+	// endpoint. Each read may be from a different table.
+	BatchReadRequest struct {
+		Reads []BatchRead
+	}
+	BatchRead struct {
+		Family string
+		Table  string
+		Key    []Key
+	}
+	// BatchReadResult is the result of a single read of a batch, returned
+	// in the same order as the reads were requested. Error is set instead
+	// if the read failed.
+	BatchReadResult struct {
+		Found bool                   `json:"found"`
+		Row   map[string]interface{} `json:"row,omitempty"`
+		Error string                 `json:"error,omitempty"`
+	}
+	// TableDescription is the response body of the describe-table endpoint.
+	// Fields are [name, type] pairs in the same form the executive accepts
+	// when creating a table.
+	TableDescription struct {
+		Family    string     `json:"family"`
+		Table     string     `json:"table"`
+		Fields    [][]string `json:"fields"`
+		KeyFields []string   `json:"keyFields"`
+	}
 	// Key represents a primary key segment.  The 'Value' field should be used unless the key segment is a
 	// varbinary field.  This is so that the json unmarshaling will decode base64 for the Binary property.
 	Key struct {
@@ -61,11 +162,39 @@ func keysToInterface(keys []Key) []interface{} {
 	return res
 }
 
+// shutdownTimeout bounds how long the sidecar waits for requests to complete
+// when it shuts down.
+var shutdownTimeout = 10 * time.Second
+
 func New(config Config) (*Sidecar, error) {
 	sidecar := &Sidecar{
-		bindAddr: config.BindAddr,
-		reader:   config.Reader,
-		maxRows:  config.MaxRows,
+		bindAddr:      config.BindAddr,
+		reader:        config.Reader,
+		maxRows:       config.MaxRows,
+		changelogPath: config.ChangelogPath,
+		grpcBindAddr:  config.GRPCBindAddr,
+		tlsConfig:     config.TLSConfig,
+		socketMode:    config.SocketMode,
+		minSeqTimeout: config.MinSequenceTimeout,
+		maxStream:     config.MaxStreamDuration,
+		streamAll:     config.StreamAllRows,
+		closing:       make(chan struct{}),
+	}
+	if sidecar.socketMode == 0 {
+		sidecar.socketMode = DefaultSocketMode
+	}
+	if sidecar.minSeqTimeout <= 0 {
+		sidecar.minSeqTimeout = DefaultMinSequenceTimeout
+	}
+	if sidecar.maxStream <= 0 {
+		sidecar.maxStream = DefaultMaxStreamDuration
+	}
+	if config.ACL != nil {
+		acl, err := config.ACL.normalize()
+		if err != nil {
+			return nil, errors.Wrap(err, "acl")
+		}
+		sidecar.acl = acl
 	}
 	mux := mux.NewRouter()
 	handleErr := func(fn func(http.ResponseWriter, *http.Request) error) http.HandlerFunc {
@@ -75,6 +204,14 @@ func New(config Config) (*Sidecar, error) {
 			case err == nil:
 			case errors.Is("limit-exceeded", err):
 				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			case errors.Is("bad-request", err):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is("forbidden", err):
+				http.Error(w, err.Error(), http.StatusForbidden)
+			case errors.Is("not-implemented", err):
+				http.Error(w, err.Error(), http.StatusNotImplemented)
+			case errors.Is("not-acceptable", err):
+				http.Error(w, err.Error(), http.StatusNotAcceptable)
 			default:
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 			}
@@ -82,7 +219,12 @@ func New(config Config) (*Sidecar, error) {
 	}
 	mux.HandleFunc("/get-row-by-key/{familyName}/{tableName}", handleErr(sidecar.getRowByKey)).Methods("POST")
 	mux.HandleFunc("/get-rows-by-key-prefix/{familyName}/{tableName}", handleErr(sidecar.getRowsByKeyPrefix)).Methods("POST")
+	mux.HandleFunc("/batch-get-row-by-key", handleErr(sidecar.batchGetRowByKey)).Methods("POST")
+	mux.HandleFunc("/list-families", handleErr(sidecar.listFamilies)).Methods("GET")
+	mux.HandleFunc("/list-tables/{familyName}", handleErr(sidecar.listTables)).Methods("GET")
+	mux.HandleFunc("/describe-table/{familyName}/{tableName}", handleErr(sidecar.describeTable)).Methods("GET")
 	mux.HandleFunc("/get-ledger-latency", handleErr(sidecar.getLedgerLatency)).Methods("GET")
+	mux.HandleFunc("/get-ledger-sequence", handleErr(sidecar.getLedgerSequence)).Methods("GET")
 	mux.HandleFunc("/healthcheck", handleErr(sidecar.healthcheck)).Methods("GET")
 	mux.HandleFunc("/ping", handleErr(sidecar.ping)).Methods("GET")
 
@@ -90,28 +232,137 @@ func New(config Config) (*Sidecar, error) {
 	stats.DefaultEngine.Tags = append(stats.DefaultEngine.Tags, stats.T("application", application))
 	stats.DefaultEngine.Tags = stats.SortTags(stats.DefaultEngine.Tags) // tags must be sorted
 
-	sidecar.handler = sidecar.statsHandler(mux)
+	handler := sidecar.statsHandler(sidecar.withFreshness(mux))
+	// requests other than streamed scans are also timed out here, so that
+	// their reads are canceled once the server has given up on them
+	timed := http.TimeoutHandler(handler, writeTimeout, "")
+	sidecar.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sidecar.streamedScan(r) {
+			handler.ServeHTTP(w, r)
+		} else {
+			timed.ServeHTTP(w, r)
+		}
+	})
+	if sidecar.changelogPath != "" {
+		sidecar.handler = sidecar.withChanges(sidecar.handler)
+	}
+	if sidecar.grpcBindAddr != "" {
+		sidecar.grpcServer = sidecar.newGRPCServer()
+		if sidecar.grpcBindAddr == sidecar.bindAddr {
+			sidecar.handler = sidecar.withGRPC(sidecar.handler)
+		}
+	}
 
 	return sidecar, nil
 }
 
+// Start serves the sidecar until the context is done, and then shuts it
+// down gracefully, giving requests which are in flight up to
+// shutdownTimeout to complete.
 func (s *Sidecar) Start(ctx context.Context) error {
 	srv := &http.Server{
-		Addr:         s.bindAddr,
 		Handler:      s,
 		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 5 * time.Second,
+		WriteTimeout: writeTimeout,
 		ErrorLog:     log.New(os.Stderr, "SRV ERR:", log.LstdFlags),
+		ConnContext:  connContext,
+		TLSConfig:    s.tlsConfig,
+	}
+	// change streams would otherwise hold up the shutdown
+	srv.RegisterOnShutdown(func() { close(s.closing) })
+
+	lis, err := listen(s.bindAddr, s.socketMode)
+	if err != nil {
+		return err
 	}
-	defer srv.Close()
-	err := srv.ListenAndServe()
-	return errors.Wrap(err, "listen and serve")
+	errs := make(chan error, 2)
+	go func() {
+		if s.tlsConfig != nil {
+			errs <- errors.Wrap(srv.ServeTLS(lis, "", ""), "serve")
+		} else {
+			errs <- errors.Wrap(srv.Serve(lis), "serve")
+		}
+	}()
+	if s.grpcServer != nil && s.grpcBindAddr != s.bindAddr {
+		grpcLis, err := listen(s.grpcBindAddr, s.socketMode)
+		if err != nil {
+			srv.Close()
+			return errors.Wrap(err, "grpc")
+		}
+		go func() {
+			errs <- errors.Wrap(s.grpcServer.Serve(grpcLis), "serve grpc")
+		}()
+	}
+	events.Log("Sidecar listening on %{addr}s", s.bindAddr)
+
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+		events.Log("Sidecar failed: %{error}+v", err)
+	}
+
+	events.Log("Shutting down the sidecar...")
+	sctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if s.grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			s.grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		defer func() {
+			select {
+			case <-stopped:
+			case <-sctx.Done():
+				s.grpcServer.Stop()
+			}
+		}()
+	}
+	if shutdownErr := srv.Shutdown(sctx); shutdownErr != nil {
+		events.Log("Sidecar shutdown error: %{error}+v", shutdownErr)
+		srv.Close()
+	}
+	return err
 }
 
 func (s *Sidecar) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.handler.ServeHTTP(w, r)
 }
 
+// identities returns the identities of the client which sent the request,
+// if the sidecar has an ACL.
+func (s *Sidecar) identities(r *http.Request) []string {
+	if s.acl == nil {
+		return nil
+	}
+	return s.acl.identities(r.Context(), r.Header.Get("Authorization"), r.TLS)
+}
+
+type connKey struct{}
+
+// connContext is the ConnContext of the sidecar's server. It records the
+// connection of each request, for setWriteDeadline, along with the user ID
+// of the peer.
+func connContext(ctx context.Context, conn net.Conn) context.Context {
+	return withPeerUID(context.WithValue(ctx, connKey{}, conn), conn)
+}
+
+// setWriteDeadline overrides the server's write timeout for the response to
+// a request, which streams do since they outlast it. A zero deadline lets
+// the response take as long as it needs. The server sets the deadline of
+// HTTP/1 connections again for each request, so it only applies to this
+// response.
+func setWriteDeadline(w http.ResponseWriter, r *http.Request, deadline time.Time) {
+	if dw, ok := w.(interface{ SetWriteDeadline(time.Time) error }); ok && dw.SetWriteDeadline(deadline) == nil {
+		return
+	}
+	if conn, ok := r.Context().Value(connKey{}).(net.Conn); ok && r.ProtoMajor == 1 {
+		if err := conn.SetWriteDeadline(deadline); err != nil {
+			events.Log("Could not set write deadline: %{error}v", err)
+		}
+	}
+}
+
 func (s *Sidecar) statsHandler(delegate http.Handler) http.Handler {
 	return httpstats.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ua := orUnknown(r.UserAgent())
@@ -132,6 +383,21 @@ func (s *Sidecar) getLedgerLatency(w http.ResponseWriter, r *http.Request) error
 	return json.NewEncoder(w).Encode(res)
 }
 
+func (s *Sidecar) getLedgerSequence(w http.ResponseWriter, r *http.Request) error {
+	reader, ok := s.reader.(SequenceReader)
+	if !ok {
+		return errors.WithTypes(errors.New("this sidecar does not track the ledger sequence"), "not-implemented")
+	}
+	seq, err := reader.GetLastSequence(r.Context())
+	if err != nil {
+		return errors.Wrap(err, "get ledger sequence")
+	}
+	res := map[string]interface{}{
+		"value": seq.Int(),
+	}
+	return json.NewEncoder(w).Encode(res)
+}
+
 func (s *Sidecar) healthcheck(w http.ResponseWriter, r *http.Request) error {
 	_, err := s.reader.GetLedgerLatency(r.Context())
 	return errors.Wrap(err, "healthcheck")
@@ -146,18 +412,67 @@ func (s *Sidecar) getRowsByKeyPrefix(w http.ResponseWriter, r *http.Request) err
 	vars := mux.Vars(r)
 	family := vars["familyName"]
 	table := vars["tableName"]
+	if err := s.authorize(s.identities(r), r.UserAgent(), family, table); err != nil {
+		return err
+	}
 
 	var rr ReadRequest
 	err := json.NewDecoder(r.Body).Decode(&rr)
 	if err != nil {
 		return errors.Wrap(err, "decode body")
 	}
-	res := make([]interface{}, 0)
-	rows, err := s.reader.GetRowsByKeyPrefix(r.Context(), family, table, keysToInterface(rr.Key)...)
+	format, err := negotiateRowFormat(r.Header.Get("Accept"))
+	if err != nil {
+		return err
+	}
+	pageSize, pageToken, err := pageParams(r)
+	if err != nil {
+		return err
+	}
+	// scans are only paged when the client asks for it, and unpaged scans
+	// of more rows than the max row count are refused.
+	paged := pageSize > 0 || pageToken != ""
+	paging, canPage := s.reader.(PagingReader)
+	if paged && !canPage {
+		return errors.WithTypes(errors.New("this sidecar does not support paging"), "bad-request")
+	}
+	streamed := s.streams(format, paged)
+
+	ctx := r.Context()
+	if streamed {
+		// streamed scans outlast the write timeout, but are still bounded
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.maxStream)
+		defer cancel()
+		setWriteDeadline(w, r, time.Now().Add(s.maxStream))
+	}
+	var rows *ctlstore.Rows
+	if paged {
+		limit := pageSize
+		if s.maxRows > 0 && (limit == 0 || limit > s.maxRows) {
+			limit = s.maxRows
+		}
+		prefix := keysToInterface(rr.Key)
+		rows, err = paging.GetRowsByKeyRange(ctx, family, table, prefix, prefix, ctlstore.KeyRangeOpts{
+			Limit:  limit,
+			Cursor: pageToken,
+		})
+	} else {
+		rows, err = s.reader.GetRowsByKeyPrefix(ctx, family, table, keysToInterface(rr.Key)...)
+	}
+	if err == ctlstore.ErrInvalidCursor {
+		return errors.WithTypes(errors.Wrap(err, "page token"), "bad-request")
+	}
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
+	w.Header().Set("Content-Type", format.contentType)
+	if streamed {
+		return s.streamRows(w, format, rows, family, table)
+	}
+
+	res := make([]map[string]interface{}, 0)
 	for rows.Next() {
 		out := make(map[string]interface{})
 		err = rows.Scan(out)
@@ -175,15 +490,75 @@ func (s *Sidecar) getRowsByKeyPrefix(w http.ResponseWriter, r *http.Request) err
 	if err != nil {
 		return err
 	}
+	nextPageToken, err := rows.Cursor()
+	if err != nil {
+		return err
+	}
 	stats.Observe("get-rows-by-key-prefix-num-rows", len(res), stats.T("family", family), stats.T("table", table))
-	err = json.NewEncoder(w).Encode(res)
+	if nextPageToken != "" {
+		w.Header().Set(NextPageTokenHeader, nextPageToken)
+	}
+	enc := format.newEncoder(w, rows.Columns())
+	for _, row := range res {
+		if err := enc.encode(row); err != nil {
+			return errors.Wrap(err, "encode")
+		}
+	}
+	return enc.close()
+}
+
+// streamRows encodes the rows of a prefix scan as they're read. Errors can
+// only be returned until the first row has been written, after which the
+// response is aborted instead, so that the client can't mistake the rows
+// it received for all of them.
+func (s *Sidecar) streamRows(w http.ResponseWriter, format *rowFormat, rows *ctlstore.Rows, family string, table string) error {
+	enc := format.newEncoder(w, rows.Columns())
+	n := 0
+	err := func() error {
+		for rows.Next() {
+			out := make(map[string]interface{})
+			if err := rows.Scan(out); err != nil {
+				return errors.Wrap(err, "scan")
+			}
+			n++
+			if err := enc.encode(out); err != nil {
+				return errors.Wrap(err, "encode")
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return enc.close()
+	}()
+	if err != nil && n > 0 {
+		events.Log("Aborted prefix scan of %{family}s.%{table}s after %{rows}d rows: %{error}+v", family, table, n, err)
+		panic(http.ErrAbortHandler)
+	}
+	stats.Observe("get-rows-by-key-prefix-num-rows", n, stats.T("family", family), stats.T("table", table))
 	return err
 }
 
+// pageParams reads the page_size and page_token query parameters of a
+// prefix scan.
+func pageParams(r *http.Request) (pageSize int, pageToken string, err error) {
+	query := r.URL.Query()
+	if v := query.Get("page_size"); v != "" {
+		pageSize, err = strconv.Atoi(v)
+		if err != nil || pageSize <= 0 {
+			err = errors.Errorf("invalid page_size '%s'", v)
+			return 0, "", errors.WithTypes(err, "bad-request")
+		}
+	}
+	return pageSize, query.Get("page_token"), nil
+}
+
 func (s *Sidecar) getRowByKey(w http.ResponseWriter, r *http.Request) error {
 	vars := mux.Vars(r)
 	family := vars["familyName"]
 	table := vars["tableName"]
+	if err := s.authorize(s.identities(r), r.UserAgent(), family, table); err != nil {
+		return err
+	}
 
 	var rr ReadRequest
 	err := json.NewDecoder(r.Body).Decode(&rr)
@@ -205,6 +580,130 @@ func (s *Sidecar) getRowByKey(w http.ResponseWriter, r *http.Request) error {
 	return err
 }
 
+func (s *Sidecar) batchGetRowByKey(w http.ResponseWriter, r *http.Request) error {
+	var br BatchReadRequest
+	err := json.NewDecoder(r.Body).Decode(&br)
+	if err != nil {
+		return errors.Wrap(err, "decode body")
+	}
+	if s.maxRows > 0 && len(br.Reads) > s.maxRows {
+		err = errors.Errorf("max row count (%d) exceeded", s.maxRows)
+		return errors.WithTypes(err, "limit-exceeded")
+	}
+	identities := s.identities(r)
+	for _, read := range br.Reads {
+		if err := s.authorize(identities, r.UserAgent(), read.Family, read.Table); err != nil {
+			return err
+		}
+	}
+	res := make([]BatchReadResult, len(br.Reads))
+	for _, group := range groupBatchReads(br.Reads) {
+		if s.readBatch(r.Context(), br.Reads, group, res) {
+			continue
+		}
+		for _, i := range group {
+			res[i] = s.readOne(r.Context(), br.Reads[i])
+		}
+	}
+	stats.Observe("batch-get-row-by-key-num-reads", len(br.Reads))
+	return json.NewEncoder(w).Encode(res)
+}
+
+// schemaReader returns the sidecar's reader as a SchemaReader, or a
+// "not-implemented" error if it can't describe the LDB.
+func (s *Sidecar) schemaReader() (SchemaReader, error) {
+	reader, ok := s.reader.(SchemaReader)
+	if !ok {
+		return nil, errors.WithTypes(errors.New("this sidecar does not describe the LDB's schema"), "not-implemented")
+	}
+	return reader, nil
+}
+
+func (s *Sidecar) listFamilies(w http.ResponseWriter, r *http.Request) error {
+	reader, err := s.schemaReader()
+	if err != nil {
+		return err
+	}
+	families, err := reader.ListFamilies(r.Context())
+	if err != nil {
+		return errors.Wrap(err, "list families")
+	}
+	if s.acl != nil {
+		// only the families the client may read are listed
+		identities := s.identities(r)
+		allowed := make([]string, 0, len(families))
+		for _, family := range families {
+			if s.acl.allowsFamily(identities, family) {
+				allowed = append(allowed, family)
+			}
+		}
+		families = allowed
+	}
+	return json.NewEncoder(w).Encode(families)
+}
+
+func (s *Sidecar) listTables(w http.ResponseWriter, r *http.Request) error {
+	family := mux.Vars(r)["familyName"]
+	identities := s.identities(r)
+	if err := s.authorize(identities, r.UserAgent(), family, ""); err != nil {
+		return err
+	}
+	reader, err := s.schemaReader()
+	if err != nil {
+		return err
+	}
+	tables, err := reader.ListTables(r.Context(), family)
+	if err != nil {
+		return errors.Wrap(err, "list tables")
+	}
+	if s.acl != nil {
+		allowed := make([]string, 0, len(tables))
+		for _, table := range tables {
+			if s.acl.allows(identities, family, table) {
+				allowed = append(allowed, table)
+			}
+		}
+		tables = allowed
+	}
+	return json.NewEncoder(w).Encode(tables)
+}
+
+func (s *Sidecar) describeTable(w http.ResponseWriter, r *http.Request) error {
+	vars := mux.Vars(r)
+	family := vars["familyName"]
+	table := vars["tableName"]
+	if err := s.authorize(s.identities(r), r.UserAgent(), family, table); err != nil {
+		return err
+	}
+	reader, err := s.schemaReader()
+	if err != nil {
+		return err
+	}
+
+	ts, err := reader.DescribeTable(r.Context(), family, table)
+	if err == ctlstore.ErrTableNotFound {
+		w.Header().Set("X-Ctlstore", "Not Found") // to differentiate between route based 404s
+		w.WriteHeader(http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "describe table")
+	}
+	res := TableDescription{
+		Family:    ts.FamilyName,
+		Table:     ts.TableName,
+		Fields:    make([][]string, 0, len(ts.Fields)),
+		KeyFields: ts.PrimaryKey.Strings(),
+	}
+	for _, field := range ts.Fields {
+		res.Fields = append(res.Fields, []string{
+			field.Name.Name,
+			schema.FieldTypeStringsByFieldType[field.FieldType],
+		})
+	}
+	return json.NewEncoder(w).Encode(res)
+}
+
 func orUnknown(value string) string {
 	if value == "" {
 		return "unknown"
@@ -2,14 +2,19 @@ package sidecar
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
 
 	"github.com/segmentio/ctlstore"
+	"github.com/segmentio/ctlstore/pkg/ldbwriter"
+	"github.com/segmentio/ctlstore/pkg/schema"
 	"github.com/stretchr/testify/require"
 )
 
@@ -264,3 +269,370 @@ func TestFetchCtlstoreData(t *testing.T) {
 	}
 
 }
+
+func TestGetRowsByKeyPrefixPaging(t *testing.T) {
+	tu, teardown := ctlstore.NewLDBTestUtil(t)
+	defer teardown()
+	rows := [][]interface{}{}
+	for i := 0; i < 10; i++ {
+		rows = append(rows, []interface{}{"a", i}, []interface{}{"b", i})
+	}
+	tu.CreateTable(ctlstore.LDBTestTableDef{
+		Family: "test_family",
+		Name:   "test_table",
+		Fields: [][]string{
+			{"k1", "string"},
+			{"k2", "integer"},
+		},
+		KeyFields: []string{"k1", "k2"},
+		Rows:      rows,
+	})
+	body, err := json.Marshal(ReadRequest{[]Key{{Value: "b"}}})
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name     string
+		reader   Reader
+		maxRows  int
+		pageSize string
+		pages    []int // the number of rows in each page
+		status   int
+	}{
+		{name: "pages", pageSize: "4", pages: []int{4, 4, 2}},
+		{name: "single page", pageSize: "10", pages: []int{10}},
+		{name: "page size capped by max rows", maxRows: 3, pageSize: "5", pages: []int{3, 3, 3, 1}},
+		{name: "max rows without a page size", maxRows: 6, status: http.StatusRequestedRangeNotSatisfiable},
+		{name: "unpaged within max rows", maxRows: 10, pages: []int{10}},
+		{name: "invalid page size", pageSize: "-1", status: http.StatusBadRequest},
+		{name: "unsupported", reader: prefixOnlyReader{ctlstore.NewLDBReaderFromDB(tu.DB)}, pageSize: "4", status: http.StatusBadRequest},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			reader := test.reader
+			if reader == nil {
+				reader = ctlstore.NewLDBReaderFromDB(tu.DB)
+			}
+			sc, err := New(Config{Reader: reader, MaxRows: test.maxRows})
+			require.NoError(t, err)
+
+			var pages []int
+			var keys []float64
+			token := ""
+			for {
+				query := url.Values{}
+				if test.pageSize != "" {
+					query.Set("page_size", test.pageSize)
+				}
+				if token != "" {
+					query.Set("page_token", token)
+				}
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest(http.MethodPost, "/get-rows-by-key-prefix/test_family/test_table?"+query.Encode(), bytes.NewReader(body))
+				sc.ServeHTTP(w, r)
+				if test.status != 0 {
+					require.EqualValues(t, test.status, w.Code, w.Body.String())
+					return
+				}
+				require.EqualValues(t, http.StatusOK, w.Code, w.Body.String())
+				token = w.Header().Get(NextPageTokenHeader)
+				var res []map[string]interface{}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+				pages = append(pages, len(res))
+				for _, row := range res {
+					require.Equal(t, "b", row["k1"])
+					keys = append(keys, row["k2"].(float64))
+				}
+				if token == "" {
+					break
+				}
+			}
+			require.Equal(t, test.pages, pages)
+			require.Equal(t, []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, keys)
+		})
+	}
+
+	sc, err := New(Config{Reader: ctlstore.NewLDBReaderFromDB(tu.DB)})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/get-rows-by-key-prefix/test_family/test_table?page_token=bogus", bytes.NewReader(body))
+	sc.ServeHTTP(w, r)
+	require.EqualValues(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	// page tokens can't be used to continue the scan of another prefix
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/get-rows-by-key-prefix/test_family/test_table?page_size=1", bytes.NewReader(body))
+	sc.ServeHTTP(w, r)
+	require.EqualValues(t, http.StatusOK, w.Code, w.Body.String())
+	token := w.Header().Get(NextPageTokenHeader)
+	require.NotEmpty(t, token)
+	otherBody, err := json.Marshal(ReadRequest{[]Key{{Value: "a"}}})
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/get-rows-by-key-prefix/test_family/test_table?page_token="+url.QueryEscape(token), bytes.NewReader(otherBody))
+	sc.ServeHTTP(w, r)
+	require.EqualValues(t, http.StatusBadRequest, w.Code, w.Body.String())
+}
+
+// prefixOnlyReader hides the optional interfaces of the reader it wraps,
+// such as PagingReader.
+type prefixOnlyReader struct {
+	Reader
+}
+
+func TestBatchGetRowByKey(t *testing.T) {
+	tu, teardown := ctlstore.NewLDBTestUtil(t)
+	defer teardown()
+	tu.CreateTable(ctlstore.LDBTestTableDef{
+		Family: "test_family",
+		Name:   "test_table",
+		Fields: [][]string{
+			{"key", "string"},
+			{"value", "string"},
+		},
+		KeyFields: []string{"key"},
+		Rows: [][]interface{}{
+			{"test-key", "test-value"},
+			{"test-key-2", "test-value-2"},
+		},
+	})
+	tu.CreateTable(ctlstore.LDBTestTableDef{
+		Family: "test_family",
+		Name:   "binary_key_table",
+		Fields: [][]string{
+			{"key", "bytestring"},
+			{"value", "string"},
+		},
+		KeyFields: []string{"key"},
+		Rows: [][]interface{}{
+			{[]byte{0xde, 0xad, 0xbe, 0xef}, "binary-value"},
+		},
+	})
+	tu.CreateTable(ctlstore.LDBTestTableDef{
+		Family: "test_family",
+		Name:   "int_key_table",
+		Fields: [][]string{
+			{"id", "integer"},
+			{"value", "string"},
+		},
+		KeyFields: []string{"id"},
+		Rows: [][]interface{}{
+			{1, "one"},
+			{2, "two"},
+		},
+	})
+	br := BatchReadRequest{Reads: []BatchRead{
+		{Family: "test_family", Table: "test_table", Key: []Key{{Value: "test-key-2"}}},
+		{Family: "test_family", Table: "test_table", Key: []Key{{Value: "does not exist"}}},
+		{Family: "test_family", Table: "binary_key_table", Key: []Key{{Binary: []byte{0xde, 0xad, 0xbe, 0xef}}}},
+		{Family: "test_family", Table: "no_such_table", Key: []Key{{Value: "test-key"}}},
+		{Family: "test_family", Table: "test_table", Key: []Key{{Value: "test-key"}}},
+		{Family: "test_family", Table: "int_key_table", Key: []Key{{Value: 2}}},
+		{Family: "test_family", Table: "int_key_table", Key: []Key{{Value: 1}}},
+		{Family: "test_family", Table: "int_key_table", Key: []Key{{Value: 2}}},
+	}}
+	body, err := json.Marshal(br)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name     string
+		maxRows  int
+		batched  bool
+		status   int
+		rowReads int32
+	}{
+		{name: "no limit", status: http.StatusOK, rowReads: 8},
+		{name: "within limit", maxRows: len(br.Reads), status: http.StatusOK, rowReads: 8},
+		{name: "limit exceeded", maxRows: len(br.Reads) - 1, status: http.StatusRequestedRangeNotSatisfiable},
+		// only the tables with a single read are read from one at a time
+		{name: "batched", batched: true, status: http.StatusOK, rowReads: 2},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			reader := &rowCountingReader{Reader: ctlstore.NewLDBReaderFromDB(tu.DB)}
+			config := Config{Reader: reader, MaxRows: test.maxRows}
+			if test.batched {
+				config.Reader = &batchingReader{reader, ctlstore.NewLDBReaderFromDB(tu.DB)}
+			}
+			sc, err := New(config)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/batch-get-row-by-key", bytes.NewReader(body))
+			sc.ServeHTTP(w, r)
+			require.EqualValues(t, test.status, w.Code, w.Body.String())
+			if test.status != http.StatusOK {
+				return
+			}
+
+			var res []BatchReadResult
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			require.Len(t, res, len(br.Reads))
+			require.Equal(t, BatchReadResult{
+				Found: true,
+				Row:   map[string]interface{}{"key": "test-key-2", "value": "test-value-2"},
+			}, res[0])
+			require.Equal(t, BatchReadResult{}, res[1])
+			require.Equal(t, BatchReadResult{
+				Found: true,
+				Row: map[string]interface{}{
+					"key":   base64.StdEncoding.EncodeToString([]byte{0xde, 0xad, 0xbe, 0xef}),
+					"value": "binary-value",
+				},
+			}, res[2])
+			require.False(t, res[3].Found)
+			require.NotEmpty(t, res[3].Error)
+			require.Equal(t, "test-value", res[4].Row["value"])
+			require.Equal(t, "two", res[5].Row["value"])
+			require.Equal(t, "one", res[6].Row["value"])
+			require.Equal(t, "two", res[7].Row["value"])
+			require.Equal(t, test.rowReads, atomic.LoadInt32(&reader.rowReads))
+		})
+	}
+}
+
+// rowCountingReader counts the rows read one at a time.
+type rowCountingReader struct {
+	Reader
+	rowReads int32
+}
+
+func (r *rowCountingReader) GetRowByKey(ctx context.Context, out interface{}, familyName string, tableName string, key ...interface{}) (bool, error) {
+	atomic.AddInt32(&r.rowReads, 1)
+	return r.Reader.GetRowByKey(ctx, out, familyName, tableName, key...)
+}
+
+// batchingReader reads rows one at a time through a rowCountingReader, and
+// everything else through an LDBReader.
+type batchingReader struct {
+	*rowCountingReader
+	*ctlstore.LDBReader
+}
+
+func (r *batchingReader) GetRowByKey(ctx context.Context, out interface{}, familyName string, tableName string, key ...interface{}) (bool, error) {
+	return r.rowCountingReader.GetRowByKey(ctx, out, familyName, tableName, key...)
+}
+
+func TestLedgerSequence(t *testing.T) {
+	ctx := context.Background()
+	tu, teardown := ctlstore.NewLDBTestUtil(t)
+	defer teardown()
+	writer := ldbwriter.SqlLdbWriter{Db: tu.DB}
+	err := writer.ApplyDMLStatement(ctx, schema.DMLStatement{
+		Sequence:  42,
+		Statement: "CREATE TABLE family___table (key VARCHAR PRIMARY KEY)",
+	})
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name   string
+		reader Reader
+		status int
+		result interface{}
+	}{
+		{name: "sequence", reader: ctlstore.NewLDBReaderFromDB(tu.DB), status: http.StatusOK, result: map[string]interface{}{"value": 42.0}},
+		{name: "unsupported", reader: prefixOnlyReader{ctlstore.NewLDBReaderFromDB(tu.DB)}, status: http.StatusNotImplemented},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			sc, err := New(Config{Reader: test.reader})
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/get-ledger-sequence", nil)
+			sc.ServeHTTP(w, r)
+			require.EqualValues(t, test.status, w.Code, w.Body.String())
+			if test.result == nil {
+				return
+			}
+			var res interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			require.Equal(t, test.result, res)
+		})
+	}
+}
+
+func TestSchemaIntrospection(t *testing.T) {
+	tu, teardown := ctlstore.NewLDBTestUtil(t)
+	defer teardown()
+
+	tu.CreateTable(ctlstore.LDBTestTableDef{
+		Family: "family",
+		Name:   "table",
+		Fields: [][]string{
+			{"key", "string"},
+			{"data", "bytestring"},
+			{"num", "integer"},
+		},
+		KeyFields: []string{"key", "data"},
+	})
+
+	reader := ctlstore.NewLDBReaderFromDB(tu.DB)
+
+	for _, test := range []struct {
+		name   string
+		reader Reader // defaults to reader
+		path   string
+		status int
+		result interface{}
+	}{
+		{
+			name:   "list families",
+			path:   "/list-families",
+			status: http.StatusOK,
+			result: []interface{}{"family"},
+		},
+		{
+			name:   "list tables",
+			path:   "/list-tables/family",
+			status: http.StatusOK,
+			result: []interface{}{"table"},
+		},
+		{
+			name:   "list tables of unknown family",
+			path:   "/list-tables/nope",
+			status: http.StatusOK,
+			result: []interface{}{},
+		},
+		{
+			name:   "describe table",
+			path:   "/describe-table/family/table",
+			status: http.StatusOK,
+			result: map[string]interface{}{
+				"family": "family",
+				"table":  "table",
+				"fields": []interface{}{
+					[]interface{}{"key", "string"},
+					[]interface{}{"data", "bytestring"},
+					[]interface{}{"num", "integer"},
+				},
+				"keyFields": []interface{}{"key", "data"},
+			},
+		},
+		{
+			name:   "describe unknown table",
+			path:   "/describe-table/family/nope",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "unsupported",
+			reader: prefixOnlyReader{reader},
+			path:   "/list-families",
+			status: http.StatusNotImplemented,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			config := Config{Reader: reader}
+			if test.reader != nil {
+				config.Reader = test.reader
+			}
+			sc, err := New(config)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, test.path, nil)
+			sc.ServeHTTP(w, r)
+			require.EqualValues(t, test.status, w.Code, w.Body.String())
+			if test.result == nil {
+				return
+			}
+			var res interface{}
+			err = json.Unmarshal(w.Body.Bytes(), &res)
+			require.NoError(t, err)
+			require.EqualValues(t, test.result, res)
+		})
+	}
+}
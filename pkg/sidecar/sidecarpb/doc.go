@@ -0,0 +1,5 @@
+// Package sidecarpb contains the protocol buffer messages and the gRPC
+// service of the sidecar, generated from sidecar.proto.
+package sidecarpb
+
+//go:generate protoc --go_out=plugins=grpc:. sidecar.proto
@@ -0,0 +1,844 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: sidecar.proto
+
+package sidecarpb
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// Key is a primary key segment.
+type Key struct {
+	// Types that are valid to be assigned to Value:
+	//	*Key_String_
+	//	*Key_Int
+	//	*Key_Binary
+	Value                isKey_Value `protobuf_oneof:"value"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *Key) Reset()         { *m = Key{} }
+func (m *Key) String() string { return proto.CompactTextString(m) }
+func (*Key) ProtoMessage()    {}
+func (*Key) Descriptor() ([]byte, []int) {
+	return fileDescriptor_179ad3b13e6397ec, []int{0}
+}
+
+func (m *Key) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Key.Unmarshal(m, b)
+}
+func (m *Key) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Key.Marshal(b, m, deterministic)
+}
+func (m *Key) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Key.Merge(m, src)
+}
+func (m *Key) XXX_Size() int {
+	return xxx_messageInfo_Key.Size(m)
+}
+func (m *Key) XXX_DiscardUnknown() {
+	xxx_messageInfo_Key.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Key proto.InternalMessageInfo
+
+type isKey_Value interface {
+	isKey_Value()
+}
+
+type Key_String_ struct {
+	String_ string `protobuf:"bytes,1,opt,name=string,proto3,oneof"`
+}
+
+type Key_Int struct {
+	Int int64 `protobuf:"varint,2,opt,name=int,proto3,oneof"`
+}
+
+type Key_Binary struct {
+	Binary []byte `protobuf:"bytes,3,opt,name=binary,proto3,oneof"`
+}
+
+func (*Key_String_) isKey_Value() {}
+
+func (*Key_Int) isKey_Value() {}
+
+func (*Key_Binary) isKey_Value() {}
+
+func (m *Key) GetValue() isKey_Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *Key) GetString_() string {
+	if x, ok := m.GetValue().(*Key_String_); ok {
+		return x.String_
+	}
+	return ""
+}
+
+func (m *Key) GetInt() int64 {
+	if x, ok := m.GetValue().(*Key_Int); ok {
+		return x.Int
+	}
+	return 0
+}
+
+func (m *Key) GetBinary() []byte {
+	if x, ok := m.GetValue().(*Key_Binary); ok {
+		return x.Binary
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*Key) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*Key_String_)(nil),
+		(*Key_Int)(nil),
+		(*Key_Binary)(nil),
+	}
+}
+
+// Value is a column value. A value with none of its fields set is NULL.
+type Value struct {
+	// Types that are valid to be assigned to Value:
+	//	*Value_String_
+	//	*Value_Int
+	//	*Value_Float
+	//	*Value_Binary
+	//	*Value_Bool
+	Value                isValue_Value `protobuf_oneof:"value"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *Value) Reset()         { *m = Value{} }
+func (m *Value) String() string { return proto.CompactTextString(m) }
+func (*Value) ProtoMessage()    {}
+func (*Value) Descriptor() ([]byte, []int) {
+	return fileDescriptor_179ad3b13e6397ec, []int{1}
+}
+
+func (m *Value) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Value.Unmarshal(m, b)
+}
+func (m *Value) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Value.Marshal(b, m, deterministic)
+}
+func (m *Value) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Value.Merge(m, src)
+}
+func (m *Value) XXX_Size() int {
+	return xxx_messageInfo_Value.Size(m)
+}
+func (m *Value) XXX_DiscardUnknown() {
+	xxx_messageInfo_Value.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Value proto.InternalMessageInfo
+
+type isValue_Value interface {
+	isValue_Value()
+}
+
+type Value_String_ struct {
+	String_ string `protobuf:"bytes,1,opt,name=string,proto3,oneof"`
+}
+
+type Value_Int struct {
+	Int int64 `protobuf:"varint,2,opt,name=int,proto3,oneof"`
+}
+
+type Value_Float struct {
+	Float float64 `protobuf:"fixed64,3,opt,name=float,proto3,oneof"`
+}
+
+type Value_Binary struct {
+	Binary []byte `protobuf:"bytes,4,opt,name=binary,proto3,oneof"`
+}
+
+type Value_Bool struct {
+	Bool bool `protobuf:"varint,5,opt,name=bool,proto3,oneof"`
+}
+
+func (*Value_String_) isValue_Value() {}
+
+func (*Value_Int) isValue_Value() {}
+
+func (*Value_Float) isValue_Value() {}
+
+func (*Value_Binary) isValue_Value() {}
+
+func (*Value_Bool) isValue_Value() {}
+
+func (m *Value) GetValue() isValue_Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *Value) GetString_() string {
+	if x, ok := m.GetValue().(*Value_String_); ok {
+		return x.String_
+	}
+	return ""
+}
+
+func (m *Value) GetInt() int64 {
+	if x, ok := m.GetValue().(*Value_Int); ok {
+		return x.Int
+	}
+	return 0
+}
+
+func (m *Value) GetFloat() float64 {
+	if x, ok := m.GetValue().(*Value_Float); ok {
+		return x.Float
+	}
+	return 0
+}
+
+func (m *Value) GetBinary() []byte {
+	if x, ok := m.GetValue().(*Value_Binary); ok {
+		return x.Binary
+	}
+	return nil
+}
+
+func (m *Value) GetBool() bool {
+	if x, ok := m.GetValue().(*Value_Bool); ok {
+		return x.Bool
+	}
+	return false
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*Value) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*Value_String_)(nil),
+		(*Value_Int)(nil),
+		(*Value_Float)(nil),
+		(*Value_Binary)(nil),
+		(*Value_Bool)(nil),
+	}
+}
+
+// Row is a table row, keyed by column name.
+type Row struct {
+	Fields               map[string]*Value `protobuf:"bytes,1,rep,name=fields,proto3" json:"fields,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *Row) Reset()         { *m = Row{} }
+func (m *Row) String() string { return proto.CompactTextString(m) }
+func (*Row) ProtoMessage()    {}
+func (*Row) Descriptor() ([]byte, []int) {
+	return fileDescriptor_179ad3b13e6397ec, []int{2}
+}
+
+func (m *Row) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Row.Unmarshal(m, b)
+}
+func (m *Row) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Row.Marshal(b, m, deterministic)
+}
+func (m *Row) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Row.Merge(m, src)
+}
+func (m *Row) XXX_Size() int {
+	return xxx_messageInfo_Row.Size(m)
+}
+func (m *Row) XXX_DiscardUnknown() {
+	xxx_messageInfo_Row.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Row proto.InternalMessageInfo
+
+func (m *Row) GetFields() map[string]*Value {
+	if m != nil {
+		return m.Fields
+	}
+	return nil
+}
+
+type GetRowByKeyRequest struct {
+	Family               string   `protobuf:"bytes,1,opt,name=family,proto3" json:"family,omitempty"`
+	Table                string   `protobuf:"bytes,2,opt,name=table,proto3" json:"table,omitempty"`
+	Key                  []*Key   `protobuf:"bytes,3,rep,name=key,proto3" json:"key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetRowByKeyRequest) Reset()         { *m = GetRowByKeyRequest{} }
+func (m *GetRowByKeyRequest) String() string { return proto.CompactTextString(m) }
+func (*GetRowByKeyRequest) ProtoMessage()    {}
+func (*GetRowByKeyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_179ad3b13e6397ec, []int{3}
+}
+
+func (m *GetRowByKeyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetRowByKeyRequest.Unmarshal(m, b)
+}
+func (m *GetRowByKeyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetRowByKeyRequest.Marshal(b, m, deterministic)
+}
+func (m *GetRowByKeyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetRowByKeyRequest.Merge(m, src)
+}
+func (m *GetRowByKeyRequest) XXX_Size() int {
+	return xxx_messageInfo_GetRowByKeyRequest.Size(m)
+}
+func (m *GetRowByKeyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetRowByKeyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetRowByKeyRequest proto.InternalMessageInfo
+
+func (m *GetRowByKeyRequest) GetFamily() string {
+	if m != nil {
+		return m.Family
+	}
+	return ""
+}
+
+func (m *GetRowByKeyRequest) GetTable() string {
+	if m != nil {
+		return m.Table
+	}
+	return ""
+}
+
+func (m *GetRowByKeyRequest) GetKey() []*Key {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+type GetRowByKeyResponse struct {
+	Found                bool     `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
+	Row                  *Row     `protobuf:"bytes,2,opt,name=row,proto3" json:"row,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetRowByKeyResponse) Reset()         { *m = GetRowByKeyResponse{} }
+func (m *GetRowByKeyResponse) String() string { return proto.CompactTextString(m) }
+func (*GetRowByKeyResponse) ProtoMessage()    {}
+func (*GetRowByKeyResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_179ad3b13e6397ec, []int{4}
+}
+
+func (m *GetRowByKeyResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetRowByKeyResponse.Unmarshal(m, b)
+}
+func (m *GetRowByKeyResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetRowByKeyResponse.Marshal(b, m, deterministic)
+}
+func (m *GetRowByKeyResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetRowByKeyResponse.Merge(m, src)
+}
+func (m *GetRowByKeyResponse) XXX_Size() int {
+	return xxx_messageInfo_GetRowByKeyResponse.Size(m)
+}
+func (m *GetRowByKeyResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetRowByKeyResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetRowByKeyResponse proto.InternalMessageInfo
+
+func (m *GetRowByKeyResponse) GetFound() bool {
+	if m != nil {
+		return m.Found
+	}
+	return false
+}
+
+func (m *GetRowByKeyResponse) GetRow() *Row {
+	if m != nil {
+		return m.Row
+	}
+	return nil
+}
+
+type GetRowsByKeyPrefixRequest struct {
+	Family string `protobuf:"bytes,1,opt,name=family,proto3" json:"family,omitempty"`
+	Table  string `protobuf:"bytes,2,opt,name=table,proto3" json:"table,omitempty"`
+	Key    []*Key `protobuf:"bytes,3,rep,name=key,proto3" json:"key,omitempty"`
+	// limit is the maximum number of rows to stream, or 0 for all of them.
+	Limit                int64    `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetRowsByKeyPrefixRequest) Reset()         { *m = GetRowsByKeyPrefixRequest{} }
+func (m *GetRowsByKeyPrefixRequest) String() string { return proto.CompactTextString(m) }
+func (*GetRowsByKeyPrefixRequest) ProtoMessage()    {}
+func (*GetRowsByKeyPrefixRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_179ad3b13e6397ec, []int{5}
+}
+
+func (m *GetRowsByKeyPrefixRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetRowsByKeyPrefixRequest.Unmarshal(m, b)
+}
+func (m *GetRowsByKeyPrefixRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetRowsByKeyPrefixRequest.Marshal(b, m, deterministic)
+}
+func (m *GetRowsByKeyPrefixRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetRowsByKeyPrefixRequest.Merge(m, src)
+}
+func (m *GetRowsByKeyPrefixRequest) XXX_Size() int {
+	return xxx_messageInfo_GetRowsByKeyPrefixRequest.Size(m)
+}
+func (m *GetRowsByKeyPrefixRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetRowsByKeyPrefixRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetRowsByKeyPrefixRequest proto.InternalMessageInfo
+
+func (m *GetRowsByKeyPrefixRequest) GetFamily() string {
+	if m != nil {
+		return m.Family
+	}
+	return ""
+}
+
+func (m *GetRowsByKeyPrefixRequest) GetTable() string {
+	if m != nil {
+		return m.Table
+	}
+	return ""
+}
+
+func (m *GetRowsByKeyPrefixRequest) GetKey() []*Key {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *GetRowsByKeyPrefixRequest) GetLimit() int64 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type GetLedgerLatencyRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetLedgerLatencyRequest) Reset()         { *m = GetLedgerLatencyRequest{} }
+func (m *GetLedgerLatencyRequest) String() string { return proto.CompactTextString(m) }
+func (*GetLedgerLatencyRequest) ProtoMessage()    {}
+func (*GetLedgerLatencyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_179ad3b13e6397ec, []int{6}
+}
+
+func (m *GetLedgerLatencyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetLedgerLatencyRequest.Unmarshal(m, b)
+}
+func (m *GetLedgerLatencyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetLedgerLatencyRequest.Marshal(b, m, deterministic)
+}
+func (m *GetLedgerLatencyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetLedgerLatencyRequest.Merge(m, src)
+}
+func (m *GetLedgerLatencyRequest) XXX_Size() int {
+	return xxx_messageInfo_GetLedgerLatencyRequest.Size(m)
+}
+func (m *GetLedgerLatencyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetLedgerLatencyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetLedgerLatencyRequest proto.InternalMessageInfo
+
+type GetLedgerLatencyResponse struct {
+	Seconds              float64  `protobuf:"fixed64,1,opt,name=seconds,proto3" json:"seconds,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetLedgerLatencyResponse) Reset()         { *m = GetLedgerLatencyResponse{} }
+func (m *GetLedgerLatencyResponse) String() string { return proto.CompactTextString(m) }
+func (*GetLedgerLatencyResponse) ProtoMessage()    {}
+func (*GetLedgerLatencyResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_179ad3b13e6397ec, []int{7}
+}
+
+func (m *GetLedgerLatencyResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetLedgerLatencyResponse.Unmarshal(m, b)
+}
+func (m *GetLedgerLatencyResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetLedgerLatencyResponse.Marshal(b, m, deterministic)
+}
+func (m *GetLedgerLatencyResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetLedgerLatencyResponse.Merge(m, src)
+}
+func (m *GetLedgerLatencyResponse) XXX_Size() int {
+	return xxx_messageInfo_GetLedgerLatencyResponse.Size(m)
+}
+func (m *GetLedgerLatencyResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetLedgerLatencyResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetLedgerLatencyResponse proto.InternalMessageInfo
+
+func (m *GetLedgerLatencyResponse) GetSeconds() float64 {
+	if m != nil {
+		return m.Seconds
+	}
+	return 0
+}
+
+type HealthcheckRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HealthcheckRequest) Reset()         { *m = HealthcheckRequest{} }
+func (m *HealthcheckRequest) String() string { return proto.CompactTextString(m) }
+func (*HealthcheckRequest) ProtoMessage()    {}
+func (*HealthcheckRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_179ad3b13e6397ec, []int{8}
+}
+
+func (m *HealthcheckRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HealthcheckRequest.Unmarshal(m, b)
+}
+func (m *HealthcheckRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HealthcheckRequest.Marshal(b, m, deterministic)
+}
+func (m *HealthcheckRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HealthcheckRequest.Merge(m, src)
+}
+func (m *HealthcheckRequest) XXX_Size() int {
+	return xxx_messageInfo_HealthcheckRequest.Size(m)
+}
+func (m *HealthcheckRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_HealthcheckRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HealthcheckRequest proto.InternalMessageInfo
+
+type HealthcheckResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HealthcheckResponse) Reset()         { *m = HealthcheckResponse{} }
+func (m *HealthcheckResponse) String() string { return proto.CompactTextString(m) }
+func (*HealthcheckResponse) ProtoMessage()    {}
+func (*HealthcheckResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_179ad3b13e6397ec, []int{9}
+}
+
+func (m *HealthcheckResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HealthcheckResponse.Unmarshal(m, b)
+}
+func (m *HealthcheckResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HealthcheckResponse.Marshal(b, m, deterministic)
+}
+func (m *HealthcheckResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HealthcheckResponse.Merge(m, src)
+}
+func (m *HealthcheckResponse) XXX_Size() int {
+	return xxx_messageInfo_HealthcheckResponse.Size(m)
+}
+func (m *HealthcheckResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_HealthcheckResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HealthcheckResponse proto.InternalMessageInfo
+
+func init() {
+	proto.RegisterType((*Key)(nil), "ctlstore.sidecar.Key")
+	proto.RegisterType((*Value)(nil), "ctlstore.sidecar.Value")
+	proto.RegisterType((*Row)(nil), "ctlstore.sidecar.Row")
+	proto.RegisterMapType((map[string]*Value)(nil), "ctlstore.sidecar.Row.FieldsEntry")
+	proto.RegisterType((*GetRowByKeyRequest)(nil), "ctlstore.sidecar.GetRowByKeyRequest")
+	proto.RegisterType((*GetRowByKeyResponse)(nil), "ctlstore.sidecar.GetRowByKeyResponse")
+	proto.RegisterType((*GetRowsByKeyPrefixRequest)(nil), "ctlstore.sidecar.GetRowsByKeyPrefixRequest")
+	proto.RegisterType((*GetLedgerLatencyRequest)(nil), "ctlstore.sidecar.GetLedgerLatencyRequest")
+	proto.RegisterType((*GetLedgerLatencyResponse)(nil), "ctlstore.sidecar.GetLedgerLatencyResponse")
+	proto.RegisterType((*HealthcheckRequest)(nil), "ctlstore.sidecar.HealthcheckRequest")
+	proto.RegisterType((*HealthcheckResponse)(nil), "ctlstore.sidecar.HealthcheckResponse")
+}
+
+func init() { proto.RegisterFile("sidecar.proto", fileDescriptor_179ad3b13e6397ec) }
+
+var fileDescriptor_179ad3b13e6397ec = []byte{
+	// 511 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x54, 0x41, 0x6f, 0xd3, 0x30,
+	0x18, 0xad, 0xe7, 0xb5, 0x5d, 0xbf, 0x80, 0x54, 0x79, 0x2d, 0xcb, 0x72, 0x0a, 0x11, 0x13, 0x01,
+	0x44, 0x84, 0x0a, 0x07, 0xe0, 0x58, 0x09, 0x16, 0x69, 0x3b, 0x20, 0x83, 0x76, 0xe8, 0x2d, 0x49,
+	0x9d, 0xcd, 0x6a, 0x16, 0x97, 0xc4, 0xa5, 0xe4, 0x0f, 0xa0, 0xfd, 0x00, 0x7e, 0x30, 0xb2, 0x9d,
+	0x42, 0xbb, 0x04, 0x0d, 0x0e, 0xbb, 0xe5, 0xd9, 0xcf, 0xef, 0x7d, 0xef, 0xd9, 0x0a, 0x3c, 0x2c,
+	0xf9, 0x9c, 0x25, 0x51, 0x11, 0x2c, 0x0b, 0x21, 0x05, 0x19, 0x26, 0x32, 0x2b, 0xa5, 0x28, 0x58,
+	0x50, 0xaf, 0x7b, 0x17, 0x80, 0xcf, 0x58, 0x45, 0x6c, 0xe8, 0x95, 0xb2, 0xe0, 0xf9, 0xa5, 0x8d,
+	0x5c, 0xe4, 0x0f, 0xc2, 0x0e, 0xad, 0x31, 0x21, 0x80, 0x79, 0x2e, 0xed, 0x3d, 0x17, 0xf9, 0x38,
+	0xec, 0x50, 0x05, 0x14, 0x3b, 0xe6, 0x79, 0x54, 0x54, 0x36, 0x76, 0x91, 0xff, 0x40, 0xb1, 0x0d,
+	0x9e, 0xf6, 0xa1, 0xfb, 0x2d, 0xca, 0x56, 0xcc, 0xfb, 0x81, 0xa0, 0x7b, 0xa1, 0xbe, 0xfe, 0x53,
+	0xfa, 0x11, 0x74, 0xd3, 0x4c, 0x44, 0x52, 0x2b, 0xa3, 0xb0, 0x43, 0x0d, 0xdc, 0xb2, 0xdc, 0xdf,
+	0xb5, 0x24, 0x23, 0xd8, 0x8f, 0x85, 0xc8, 0xec, 0xae, 0x8b, 0xfc, 0x83, 0xb0, 0x43, 0x35, 0xfa,
+	0x33, 0xc8, 0x4f, 0x04, 0x98, 0x8a, 0x35, 0x79, 0x07, 0xbd, 0x94, 0xb3, 0x6c, 0x5e, 0xda, 0xc8,
+	0xc5, 0xbe, 0x35, 0x79, 0x1c, 0xdc, 0xee, 0x22, 0xa0, 0x62, 0x1d, 0x7c, 0xd4, 0x9c, 0x0f, 0xb9,
+	0x2c, 0x2a, 0x5a, 0x1f, 0x70, 0x28, 0x58, 0x5b, 0xcb, 0x64, 0x08, 0x78, 0xc1, 0x2a, 0x93, 0x86,
+	0xaa, 0x4f, 0xf2, 0xb2, 0x36, 0xd3, 0x51, 0xac, 0xc9, 0x51, 0x53, 0x5a, 0x57, 0x41, 0x0d, 0xeb,
+	0xfd, 0xde, 0x5b, 0xe4, 0x2d, 0x80, 0x9c, 0x32, 0x49, 0xc5, 0x7a, 0x5a, 0x9d, 0xb1, 0x8a, 0xb2,
+	0xaf, 0x2b, 0x56, 0xaa, 0xf4, 0xbd, 0x34, 0xba, 0xe6, 0xd9, 0x46, 0xbd, 0x46, 0x64, 0x04, 0x5d,
+	0x19, 0xc5, 0x99, 0x31, 0x18, 0x50, 0x03, 0xc8, 0x53, 0x33, 0x08, 0xd6, 0x79, 0xc6, 0x4d, 0x53,
+	0x25, 0xac, 0x18, 0xde, 0x17, 0x38, 0xdc, 0x31, 0x2b, 0x97, 0x22, 0x2f, 0x99, 0x52, 0x4d, 0xc5,
+	0x2a, 0x9f, 0x6b, 0xb3, 0x03, 0x6a, 0x80, 0x52, 0x2d, 0xc4, 0xba, 0x8e, 0x32, 0x6e, 0x6d, 0x89,
+	0x2a, 0x86, 0x77, 0x83, 0xe0, 0xd8, 0xc8, 0x96, 0x5a, 0xf7, 0x53, 0xc1, 0x52, 0xfe, 0xfd, 0x7e,
+	0xa3, 0xa8, 0xe3, 0x19, 0xbf, 0xe6, 0x52, 0x3f, 0x03, 0x4c, 0x0d, 0xf0, 0x8e, 0xe1, 0xe8, 0x94,
+	0xc9, 0x73, 0x36, 0xbf, 0x64, 0xc5, 0x79, 0x24, 0x59, 0x9e, 0x6c, 0x2a, 0xf5, 0xde, 0x80, 0xdd,
+	0xdc, 0xaa, 0x0b, 0xb0, 0xa1, 0x5f, 0xb2, 0x44, 0xe4, 0xfa, 0x51, 0x20, 0x1f, 0xd1, 0x0d, 0xf4,
+	0x46, 0x40, 0x42, 0x16, 0x65, 0xf2, 0x2a, 0xb9, 0x62, 0xc9, 0x62, 0xa3, 0x35, 0x86, 0xc3, 0x9d,
+	0x55, 0x23, 0x33, 0xb9, 0xc1, 0xd0, 0xff, 0x6c, 0x06, 0x25, 0x33, 0xb0, 0xb6, 0xaa, 0x26, 0x4f,
+	0x9a, 0x51, 0x9a, 0xd7, 0xee, 0x9c, 0xdc, 0xc1, 0xaa, 0xc7, 0x9d, 0x01, 0x69, 0xf6, 0x4d, 0x5e,
+	0xfc, 0xed, 0x70, 0xcb, 0xad, 0x38, 0xed, 0xf7, 0xf9, 0x0a, 0x11, 0x0e, 0xc3, 0xdb, 0x35, 0x91,
+	0x67, 0xad, 0xca, 0x6d, 0x2d, 0x3b, 0xcf, 0xff, 0x85, 0xfa, 0x3b, 0x86, 0xb5, 0xd5, 0x62, 0x5b,
+	0x45, 0xcd, 0xea, 0x9d, 0x93, 0x3b, 0x58, 0x46, 0x7b, 0x6a, 0xcd, 0x06, 0xf5, 0xf6, 0x32, 0x8e,
+	0x7b, 0xfa, 0xa7, 0xf7, 0xfa, 0xd7, 0x00, 0xfd, 0x03, 0x8c, 0xe7, 0x05, 0x05, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// SidecarClient is the client API for Sidecar service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type SidecarClient interface {
+	// GetRowByKey reads the row of a table with the given primary key.
+	GetRowByKey(ctx context.Context, in *GetRowByKeyRequest, opts ...grpc.CallOption) (*GetRowByKeyResponse, error)
+	// GetRowsByKeyPrefix streams the rows of a table whose primary keys start
+	// with the given key segments, in primary key order.
+	GetRowsByKeyPrefix(ctx context.Context, in *GetRowsByKeyPrefixRequest, opts ...grpc.CallOption) (Sidecar_GetRowsByKeyPrefixClient, error)
+	// GetLedgerLatency reads how far behind the ledger the LDB is.
+	GetLedgerLatency(ctx context.Context, in *GetLedgerLatencyRequest, opts ...grpc.CallOption) (*GetLedgerLatencyResponse, error)
+	// Healthcheck fails if the LDB has never received a ledger update.
+	Healthcheck(ctx context.Context, in *HealthcheckRequest, opts ...grpc.CallOption) (*HealthcheckResponse, error)
+}
+
+type sidecarClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewSidecarClient(cc *grpc.ClientConn) SidecarClient {
+	return &sidecarClient{cc}
+}
+
+func (c *sidecarClient) GetRowByKey(ctx context.Context, in *GetRowByKeyRequest, opts ...grpc.CallOption) (*GetRowByKeyResponse, error) {
+	out := new(GetRowByKeyResponse)
+	err := c.cc.Invoke(ctx, "/ctlstore.sidecar.Sidecar/GetRowByKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sidecarClient) GetRowsByKeyPrefix(ctx context.Context, in *GetRowsByKeyPrefixRequest, opts ...grpc.CallOption) (Sidecar_GetRowsByKeyPrefixClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Sidecar_serviceDesc.Streams[0], "/ctlstore.sidecar.Sidecar/GetRowsByKeyPrefix", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sidecarGetRowsByKeyPrefixClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Sidecar_GetRowsByKeyPrefixClient interface {
+	Recv() (*Row, error)
+	grpc.ClientStream
+}
+
+type sidecarGetRowsByKeyPrefixClient struct {
+	grpc.ClientStream
+}
+
+func (x *sidecarGetRowsByKeyPrefixClient) Recv() (*Row, error) {
+	m := new(Row)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *sidecarClient) GetLedgerLatency(ctx context.Context, in *GetLedgerLatencyRequest, opts ...grpc.CallOption) (*GetLedgerLatencyResponse, error) {
+	out := new(GetLedgerLatencyResponse)
+	err := c.cc.Invoke(ctx, "/ctlstore.sidecar.Sidecar/GetLedgerLatency", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sidecarClient) Healthcheck(ctx context.Context, in *HealthcheckRequest, opts ...grpc.CallOption) (*HealthcheckResponse, error) {
+	out := new(HealthcheckResponse)
+	err := c.cc.Invoke(ctx, "/ctlstore.sidecar.Sidecar/Healthcheck", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SidecarServer is the server API for Sidecar service.
+type SidecarServer interface {
+	// GetRowByKey reads the row of a table with the given primary key.
+	GetRowByKey(context.Context, *GetRowByKeyRequest) (*GetRowByKeyResponse, error)
+	// GetRowsByKeyPrefix streams the rows of a table whose primary keys start
+	// with the given key segments, in primary key order.
+	GetRowsByKeyPrefix(*GetRowsByKeyPrefixRequest, Sidecar_GetRowsByKeyPrefixServer) error
+	// GetLedgerLatency reads how far behind the ledger the LDB is.
+	GetLedgerLatency(context.Context, *GetLedgerLatencyRequest) (*GetLedgerLatencyResponse, error)
+	// Healthcheck fails if the LDB has never received a ledger update.
+	Healthcheck(context.Context, *HealthcheckRequest) (*HealthcheckResponse, error)
+}
+
+func RegisterSidecarServer(s *grpc.Server, srv SidecarServer) {
+	s.RegisterService(&_Sidecar_serviceDesc, srv)
+}
+
+func _Sidecar_GetRowByKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRowByKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SidecarServer).GetRowByKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ctlstore.sidecar.Sidecar/GetRowByKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SidecarServer).GetRowByKey(ctx, req.(*GetRowByKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sidecar_GetRowsByKeyPrefix_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetRowsByKeyPrefixRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SidecarServer).GetRowsByKeyPrefix(m, &sidecarGetRowsByKeyPrefixServer{stream})
+}
+
+type Sidecar_GetRowsByKeyPrefixServer interface {
+	Send(*Row) error
+	grpc.ServerStream
+}
+
+type sidecarGetRowsByKeyPrefixServer struct {
+	grpc.ServerStream
+}
+
+func (x *sidecarGetRowsByKeyPrefixServer) Send(m *Row) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Sidecar_GetLedgerLatency_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLedgerLatencyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SidecarServer).GetLedgerLatency(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ctlstore.sidecar.Sidecar/GetLedgerLatency",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SidecarServer).GetLedgerLatency(ctx, req.(*GetLedgerLatencyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sidecar_Healthcheck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HealthcheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SidecarServer).Healthcheck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ctlstore.sidecar.Sidecar/Healthcheck",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SidecarServer).Healthcheck(ctx, req.(*HealthcheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Sidecar_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "ctlstore.sidecar.Sidecar",
+	HandlerType: (*SidecarServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetRowByKey",
+			Handler:    _Sidecar_GetRowByKey_Handler,
+		},
+		{
+			MethodName: "GetLedgerLatency",
+			Handler:    _Sidecar_GetLedgerLatency_Handler,
+		},
+		{
+			MethodName: "Healthcheck",
+			Handler:    _Sidecar_Healthcheck_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetRowsByKeyPrefix",
+			Handler:       _Sidecar_GetRowsByKeyPrefix_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "sidecar.proto",
+}
@@ -178,6 +178,42 @@ func (t *MetaTable) AddColumnDDL(fn schema.FieldName, ft schema.FieldType) (stri
 	return ddl, nil
 }
 
+// Returns the DDL to create a secondary index over the supplied fields, which
+// must be fields of this table that are able to be keys.
+func (t *MetaTable) CreateIndexDDL(idxName schema.IndexName, fns []schema.FieldName, unique bool) (string, error) {
+	if len(fns) == 0 {
+		return "", errors.New("index must have at least one field")
+	}
+
+	for _, fn := range fns {
+		ft, found := t.fieldTypeByName(fn)
+		if !found {
+			return "", fmt.Errorf("Index field '%s' not specified as a field", fn.Name)
+		}
+		if !ft.CanBeKey() {
+			typeName := schema.FieldTypeStringsByFieldType[ft]
+			return "", fmt.Errorf("Fields of type '%s' cannot be an index field", typeName)
+		}
+	}
+
+	createDDL := "CREATE INDEX"
+	if unique {
+		createDDL = "CREATE UNIQUE INDEX"
+	}
+
+	tableName := schema.LDBTableName(t.FamilyName, t.TableName)
+	indexName := schema.LDBIndexName(t.FamilyName, t.TableName, idxName)
+	fields := strings.Join(dblquoteStrings(schema.StringifyFieldNames(fns)), ",")
+	ddl := SqlSprintf(
+		"$1 $2 ON $3 ($4)",
+		createDDL,
+		indexName,
+		tableName,
+		fields)
+
+	return ddl, nil
+}
+
 // Returns the names of the fields in this table in order
 func (t *MetaTable) FieldNames() []schema.FieldName {
 	fns := []schema.FieldName{}
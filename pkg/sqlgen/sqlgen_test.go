@@ -158,6 +158,85 @@ func TestMetaTableAddColumnDDL(t *testing.T) {
 	}
 }
 
+func TestMetaTableCreateIndexDDL(t *testing.T) {
+	famName, _ := schema.NewFamilyName("family1")
+	tblName, _ := schema.NewTableName("table1")
+	idxName, _ := schema.NewIndexName("by_email")
+	tbl := &MetaTable{
+		DriverName: "sqlite3",
+		FamilyName: famName,
+		TableName:  tblName,
+		Fields: []schema.NamedFieldType{
+			{Name: schema.FieldName{Name: "field1"}, FieldType: schema.FTString},
+			{Name: schema.FieldName{Name: "field2"}, FieldType: schema.FTInteger},
+			{Name: schema.FieldName{Name: "field3"}, FieldType: schema.FTText},
+		},
+		KeyFields: schema.PrimaryKey{Fields: []schema.FieldName{{Name: "field1"}}},
+	}
+
+	for _, test := range []struct {
+		desc   string
+		fields []schema.FieldName
+		unique bool
+		want   string
+		err    string
+	}{
+		{
+			desc:   "single field",
+			fields: []schema.FieldName{{Name: "field2"}},
+			want:   `CREATE INDEX family1___table1___by_email ON family1___table1 ("field2")`,
+		},
+		{
+			desc:   "unique composite",
+			fields: []schema.FieldName{{Name: "field2"}, {Name: "field1"}},
+			unique: true,
+			want:   `CREATE UNIQUE INDEX family1___table1___by_email ON family1___table1 ("field2","field1")`,
+		},
+		{
+			desc: "no fields",
+			err:  "index must have at least one field",
+		},
+		{
+			desc:   "unknown field",
+			fields: []schema.FieldName{{Name: "field4"}},
+			err:    "Index field 'field4' not specified as a field",
+		},
+		{
+			desc:   "unindexable field",
+			fields: []schema.FieldName{{Name: "field3"}},
+			err:    "Fields of type 'text' cannot be an index field",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := tbl.CreateIndexDDL(idxName, test.fields, test.unique)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("Expected error '%s', got: %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error calling CreateIndexDDL method: %v", err)
+			}
+			if want := test.want; want != got {
+				t.Errorf("Expected SQL to be '%s', got: '%s'", want, got)
+			}
+
+			db, err := sql.Open("sqlite3", ":memory:")
+			if err != nil {
+				t.Fatalf("Unexpected error opening SQLite3 DB: %v", err)
+			}
+			defer db.Close()
+
+			ddl := `CREATE TABLE family1___table1 ("field1" VARCHAR PRIMARY KEY, "field2" INTEGER, "field3" TEXT); ` + got
+			_, err = db.Exec(ddl)
+			if err != nil {
+				t.Errorf("Error executing generated SQL statement: %v", err)
+			}
+		})
+	}
+}
+
 func TestMetaTableUpsertDML(t *testing.T) {
 	famName, _ := schema.NewFamilyName("family1")
 	tblName, _ := schema.NewTableName("table1")
@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"reflect"
 
 	"github.com/pkg/errors"
 	"github.com/segmentio/ctlstore/pkg/scanfunc"
@@ -20,6 +21,15 @@ type (
 		OldRow       []interface{}
 		NewRow       []interface{}
 	}
+	// RowChange is the effect of a change on a single row. Op is one of
+	// sqlite3.SQLITE_INSERT, SQLITE_UPDATE, or SQLITE_DELETE, and the rows
+	// are keyed by column name.
+	RowChange struct {
+		Op     int
+		Key    []interface{}
+		OldRow map[string]interface{}
+		NewRow map[string]interface{}
+	}
 	// pkAndMeta is a primary key value with name and type metadata to boot
 	pkAndMeta struct {
 		Name  string      `json:"name"`
@@ -76,53 +86,15 @@ func RegisterSQLiteWatch(dbName string, buffer *SQLChangeBuffer) error {
 // Returns the primary key values of the impacted rows by looking up the
 // metadata in the passed db.
 func (c *SQLiteWatchChange) ExtractKeys(db *sql.DB) ([][]interface{}, error) {
-	// guard this edge just in case!
-	if c.DatabaseName != "main" {
-		return nil, errors.New("Only meant to be used on main database")
-	}
-
-	// go straight for the sqlite db info instead of going through the dbinfo
-	// package, which lets us avoid importing a mysql dependency.
-	dbInfo := SqliteDBInfo{Db: db}
-	colInfos, err := dbInfo.GetColumnInfo(context.Background(), []string{c.TableName})
+	colInfos, err := c.columnInfo(db)
 	if err != nil {
 		return nil, err
 	}
 
-	exKey := func(row []interface{}) ([]interface{}, error) {
-		key := []interface{}{}
-		for _, colInfo := range colInfos {
-			if colInfo.IsPrimaryKey {
-				if colInfo.Index >= len(row) {
-					// Should never happen, but yeah.
-					return nil, errors.New("column info couldn't be matched to row")
-				}
-				// use a placeholder to scan the value of the column. it will use the
-				// column metadata to correctly convert byte slices into strings
-				// where appropriate.
-				ph := scanfunc.Placeholder{
-					Col: schema.DBColumnMeta{
-						Name: colInfo.ColumnName,
-						Type: colInfo.DataType,
-					},
-				}
-				if err := ph.Scan(row[colInfo.Index]); err != nil {
-					return nil, errors.Wrap(err, "scan key value column")
-				}
-				key = append(key, pkAndMeta{
-					Name:  colInfo.ColumnName,
-					Type:  colInfo.DataType,
-					Value: ph.Val,
-				})
-			}
-		}
-		return key, nil
-	}
-
 	keys := [][]interface{}{}
 	for _, row := range [][]interface{}{c.OldRow, c.NewRow} {
 		if row != nil {
-			key, err := exKey(row)
+			key, _, err := extractRow(colInfos, row, false)
 			if err != nil {
 				return nil, err
 			}
@@ -133,3 +105,113 @@ func (c *SQLiteWatchChange) ExtractKeys(db *sql.DB) ([][]interface{}, error) {
 	}
 	return keys, nil
 }
+
+// Returns the changes made to each impacted row, including the values of
+// the row before and after the change, by looking up the metadata in the
+// passed db. An update which changes the primary key of a row is returned
+// as a delete of the old key followed by an insert of the new key.
+func (c *SQLiteWatchChange) ExtractRowChanges(db *sql.DB) ([]RowChange, error) {
+	colInfos, err := c.columnInfo(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var oldKey, newKey []interface{}
+	var oldVals, newVals map[string]interface{}
+	if c.OldRow != nil {
+		oldKey, oldVals, err = extractRow(colInfos, c.OldRow, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.NewRow != nil {
+		newKey, newVals, err = extractRow(colInfos, c.NewRow, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	changes := []RowChange{}
+	switch {
+	case len(oldKey) == 0 && len(newKey) == 0:
+		// not a table with a primary key
+	case c.Op == sqlite3.SQLITE_UPDATE && reflect.DeepEqual(oldKey, newKey):
+		changes = append(changes, RowChange{
+			Op:     sqlite3.SQLITE_UPDATE,
+			Key:    newKey,
+			OldRow: oldVals,
+			NewRow: newVals,
+		})
+	default:
+		if c.OldRow != nil {
+			changes = append(changes, RowChange{
+				Op:     sqlite3.SQLITE_DELETE,
+				Key:    oldKey,
+				OldRow: oldVals,
+			})
+		}
+		if c.NewRow != nil {
+			changes = append(changes, RowChange{
+				Op:     sqlite3.SQLITE_INSERT,
+				Key:    newKey,
+				NewRow: newVals,
+			})
+		}
+	}
+	return changes, nil
+}
+
+func (c *SQLiteWatchChange) columnInfo(db *sql.DB) ([]schema.DBColumnInfo, error) {
+	// guard this edge just in case!
+	if c.DatabaseName != "main" {
+		return nil, errors.New("Only meant to be used on main database")
+	}
+
+	// go straight for the sqlite db info instead of going through the dbinfo
+	// package, which lets us avoid importing a mysql dependency.
+	dbInfo := SqliteDBInfo{Db: db}
+	return dbInfo.GetColumnInfo(context.Background(), []string{c.TableName})
+}
+
+// extractRow returns the primary key of the row, along with the value of
+// each column keyed by column name if values is set. Otherwise only the
+// primary key columns are converted.
+func extractRow(colInfos []schema.DBColumnInfo, row []interface{}, values bool) ([]interface{}, map[string]interface{}, error) {
+	key := []interface{}{}
+	var vals map[string]interface{}
+	if values {
+		vals = make(map[string]interface{}, len(colInfos))
+	}
+	for _, colInfo := range colInfos {
+		if !values && !colInfo.IsPrimaryKey {
+			continue
+		}
+		if colInfo.Index >= len(row) {
+			// Should never happen, but yeah.
+			return nil, nil, errors.New("column info couldn't be matched to row")
+		}
+		// use a placeholder to scan the value of the column. it will use the
+		// column metadata to correctly convert byte slices into strings
+		// where appropriate.
+		ph := scanfunc.Placeholder{
+			Col: schema.DBColumnMeta{
+				Name: colInfo.ColumnName,
+				Type: colInfo.DataType,
+			},
+		}
+		if err := ph.Scan(row[colInfo.Index]); err != nil {
+			return nil, nil, errors.Wrap(err, "scan column value")
+		}
+		if values {
+			vals[colInfo.ColumnName] = ph.Val
+		}
+		if colInfo.IsPrimaryKey {
+			key = append(key, pkAndMeta{
+				Name:  colInfo.ColumnName,
+				Type:  colInfo.DataType,
+				Value: ph.Val,
+			})
+		}
+	}
+	return key, vals, nil
+}
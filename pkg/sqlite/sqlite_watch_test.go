@@ -196,3 +196,119 @@ func TestSQLiteWatchChangeExtractKeys(t *testing.T) {
 		})
 	}
 }
+
+func TestSQLiteWatchChangeExtractRowChanges(t *testing.T) {
+	setup := `
+		CREATE TABLE table1 (
+			col1 INTEGER,
+			col2 VARCHAR,
+			col3 BLOB,
+			PRIMARY KEY(col1)
+		);
+	`
+	key := func(v int64) []interface{} {
+		return []interface{}{pkAndMeta{Name: "col1", Type: "INTEGER", Value: v}}
+	}
+	suite := []struct {
+		desc          string
+		change        SQLiteWatchChange
+		expectChanges []RowChange
+	}{
+		{
+			desc: "Insert",
+			change: SQLiteWatchChange{
+				Op:           sqlite3.SQLITE_INSERT,
+				DatabaseName: "main",
+				TableName:    "table1",
+				NewRow:       []interface{}{int64(1), []byte("foo"), []byte{0x01}},
+			},
+			expectChanges: []RowChange{
+				{
+					Op:     sqlite3.SQLITE_INSERT,
+					Key:    key(1),
+					NewRow: map[string]interface{}{"col1": int64(1), "col2": "foo", "col3": []byte{0x01}},
+				},
+			},
+		},
+		{
+			desc: "Update with Same Key",
+			change: SQLiteWatchChange{
+				Op:           sqlite3.SQLITE_UPDATE,
+				DatabaseName: "main",
+				TableName:    "table1",
+				OldRow:       []interface{}{int64(1), "foo", nil},
+				NewRow:       []interface{}{int64(1), "bar", nil},
+			},
+			expectChanges: []RowChange{
+				{
+					Op:     sqlite3.SQLITE_UPDATE,
+					Key:    key(1),
+					OldRow: map[string]interface{}{"col1": int64(1), "col2": "foo", "col3": nil},
+					NewRow: map[string]interface{}{"col1": int64(1), "col2": "bar", "col3": nil},
+				},
+			},
+		},
+		{
+			desc: "Update with Different Key",
+			change: SQLiteWatchChange{
+				Op:           sqlite3.SQLITE_UPDATE,
+				DatabaseName: "main",
+				TableName:    "table1",
+				OldRow:       []interface{}{int64(1), "foo", nil},
+				NewRow:       []interface{}{int64(2), "foo", nil},
+			},
+			expectChanges: []RowChange{
+				{
+					Op:     sqlite3.SQLITE_DELETE,
+					Key:    key(1),
+					OldRow: map[string]interface{}{"col1": int64(1), "col2": "foo", "col3": nil},
+				},
+				{
+					Op:     sqlite3.SQLITE_INSERT,
+					Key:    key(2),
+					NewRow: map[string]interface{}{"col1": int64(2), "col2": "foo", "col3": nil},
+				},
+			},
+		},
+		{
+			desc: "Delete",
+			change: SQLiteWatchChange{
+				Op:           sqlite3.SQLITE_DELETE,
+				DatabaseName: "main",
+				TableName:    "table1",
+				OldRow:       []interface{}{int64(1), "foo", nil},
+			},
+			expectChanges: []RowChange{
+				{
+					Op:     sqlite3.SQLITE_DELETE,
+					Key:    key(1),
+					OldRow: map[string]interface{}{"col1": int64(1), "col2": "foo", "col3": nil},
+				},
+			},
+		},
+	}
+
+	for _, testCase := range suite {
+		t.Run(testCase.desc, func(t *testing.T) {
+			db, err := sql.Open("sqlite3", ":memory:")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer db.Close()
+
+			_, err = db.Exec(setup)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			changes, err := testCase.change.ExtractRowChanges(db)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(testCase.expectChanges, changes); diff != "" {
+				t.Errorf("Changes differ\n%v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package ctlstore
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/ctlstore/pkg/globalstats"
+	"github.com/segmentio/ctlstore/pkg/ldb"
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/segmentio/errors-go"
+)
+
+// ReaderTx reads from a single snapshot of the LDB. It's only valid until
+// the callback passed to LDBReader.ReadTx returns.
+type ReaderTx interface {
+	// Sequence returns the sequence of the last ledger statement which is
+	// reflected in the snapshot.
+	Sequence() schema.DMLSequence
+	// GetRowByKey is LDBReader.GetRowByKey against the snapshot.
+	GetRowByKey(ctx context.Context, out interface{}, familyName string, tableName string, key ...interface{}) (found bool, err error)
+	// GetRowsByKeyPrefix is LDBReader.GetRowsByKeyPrefix against the
+	// snapshot. The Rows must be closed before the callback returns.
+	GetRowsByKeyPrefix(ctx context.Context, familyName string, tableName string, key ...interface{}) (*Rows, error)
+}
+
+type readerTx struct {
+	reader *LDBReader
+	tx     *sql.Tx
+	seq    schema.DMLSequence
+}
+
+// ReadTx calls fn with a ReaderTx whose reads all come from the same
+// snapshot of the LDB, so that related reads can't land on either side of
+// a reflector commit. The snapshot is held until fn returns, which keeps
+// the reflector from checkpointing past it, so fn should return promptly.
+//
+// The error returned by fn is returned as is.
+func (reader *LDBReader) ReadTx(ctx context.Context, fn func(tx ReaderTx) error) error {
+	start := time.Now()
+	defer func() {
+		globalstats.Observe("read_tx", time.Now().Sub(start))
+	}()
+
+	reader.reopenIfReplaced()
+	reader.mu.RLock()
+	// keeps the LDB from being reopened out from under the transaction,
+	// see reopenIfReplaced
+	atomic.AddInt32(&reader.openTxs, 1)
+	defer atomic.AddInt32(&reader.openTxs, -1)
+	tx, err := reader.Db.BeginTx(ctx, nil)
+	reader.mu.RUnlock()
+	if err != nil {
+		return errors.Wrap(err, "begin read tx")
+	}
+	// nothing was written, so there's nothing to commit
+	defer tx.Rollback()
+
+	// SQLite doesn't take the snapshot until the transaction first reads,
+	// so this also pins the snapshot that fn will see.
+	seq, err := ldb.FetchSeqFromLdbTx(ctx, tx)
+	if err != nil {
+		return errors.Wrap(err, "get read tx sequence")
+	}
+	return fn(&readerTx{reader: reader, tx: tx, seq: seq})
+}
+
+func (t *readerTx) Sequence() schema.DMLSequence {
+	return t.seq
+}
+
+func (t *readerTx) GetRowByKey(ctx context.Context, out interface{}, familyName string, tableName string, key ...interface{}) (bool, error) {
+	return t.reader.getRowByKey(ctx, t.tx, out, familyName, tableName, key...)
+}
+
+func (t *readerTx) GetRowsByKeyPrefix(ctx context.Context, familyName string, tableName string, key ...interface{}) (*Rows, error) {
+	return t.reader.getRowsByKeyPrefix(ctx, t.tx, familyName, tableName, key...)
+}
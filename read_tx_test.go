@@ -0,0 +1,90 @@
+package ctlstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/ctlstore/pkg/ldb"
+	"github.com/segmentio/ctlstore/pkg/ldbwriter"
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTx(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	dbPath, teardown := ldb.NewLDBTmpPath(t)
+	defer teardown()
+	db, err := ldb.OpenLDB(dbPath, "rwc")
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, ldb.EnsureLdbInitialized(ctx, db))
+
+	writer := ldbwriter.SqlLdbWriter{Db: db}
+	apply := func(seq schema.DMLSequence, statement string) {
+		err := writer.ApplyDMLStatement(ctx, schema.DMLStatement{
+			Sequence:  seq,
+			Statement: statement,
+			Timestamp: time.Now(),
+		})
+		require.NoError(t, err)
+	}
+	apply(1, "CREATE TABLE foo___bar (key VARCHAR PRIMARY KEY, value VARCHAR)")
+	apply(2, "INSERT INTO foo___bar VALUES ('a', 'one')")
+
+	reader, err := ReaderForPath(dbPath)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	readAll := func(rows *Rows, err error) []testKVStruct {
+		require.NoError(t, err)
+		defer rows.Close()
+		var res []testKVStruct
+		for rows.Next() {
+			var row testKVStruct
+			require.NoError(t, rows.Scan(&row))
+			res = append(res, row)
+		}
+		require.NoError(t, rows.Err())
+		return res
+	}
+
+	err = reader.ReadTx(ctx, func(tx ReaderTx) error {
+		require.EqualValues(t, 2, tx.Sequence())
+
+		// a commit in the middle of the transaction isn't visible to it
+		apply(3, "REPLACE INTO foo___bar VALUES ('a', 'two')")
+		apply(4, "INSERT INTO foo___bar VALUES ('b', 'two')")
+
+		var row testKVStruct
+		found, err := tx.GetRowByKey(ctx, &row, "foo", "bar", "a")
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, testKVStruct{"a", "one"}, row)
+		require.Equal(t, []testKVStruct{{"a", "one"}}, readAll(tx.GetRowsByKeyPrefix(ctx, "foo", "bar")))
+
+		// but it is visible to reads outside of it
+		found, err = reader.GetRowByKey(ctx, &row, "foo", "bar", "a")
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, testKVStruct{"a", "two"}, row)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = reader.ReadTx(ctx, func(tx ReaderTx) error {
+		require.EqualValues(t, 4, tx.Sequence())
+		require.Equal(t, []testKVStruct{{"a", "two"}, {"b", "two"}}, readAll(tx.GetRowsByKeyPrefix(ctx, "foo", "bar")))
+		return nil
+	})
+	require.NoError(t, err)
+
+	expected := errors.New("failed")
+	err = reader.ReadTx(ctx, func(tx ReaderTx) error {
+		return expected
+	})
+	require.Equal(t, expected, err)
+}
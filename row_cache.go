@@ -0,0 +1,452 @@
+package ctlstore
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/ctlstore/pkg/event"
+	"github.com/segmentio/ctlstore/pkg/scanfunc"
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/segmentio/errors-go"
+	"github.com/segmentio/events"
+)
+
+var (
+	// rowCacheRetryInterval is how long the row cache waits before trying
+	// to follow the changelog again after failing to.
+	rowCacheRetryInterval = time.Second
+	// rowCacheSchemaCheckInterval is how often the row cache checks the LDB
+	// for schema changes, which aren't recorded in the changelog.
+	rowCacheSchemaCheckInterval = time.Second
+)
+
+// WithCache caches up to size of the most recently used results of
+// GetRowByKey in memory, including lookups which found no row. Entries are
+// invalidated as the reflector logs changes to the changelog alongside the
+// LDB, so the cache is only used by readers opened with ReaderForPath, and
+// only while the changelog is being followed. The whole cache is cleared if
+// the schema of the LDB changes or changes to the changelog were missed.
+//
+// Since the changelog is written after changes are committed to the LDB,
+// cached rows can trail the LDB by the time it takes to read the changelog.
+// To keep reads consistent with the sequences the reader has returned, e.g.
+// from WaitForSequence, the cache is bypassed whenever the reader has seen
+// the LDB at a later sequence than the changelog has been read up to. Reads
+// made through ReadTx are never cached.
+func WithCache(size int) ReaderOpt {
+	return func(reader *LDBReader) {
+		if size > 0 {
+			reader.cache = newRowCache(size)
+		}
+	}
+}
+
+// rowCache is a bounded LRU cache of GetRowByKey results.
+type rowCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element // of *rowCacheEntry
+	lru     *list.List               // most recently used at the front
+	enabled bool                     // only while the changelog is followed
+	epoch   int64                    // incremented on every invalidation
+	// seq is the ledger sequence the entries have been invalidated through,
+	// and ldbSeq the latest ledger sequence the reader has seen the LDB at.
+	// Entries aren't used while the LDB is ahead.
+	seq    int64
+	ldbSeq int64
+	cancel context.CancelFunc
+}
+
+type rowCacheEntry struct {
+	key   string
+	found bool
+	cols  []schema.DBColumnMeta
+	vals  scanfunc.Values
+}
+
+func newRowCache(size int) *rowCache {
+	return &rowCache{
+		size:    size,
+		entries: make(map[string]*list.Element, size),
+		lru:     list.New(),
+	}
+}
+
+// scan fills out with the cached row.
+func (e *rowCacheEntry) scan(out interface{}) (bool, error) {
+	if !e.found {
+		return false, nil
+	}
+	scanFunc, err := scanfunc.New(out, e.cols)
+	if err != nil {
+		return false, err
+	}
+	if err := scanFunc(e.vals); err != nil {
+		return true, errors.Wrap(err, "target row scan error")
+	}
+	return true, nil
+}
+
+// get returns the cached entry for key. If there isn't one, or the LDB has
+// been seen at a sequence the entries haven't been invalidated through yet,
+// the current epoch is returned, which should be passed to add along with
+// the result of the lookup.
+func (c *rowCache) get(key string) (entry *rowCacheEntry, epoch int64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ldbSeq > c.seq {
+		return nil, c.epoch, false
+	}
+	if elem, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elem)
+		return elem.Value.(*rowCacheEntry), 0, true
+	}
+	return nil, c.epoch, false
+}
+
+// add caches the entry as long as nothing has been invalidated since the
+// epoch was returned by get, since the entry could already be out of date.
+func (c *rowCache) add(entry *rowCacheEntry, epoch int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.enabled || c.epoch != epoch {
+		return
+	}
+	if elem, ok := c.entries[entry.key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*rowCacheEntry).key)
+	}
+}
+
+func (c *rowCache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.epoch++
+	if elem, ok := c.entries[key]; ok {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// invalidatedThrough records that every change up to and including the
+// ledger sequence has been invalidated.
+func (c *rowCache) invalidatedThrough(seq int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if seq > c.seq {
+		c.seq = seq
+	}
+}
+
+// observe records that the LDB has been seen at the ledger sequence.
+func (c *rowCache) observe(seq int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if seq > c.ldbSeq {
+		c.ldbSeq = seq
+	}
+}
+
+// purge removes every entry.
+func (c *rowCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.purgeLocked()
+}
+
+// enable purges the cache, and starts or stops caching new entries. seq is
+// the ledger sequence of the LDB, which the entries added from then on are
+// at least as recent as.
+func (c *rowCache) enable(enabled bool, seq int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.purgeLocked()
+	c.enabled = enabled
+	if seq > c.seq {
+		c.seq = seq
+	}
+}
+
+func (c *rowCache) purgeLocked() {
+	c.epoch++
+	c.entries = make(map[string]*list.Element, c.size)
+	c.lru.Init()
+}
+
+// startCache starts following the changelog to invalidate the cache, if
+// the reader has one. The cache is disabled if there's no changelog.
+func (reader *LDBReader) startCache() {
+	if reader.cache == nil {
+		return
+	}
+	if reader.changelogPath == "" {
+		events.Log("Row cache disabled: no changelog is available for this reader")
+		reader.cache = nil
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	reader.cache.cancel = cancel
+	go reader.followChangelog(ctx)
+	go reader.watchSchemaVersion(ctx)
+}
+
+// followChangelog invalidates cache entries as changes are read from the
+// changelog, until the context is done.
+func (reader *LDBReader) followChangelog(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := func() error {
+			iter, err := event.NewIterator(ctx, reader.changelogPath)
+			if err != nil {
+				return errors.Wrap(err, "open changelog")
+			}
+			defer iter.Close()
+			// the sequence is read once the changelog is being followed, so
+			// that the changes made after it are all read from it
+			seq, err := reader.GetLastSequence(ctx)
+			if err != nil {
+				return errors.Wrap(err, "get last sequence")
+			}
+			reader.cache.enable(true, seq.Int())
+			for {
+				e, err := iter.Next(ctx)
+				switch {
+				case err == ErrOutOfSync:
+					reader.cache.purge()
+				case err != nil:
+					return err
+				default:
+					reader.invalidateCachedRows(e.RowUpdate)
+				}
+				if e.LedgerLast {
+					reader.cache.invalidatedThrough(e.LedgerSequence)
+				}
+			}
+		}()
+		reader.cache.enable(false, 0)
+		if ctx.Err() != nil {
+			return
+		}
+		events.Log("Row cache disabled, could not follow changelog: %{error}+v", err)
+		select {
+		case <-time.After(rowCacheRetryInterval):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// invalidateCachedRows invalidates the entries for the rows changed by an
+// update, which is the key before the change as well as after it.
+func (reader *LDBReader) invalidateCachedRows(update event.RowUpdate) {
+	famName, err := schema.NewFamilyName(update.FamilyName)
+	if err != nil {
+		reader.cache.purge()
+		return
+	}
+	tblName, err := schema.NewTableName(update.TableName)
+	if err != nil {
+		reader.cache.purge()
+		return
+	}
+	ldbTable := schema.LDBTableName(famName, tblName)
+	for _, row := range []event.Row{update.OldRow, update.NewRow} {
+		if row == nil && update.Op != "" {
+			continue
+		}
+		key, ok := changelogCacheKey(ldbTable, update.Keys, row)
+		if !ok {
+			// can't work out which entry this is, so play it safe
+			reader.cache.purge()
+			return
+		}
+		reader.cache.invalidate(key)
+	}
+}
+
+// watchSchemaVersion purges the cache whenever the schema of the LDB
+// changes, e.g. when a table is dropped, since those changes aren't
+// recorded in the changelog.
+func (reader *LDBReader) watchSchemaVersion(ctx context.Context) {
+	ticker := time.NewTicker(rowCacheSchemaCheckInterval)
+	defer ticker.Stop()
+	var last int64 = -1
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		reader.mu.RLock()
+		var version int64
+		err := reader.Db.QueryRowContext(ctx, "PRAGMA schema_version").Scan(&version)
+		reader.mu.RUnlock()
+		if err != nil {
+			if ctx.Err() == nil {
+				events.Log("Could not check LDB schema version: %{error}+v", err)
+			}
+			continue
+		}
+		if last != -1 && version != last {
+			reader.cache.purge()
+		}
+		last = version
+	}
+}
+
+// getCachedRowByKey looks the key up in the cache, returning the epoch to
+// add the result of the lookup with if it isn't cached. ok is false if
+// the key can't be cached.
+func (reader *LDBReader) getCachedRowByKey(ldbTable string, pk schema.PrimaryKey, key []interface{}) (cacheKey string, entry *rowCacheEntry, epoch int64, ok bool) {
+	if reader.cache == nil {
+		return "", nil, 0, false
+	}
+	cacheKey, ok = rowCacheKey(ldbTable, pk, key)
+	if !ok {
+		return "", nil, 0, false
+	}
+	entry, epoch, _ = reader.cache.get(cacheKey)
+	return cacheKey, entry, epoch, true
+}
+
+// captureRow reads the values of the current row into a cache entry.
+func captureRow(cacheKey string, cols []schema.DBColumnMeta, rows *sql.Rows) (*rowCacheEntry, error) {
+	vals := make(scanfunc.Values, len(cols))
+	targets := make([]interface{}, len(cols))
+	for i := range vals {
+		targets[i] = &vals[i]
+	}
+	if err := rows.Scan(targets...); err != nil {
+		return nil, err
+	}
+	return &rowCacheEntry{key: cacheKey, found: true, cols: cols, vals: vals}, nil
+}
+
+// rowCacheKey builds the cache key of a GetRowByKey lookup. ok is false for
+// key values whose type doesn't match the primary key field, since SQLite
+// may still find a row for them by converting the value.
+func rowCacheKey(ldbTable string, pk schema.PrimaryKey, key []interface{}) (cacheKey string, ok bool) {
+	parts := make([]cacheKeyPart, 0, len(key))
+	for i, k := range key {
+		var part string
+		switch pk.Types[i] {
+		case schema.FTInteger:
+			switch k := k.(type) {
+			case int:
+				part = strconv.FormatInt(int64(k), 10)
+			case int32:
+				part = strconv.FormatInt(int64(k), 10)
+			case int64:
+				part = strconv.FormatInt(k, 10)
+			case uint32:
+				part = strconv.FormatUint(uint64(k), 10)
+			default:
+				return "", false
+			}
+		case schema.FTString, schema.FTText:
+			s, isString := k.(string)
+			if !isString {
+				return "", false
+			}
+			part = s
+		case schema.FTBinary, schema.FTByteString:
+			bs, isBytes := k.([]byte)
+			if !isBytes {
+				return "", false
+			}
+			part = string(bs)
+		default:
+			return "", false
+		}
+		parts = append(parts, cacheKeyPart{name: pk.Fields[i].Name, val: part})
+	}
+	return buildCacheKey(ldbTable, parts), true
+}
+
+// changelogCacheKey builds the cache key of a row from the changelog. The
+// key values are taken from the row if there is one, since numbers in it
+// are decoded without losing precision.
+func changelogCacheKey(ldbTable string, keys []event.Key, row event.Row) (cacheKey string, ok bool) {
+	parts := make([]cacheKeyPart, 0, len(keys))
+	for _, k := range keys {
+		val := k.Value
+		if row != nil {
+			if val, ok = row[k.Name]; !ok {
+				return "", false
+			}
+		}
+		ft, ok := schema.SqlTypeToFieldType(k.Type)
+		if !ok {
+			return "", false
+		}
+		var part string
+		switch ft {
+		case schema.FTInteger:
+			switch v := val.(type) {
+			case json.Number:
+				part = v.String()
+			case float64:
+				part = strconv.FormatInt(int64(v), 10)
+			default:
+				return "", false
+			}
+		case schema.FTString, schema.FTText:
+			s, isString := val.(string)
+			if !isString {
+				return "", false
+			}
+			part = s
+		case schema.FTBinary, schema.FTByteString:
+			// binary values were base64 encoded by encoding/json
+			s, isString := val.(string)
+			if !isString {
+				return "", false
+			}
+			bs, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return "", false
+			}
+			part = string(bs)
+		default:
+			return "", false
+		}
+		parts = append(parts, cacheKeyPart{name: k.Name, val: part})
+	}
+	return buildCacheKey(ldbTable, parts), true
+}
+
+type cacheKeyPart struct {
+	name string
+	val  string
+}
+
+// buildCacheKey orders the parts by field name, since the changelog lists
+// key fields in column order rather than primary key order, and length
+// prefixes each value so that keys can't collide.
+func buildCacheKey(ldbTable string, parts []cacheKeyPart) string {
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].name < parts[j].name
+	})
+	var b strings.Builder
+	b.WriteString(ldbTable)
+	for _, part := range parts {
+		b.WriteByte(0)
+		b.WriteString(strconv.Itoa(len(part.val)))
+		b.WriteByte(':')
+		b.WriteString(part.val)
+	}
+	return b.String()
+}
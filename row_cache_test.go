@@ -0,0 +1,187 @@
+package ctlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/segmentio/ctlstore/pkg/changelog"
+	"github.com/segmentio/ctlstore/pkg/ldb"
+	"github.com/segmentio/ctlstore/pkg/ldbwriter"
+	"github.com/segmentio/ctlstore/pkg/logwriter"
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/segmentio/ctlstore/pkg/sqlite"
+	"github.com/segmentio/ctlstore/pkg/tests"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowCache(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	dir, teardown := tests.WithTmpDir(t)
+	defer teardown()
+	ldbPath := filepath.Join(dir, ldb.DefaultLDBFilename)
+
+	// writes through the same hooks as the reflector, so that changes are
+	// logged to the changelog
+	changeBuffer := new(sqlite.SQLChangeBuffer)
+	driverName := fmt.Sprintf("%s_%d", ldb.LDBDatabaseDriver, time.Now().UnixNano())
+	require.NoError(t, sqlite.RegisterSQLiteWatch(driverName, changeBuffer))
+	db, err := sql.Open(driverName, "file:"+ldbPath+"?_journal_mode=wal")
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, ldb.EnsureLdbInitialized(ctx, db))
+	writer := &ldbwriter.LDBWriterWithChangelog{
+		LdbWriter: &ldbwriter.SqlLdbWriter{Db: db},
+		ChangelogWriter: &changelog.ChangelogWriter{
+			WriteLine: &logwriter.SizedLogWriter{
+				Path:       filepath.Join(dir, DefaultChangelogFilename),
+				FileMode:   0644,
+				RotateSize: 1024 * 1024,
+			},
+		},
+		DB:           db,
+		ChangeBuffer: changeBuffer,
+	}
+	seq := schema.DMLSequence(0)
+	apply := func(statement string) {
+		seq++
+		err := writer.ApplyDMLStatement(ctx, schema.DMLStatement{
+			Sequence:  seq,
+			Statement: statement,
+			Timestamp: time.Now(),
+		})
+		require.NoError(t, err)
+	}
+	// the primary key is in a different order to the columns
+	apply(`CREATE TABLE foo___bar ("name" VARCHAR(191), "id" INTEGER, "value" VARCHAR(191), PRIMARY KEY("id","name"))`)
+	apply(`INSERT INTO foo___bar VALUES ('a', 1, 'one')`)
+
+	reader, err := ReaderForPath(ldbPath, WithCache(2))
+	require.NoError(t, err)
+	defer reader.Close()
+	require.Eventually(t, func() bool {
+		reader.cache.mu.Lock()
+		defer reader.cache.mu.Unlock()
+		return reader.cache.enabled
+	}, 5*time.Second, 10*time.Millisecond)
+
+	type row struct {
+		Name  string `ctlstore:"name"`
+		ID    int64  `ctlstore:"id"`
+		Value string `ctlstore:"value"`
+	}
+	get := func(id int64, name string) (row, bool) {
+		var out row
+		found, err := reader.GetRowByKey(ctx, &out, "foo", "bar", id, name)
+		require.NoError(t, err)
+		return out, found
+	}
+
+	out, found := get(1, "a")
+	require.True(t, found)
+	require.Equal(t, row{"a", 1, "one"}, out)
+	_, found = get(2, "b")
+	require.False(t, found)
+
+	// changes which aren't logged to the changelog don't invalidate the
+	// cache, which shows that these lookups are served from it
+	unlogged, err := ldb.OpenLDB(ldbPath, "rwc")
+	require.NoError(t, err)
+	defer unlogged.Close()
+	_, err = unlogged.Exec(`REPLACE INTO foo___bar VALUES ('a', 1, 'unlogged'), ('b', 2, 'unlogged')`)
+	require.NoError(t, err)
+	out, found = get(1, "a")
+	require.True(t, found)
+	require.Equal(t, row{"a", 1, "one"}, out)
+	m := map[string]interface{}{}
+	found, err = reader.GetRowByKey(ctx, m, "foo", "bar", 1, "a")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, map[string]interface{}{"name": "a", "id": int64(1), "value": "one"}, m)
+	_, found = get(2, "b")
+	require.False(t, found)
+
+	// logged changes invalidate the entries for the rows they change
+	apply(`REPLACE INTO foo___bar VALUES ('a', 1, 'two')`)
+	apply(`REPLACE INTO foo___bar VALUES ('b', 2, 'two')`)
+	require.Eventually(t, func() bool {
+		out, _ := get(1, "a")
+		return out.Value == "two"
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		out, found := get(2, "b")
+		return found && out.Value == "two"
+	}, 5*time.Second, 10*time.Millisecond)
+	apply(`DELETE FROM foo___bar WHERE id = 1`)
+	require.Eventually(t, func() bool {
+		_, found := get(1, "a")
+		return !found
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// once the reader has seen the LDB at a sequence whose changes haven't
+	// been read from the changelog, e.g. because the changes were made by
+	// another statement, lookups aren't served from the cache
+	_, found = get(3, "c")
+	require.False(t, found)
+	seq++
+	_, err = unlogged.Exec(`INSERT INTO foo___bar VALUES ('c', 3, 'unlogged')`)
+	require.NoError(t, err)
+	_, err = unlogged.Exec(`UPDATE _ldb_seq SET seq = ? WHERE id = ?`, seq, ldb.LDBSeqTableID)
+	require.NoError(t, err)
+	_, found = get(3, "c")
+	require.False(t, found)
+	require.NoError(t, reader.WaitForSequence(ctx, seq))
+	out, found = get(3, "c")
+	require.True(t, found)
+	require.Equal(t, row{"c", 3, "unlogged"}, out)
+
+	// which they are again once the changelog has caught up
+	apply(`INSERT INTO foo___bar VALUES ('d', 4, 'four')`)
+	require.NoError(t, reader.WaitForSequence(ctx, seq))
+	_, err = unlogged.Exec(`UPDATE foo___bar SET value = 'unlogged again' WHERE id = 3`)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		out, _ := get(3, "c")
+		return out.Value == "unlogged"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the cache is bounded
+	get(3, "c")
+	reader.cache.mu.Lock()
+	require.Equal(t, 2, reader.cache.lru.Len())
+	require.Len(t, reader.cache.entries, 2)
+	reader.cache.mu.Unlock()
+}
+
+func TestRowCacheKey(t *testing.T) {
+	pk, err := schema.NewPKFromRawNamesAndTypes([]string{"id", "name", "data"}, []string{"INTEGER", "VARCHAR(191)", "BLOB(255)"})
+	require.NoError(t, err)
+
+	key, ok := rowCacheKey("foo___bar", pk, []interface{}{1, "a", []byte{0xff}})
+	require.True(t, ok)
+	for _, k := range []interface{}{int32(1), int64(1)} {
+		other, ok := rowCacheKey("foo___bar", pk, []interface{}{k, "a", []byte{0xff}})
+		require.True(t, ok)
+		require.Equal(t, key, other)
+	}
+
+	// keys which SQLite would have to convert aren't cached
+	for _, k := range [][]interface{}{
+		{"1", "a", []byte{0xff}},
+		{1, []byte("a"), []byte{0xff}},
+		{1, "a", "\xff"},
+		{1.5, "a", []byte{0xff}},
+	} {
+		_, ok := rowCacheKey("foo___bar", pk, k)
+		require.False(t, ok, "%v", k)
+	}
+
+	other, ok := rowCacheKey("foo___bar", pk, []interface{}{1, "a\x001:", []byte{0xff}})
+	require.True(t, ok)
+	require.NotEqual(t, key, other)
+}
@@ -2,19 +2,107 @@ package ctlstore
 
 import (
 	"database/sql"
+	"reflect"
 
 	"github.com/segmentio/ctlstore/pkg/scanfunc"
 	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/segmentio/errors-go"
 )
 
+var ErrScanAllUnsupportedType = errors.New("ScanAll requires a pointer to a slice of structs, struct pointers, or map[string]interface{}")
+
 // Rows composes an *sql.Rows and allows scanning ctlstore table rows into
 // structs or maps, similar to how the GetRowByKey reader method works.
 //
 // The contract around Next/Err/Close is the same was it is for
 // *sql.Rows.
 type Rows struct {
-	rows *sql.Rows
-	cols []schema.DBColumnMeta
+	rows     rowSource
+	cols     []schema.DBColumnMeta
+	keyRange *keyRangeState // only set for key range scans
+	batches  *keyBatchState // only set for batch lookups
+
+	// the scan func for the struct pointer last passed to Scan
+	scanTarget interface{}
+	scanFunc   scanfunc.ScanFunc
+}
+
+// rowSource supplies the rows read by Rows. It's usually an *sql.Rows.
+type rowSource interface {
+	Next() bool
+	Err() error
+	Close() error
+	Scan(dest ...interface{}) error
+}
+
+// NewRowsFromJSON returns Rows over rows which were read from an LDB and
+// then encoded as JSON objects keyed by column name, e.g. by a sidecar. The
+// table schema is used to convert values back into the types they have in
+// the LDB, so numbers should have been decoded as json.Number, and binary
+// values are expected to be base64 encoded the way encoding/json encodes
+// []byte. An error is returned if a row's fields don't match the schema.
+func NewRowsFromJSON(ts TableSchema, rows []map[string]interface{}) (*Rows, error) {
+	cols := make([]schema.DBColumnMeta, len(ts.Fields))
+	for i, field := range ts.Fields {
+		cols[i] = schema.DBColumnMeta{Name: field.Name.Name, Type: jsonColumnType(field.FieldType)}
+	}
+	vals := make([]scanfunc.Values, len(rows))
+	for i, row := range rows {
+		if len(row) != len(cols) {
+			return nil, errors.Errorf("row has %d fields, expected %d", len(row), len(cols))
+		}
+		vals[i] = make(scanfunc.Values, len(cols))
+		for j, field := range ts.Fields {
+			val, ok := row[field.Name.Name]
+			if !ok {
+				return nil, errors.Errorf("row is missing field '%s'", field.Name.Name)
+			}
+			var err error
+			if vals[i][j], err = decodeChangeValue(field.FieldType, val); err != nil {
+				return nil, errors.Wrapf(err, "field '%s'", field.Name.Name)
+			}
+		}
+	}
+	return &Rows{rows: &valueRows{vals: vals, pos: -1}, cols: cols}, nil
+}
+
+// jsonColumnType returns the column type NewRowsFromJSON reports for a
+// field. Scanning only depends on whether the column is binary or not.
+func jsonColumnType(ft schema.FieldType) string {
+	switch ft {
+	case schema.FTBinary, schema.FTByteString:
+		return "BLOB"
+	}
+	return schema.FieldTypeStringsByFieldType[ft]
+}
+
+// valueRows is a rowSource for rows that have already been read.
+type valueRows struct {
+	vals []scanfunc.Values
+	pos  int
+}
+
+func (v *valueRows) Next() bool {
+	if v.pos < len(v.vals) {
+		v.pos++
+	}
+	return v.pos < len(v.vals)
+}
+
+func (v *valueRows) Err() error {
+	return nil
+}
+
+func (v *valueRows) Close() error {
+	v.pos = len(v.vals)
+	return nil
+}
+
+func (v *valueRows) Scan(dest ...interface{}) error {
+	if v.pos < 0 || v.pos >= len(v.vals) {
+		return errors.New("Scan called without calling Next")
+	}
+	return v.vals[v.pos].Scan(dest...)
 }
 
 // Next returns true if there's another row available.
@@ -22,6 +110,17 @@ func (r *Rows) Next() bool {
 	if r.rows == nil {
 		return false
 	}
+	if r.keyRange != nil {
+		return r.keyRange.next(r.rows)
+	}
+	if r.batches != nil {
+		for !r.rows.Next() {
+			if r.rows.Err() != nil || !r.batches.advance(r) {
+				return false
+			}
+		}
+		return true
+	}
 	return r.rows.Next()
 }
 
@@ -33,10 +132,32 @@ func (r *Rows) Err() error {
 	if r.rows == nil {
 		return nil
 	}
+	if r.keyRange != nil && r.keyRange.err != nil {
+		return r.keyRange.err
+	}
+	if r.batches != nil && r.batches.err != nil {
+		return r.batches.err
+	}
 	return r.rows.Err()
 }
 
-// Close closes the underlying *sql.Rows.
+// Cursor returns an opaque cursor which can be passed to GetRowsByKeyRange
+// in KeyRangeOpts to fetch the next page of rows. It should be called once
+// Next has returned false. An empty cursor is returned if there are no more
+// rows, or if these rows did not come from a key range scan.
+func (r *Rows) Cursor() (string, error) {
+	if r.keyRange == nil {
+		return "", nil
+	}
+	return r.keyRange.cursor()
+}
+
+// Columns returns the columns of the rows, in the order they are read.
+func (r *Rows) Columns() []schema.DBColumnMeta {
+	return r.cols
+}
+
+// Close closes the underlying rows.
 func (r *Rows) Close() error {
 	if r.rows == nil {
 		return nil
@@ -51,9 +172,107 @@ func (r *Rows) Scan(target interface{}) error {
 	if r.rows == nil {
 		return sql.ErrNoRows
 	}
+	// maps aren't comparable, and are cheap to build a scan func for anyway
+	isPtr := reflect.TypeOf(target).Kind() == reflect.Ptr
+	if isPtr && r.scanTarget == target {
+		return r.scanFunc(r.rows)
+	}
 	scanFunc, err := scanfunc.New(target, r.cols)
 	if err != nil {
 		return err
 	}
+	if isPtr {
+		r.scanTarget, r.scanFunc = target, scanFunc
+	}
 	return scanFunc(r.rows)
 }
+
+// ScanAll deserializes all of the remaining rows, appending them to dest,
+// and then closes the Rows. dest must be a pointer to a slice of structs,
+// of pointers to structs, or of map[string]interface{}.
+func (r *Rows) ScanAll(dest interface{}) error {
+	defer r.Close()
+
+	destVal := reflect.ValueOf(dest)
+	if destVal.Kind() != reflect.Ptr || destVal.Elem().Kind() != reflect.Slice {
+		return ErrScanAllUnsupportedType
+	}
+	slice := destVal.Elem()
+	elemType := slice.Type().Elem()
+
+	var scanRow func() (reflect.Value, error)
+	switch {
+	case elemType == reflect.TypeOf(map[string]interface{}{}):
+		scanRow = func() (reflect.Value, error) {
+			m := make(map[string]interface{}, len(r.cols))
+			err := scanfunc.ScanMap(r.rows, m, r.cols)
+			return reflect.ValueOf(m), err
+		}
+	case elemType.Kind() == reflect.Struct,
+		elemType.Kind() == reflect.Ptr && elemType.Elem().Kind() == reflect.Struct:
+		structType := elemType
+		if elemType.Kind() == reflect.Ptr {
+			structType = elemType.Elem()
+		}
+		// only the targets have to be worked out for each row
+		plan, err := scanfunc.NewStructPlan(structType, r.cols)
+		if err != nil {
+			return err
+		}
+		scanRow = func() (reflect.Value, error) {
+			ptr := reflect.New(structType)
+			err := r.rows.Scan(plan.Targets(ptr.Interface())...)
+			if elemType.Kind() == reflect.Ptr {
+				return ptr, err
+			}
+			return ptr.Elem(), err
+		}
+	default:
+		return ErrScanAllUnsupportedType
+	}
+
+	for r.Next() {
+		val, err := scanRow()
+		if err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, val)
+	}
+	if err := r.Err(); err != nil {
+		return err
+	}
+	destVal.Elem().Set(slice)
+	return nil
+}
+
+// ScanAllMaps deserializes all of the remaining rows into maps keyed by
+// column name, and then closes the Rows.
+func (r *Rows) ScanAllMaps() ([]map[string]interface{}, error) {
+	var res []map[string]interface{}
+	err := r.ScanAll(&res)
+	return res, err
+}
+
+// ForEach deserializes each of the remaining rows into target, which is
+// the same as for Scan, calling fn after each one. Iteration stops at the
+// first error returned by fn, which is returned. The Rows is closed once
+// ForEach returns.
+//
+// target is reused for every row, so values which should outlive the call
+// to fn have to be copied out of it.
+func (r *Rows) ForEach(target interface{}, fn func() error) error {
+	defer r.Close()
+	scanFunc, err := scanfunc.New(target, r.cols)
+	if err != nil {
+		return err
+	}
+	for r.Next() {
+		if err := scanFunc(r.rows); err != nil {
+			return err
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return r.Err()
+}
@@ -0,0 +1,156 @@
+package ctlstore
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/ctlstore/pkg/ldb"
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowsScanAll(t *testing.T) {
+	ctx := context.Background()
+	db, teardown := ldb.LDBForTest(t)
+	defer teardown()
+	_, err := db.Exec(`
+		CREATE TABLE foo___bar (key VARCHAR PRIMARY KEY, value VARCHAR);
+		INSERT INTO foo___bar VALUES ('a', 'one'), ('b', 'two'), ('c', 'three');
+	`)
+	require.NoError(t, err)
+	reader := LDBReader{Db: db}
+
+	getRows := func() *Rows {
+		rows, err := reader.GetRowsByKeyPrefix(ctx, "foo", "bar")
+		require.NoError(t, err)
+		return rows
+	}
+	expected := []testKVStruct{{"a", "one"}, {"b", "two"}, {"c", "three"}}
+
+	var structs []testKVStruct
+	require.NoError(t, getRows().ScanAll(&structs))
+	require.Equal(t, expected, structs)
+
+	// rows are appended
+	structs = structs[:1]
+	require.NoError(t, getRows().ScanAll(&structs))
+	require.Equal(t, append(expected[:1:1], expected...), structs)
+
+	var ptrs []*testKVStruct
+	require.NoError(t, getRows().ScanAll(&ptrs))
+	require.Len(t, ptrs, 3)
+	for i, ptr := range ptrs {
+		require.Equal(t, expected[i], *ptr)
+	}
+
+	var maps []map[string]interface{}
+	require.NoError(t, getRows().ScanAll(&maps))
+	require.Equal(t, []map[string]interface{}{
+		{"key": "a", "value": "one"},
+		{"key": "b", "value": "two"},
+		{"key": "c", "value": "three"},
+	}, maps)
+	maps, err = getRows().ScanAllMaps()
+	require.NoError(t, err)
+	require.Len(t, maps, 3)
+
+	for _, dest := range []interface{}{structs, &[]string{}, &testKVStruct{}} {
+		require.Equal(t, ErrScanAllUnsupportedType, getRows().ScanAll(dest))
+	}
+
+	// an empty result leaves the slice empty
+	var none []testKVStruct
+	rows, err := reader.GetRowsByKeyPrefix(ctx, "foo", "bar", "z")
+	require.NoError(t, err)
+	require.NoError(t, rows.ScanAll(&none))
+	require.Empty(t, none)
+}
+
+func TestRowsForEach(t *testing.T) {
+	ctx := context.Background()
+	db, teardown := ldb.LDBForTest(t)
+	defer teardown()
+	_, err := db.Exec(`
+		CREATE TABLE foo___bar (key VARCHAR PRIMARY KEY, value VARCHAR);
+		INSERT INTO foo___bar VALUES ('a', 'one'), ('b', 'two'), ('c', 'three');
+	`)
+	require.NoError(t, err)
+	reader := LDBReader{Db: db}
+
+	rows, err := reader.GetRowsByKeyPrefix(ctx, "foo", "bar")
+	require.NoError(t, err)
+	var row testKVStruct
+	var seen []testKVStruct
+	err = rows.ForEach(&row, func() error {
+		seen = append(seen, row)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []testKVStruct{{"a", "one"}, {"b", "two"}, {"c", "three"}}, seen)
+
+	rows, err = reader.GetRowsByKeyPrefix(ctx, "foo", "bar")
+	require.NoError(t, err)
+	m := map[string]interface{}{}
+	var values []interface{}
+	stop := errors.New("stop")
+	err = rows.ForEach(m, func() error {
+		values = append(values, m["value"])
+		if len(values) == 2 {
+			return stop
+		}
+		return nil
+	})
+	require.Equal(t, stop, err)
+	require.Equal(t, []interface{}{"one", "two"}, values)
+}
+
+func TestNewRowsFromJSON(t *testing.T) {
+	ts := TableSchema{
+		FamilyName: "foo",
+		TableName:  "bar",
+		Fields: []schema.NamedFieldType{
+			{Name: schema.FieldName{Name: "key"}, FieldType: schema.FTString},
+			{Name: schema.FieldName{Name: "n"}, FieldType: schema.FTInteger},
+			{Name: schema.FieldName{Name: "data"}, FieldType: schema.FTBinary},
+		},
+	}
+	decode := func(s string) []map[string]interface{} {
+		dec := json.NewDecoder(strings.NewReader(s))
+		dec.UseNumber()
+		var rows []map[string]interface{}
+		require.NoError(t, dec.Decode(&rows))
+		return rows
+	}
+
+	rows, err := NewRowsFromJSON(ts, decode(`[
+		{"key": "a", "n": 9007199254740993, "data": "AAEC"},
+		{"key": "b", "n": 2, "data": null}
+	]`))
+	require.NoError(t, err)
+	type row struct {
+		Key  string `ctlstore:"key"`
+		N    int64  `ctlstore:"n"`
+		Data []byte `ctlstore:"data"`
+	}
+	var got []row
+	require.NoError(t, rows.ScanAll(&got))
+	require.Equal(t, []row{{"a", 9007199254740993, []byte{0, 1, 2}}, {"b", 2, nil}}, got)
+
+	rows, err = NewRowsFromJSON(ts, decode(`[{"key": "a", "n": 1, "data": "AAEC"}]`))
+	require.NoError(t, err)
+	maps, err := rows.ScanAllMaps()
+	require.NoError(t, err)
+	require.Equal(t, []map[string]interface{}{{"key": "a", "n": int64(1), "data": []byte{0, 1, 2}}}, maps)
+
+	for _, bad := range []string{
+		`[{"key": "a", "n": 1}]`,
+		`[{"key": "a", "n": 1, "other": null}]`,
+		`[{"key": "a", "n": "1", "data": null}]`,
+	} {
+		_, err := NewRowsFromJSON(ts, decode(bad))
+		require.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,204 @@
+package ctlstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"path/filepath"
+	"sync"
+
+	"github.com/segmentio/ctlstore/pkg/changelog"
+	"github.com/segmentio/ctlstore/pkg/event"
+	"github.com/segmentio/ctlstore/pkg/globalstats"
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/segmentio/errors-go"
+)
+
+// ChangeOp is the kind of change that was made to a row.
+type ChangeOp string
+
+const (
+	ChangeOpInsert ChangeOp = changelog.OpInsert
+	ChangeOpUpdate ChangeOp = changelog.OpUpdate
+	ChangeOpDelete ChangeOp = changelog.OpDelete
+)
+
+// ChangeEvent describes a single change to a row. Values in OldRow and
+// NewRow have the same types that GetRowByKey would scan them into, i.e.
+// int64 for integers, float64 for decimals, string for strings and text,
+// and []byte for binary fields.
+type ChangeEvent struct {
+	Sequence   int64
+	FamilyName string
+	TableName  string
+	Op         ChangeOp
+	OldRow     map[string]interface{} // nil for inserts
+	NewRow     map[string]interface{} // nil for deletes
+}
+
+var (
+	ErrNoChangelog = errors.New("no changelog is available for this reader")
+	ErrNoRowValues = errors.New("changelog does not record row values")
+	// ErrOutOfSync is returned by Subscription.Next when changes were missed,
+	// see event.ErrOutOfSync.
+	ErrOutOfSync = event.ErrOutOfSync
+)
+
+// Subscription delivers the changes made to a single table. It is not safe
+// for concurrent use.
+type Subscription struct {
+	reader     *LDBReader
+	familyName string
+	tableName  string
+	iter       *event.Iterator
+	fieldTypes map[string]schema.FieldType // keyed by field name
+	closeOnce  sync.Once
+}
+
+// Subscribe returns a Subscription to the changes made to the specified
+// table. Changes are read from the changelog the reflector writes alongside
+// the LDB, starting with the oldest change it still holds, so ErrNoChangelog
+// is returned if the reader wasn't opened with ReaderForPath. Make sure to
+// Close() the subscription when done with it.
+func (reader *LDBReader) Subscribe(ctx context.Context, familyName string, tableName string) (*Subscription, error) {
+	famName, err := schema.NewFamilyName(familyName)
+	if err != nil {
+		return nil, err
+	}
+	tblName, err := schema.NewTableName(tableName)
+	if err != nil {
+		return nil, err
+	}
+	if reader.changelogPath == "" {
+		return nil, ErrNoChangelog
+	}
+	iter, err := event.NewIterator(ctx, reader.changelogPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "subscribe to changelog")
+	}
+	globalstats.Incr("subscribe", famName.Name, tblName.Name)
+	return &Subscription{
+		reader:     reader,
+		familyName: famName.Name,
+		tableName:  tblName.Name,
+		iter:       iter,
+	}, nil
+}
+
+// Next blocks until the next change to the table is available, or until
+// the context is done. ErrOutOfSync is returned if changes were missed, in
+// which case anything derived from earlier events should be rebuilt before
+// calling Next again. ErrNoRowValues is returned for changes logged by a
+// reflector which doesn't record row values.
+func (s *Subscription) Next(ctx context.Context) (ChangeEvent, error) {
+	for {
+		e, err := s.iter.Next(ctx)
+		if err != nil {
+			return ChangeEvent{}, err
+		}
+		update := e.RowUpdate
+		if update.FamilyName != s.familyName || update.TableName != s.tableName {
+			continue
+		}
+		if update.Op == "" {
+			return ChangeEvent{}, ErrNoRowValues
+		}
+		ce := ChangeEvent{
+			Sequence:   e.Sequence,
+			FamilyName: update.FamilyName,
+			TableName:  update.TableName,
+			Op:         ChangeOp(update.Op),
+		}
+		var decodeErr error
+		if ce.OldRow, decodeErr = s.decodeRow(ctx, update.OldRow); decodeErr != nil {
+			return ChangeEvent{}, errors.Wrap(decodeErr, "decode old row")
+		}
+		if ce.NewRow, decodeErr = s.decodeRow(ctx, update.NewRow); decodeErr != nil {
+			return ChangeEvent{}, errors.Wrap(decodeErr, "decode new row")
+		}
+		globalstats.Incr("subscription_events", s.familyName, s.tableName)
+		return ce, nil
+	}
+}
+
+// Close stops the subscription.
+func (s *Subscription) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		err = s.iter.Close()
+	})
+	return err
+}
+
+// decodeRow converts the JSON values of a changelog row into the types
+// they have in the LDB.
+func (s *Subscription) decodeRow(ctx context.Context, row event.Row) (map[string]interface{}, error) {
+	if row == nil {
+		return nil, nil
+	}
+	out := make(map[string]interface{}, len(row))
+	for name, val := range row {
+		ft, err := s.fieldType(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if out[name], err = decodeChangeValue(ft, val); err != nil {
+			return nil, errors.Wrapf(err, "field '%s'", name)
+		}
+	}
+	return out, nil
+}
+
+// fieldType looks up the type of a field, describing the table again if
+// the field isn't known yet, since it may have been added since the last
+// time the table was described.
+func (s *Subscription) fieldType(ctx context.Context, name string) (schema.FieldType, error) {
+	if ft, ok := s.fieldTypes[name]; ok {
+		return ft, nil
+	}
+	ts, err := s.reader.DescribeTable(ctx, s.familyName, s.tableName)
+	if err != nil {
+		return 0, errors.Wrap(err, "describe table")
+	}
+	s.fieldTypes = make(map[string]schema.FieldType, len(ts.Fields))
+	for _, f := range ts.Fields {
+		s.fieldTypes[f.Name.Name] = f.FieldType
+	}
+	ft, ok := s.fieldTypes[name]
+	if !ok {
+		return 0, errors.Errorf("unknown field '%s'", name)
+	}
+	return ft, nil
+}
+
+func decodeChangeValue(ft schema.FieldType, val interface{}) (interface{}, error) {
+	if val == nil {
+		return nil, nil
+	}
+	switch ft {
+	case schema.FTInteger:
+		if n, ok := val.(json.Number); ok {
+			return n.Int64()
+		}
+	case schema.FTDecimal:
+		if n, ok := val.(json.Number); ok {
+			return n.Float64()
+		}
+	case schema.FTBinary, schema.FTByteString:
+		// binary values were base64 encoded by encoding/json
+		if s, ok := val.(string); ok {
+			return base64.StdEncoding.DecodeString(s)
+		}
+	case schema.FTString, schema.FTText:
+		if s, ok := val.(string); ok {
+			return s, nil
+		}
+	}
+	return nil, errors.Errorf("unexpected %T value for %s field", val, schema.FieldTypeStringsByFieldType[ft])
+}
+
+// changelogPathFor returns the path of the changelog which the reflector
+// writes alongside the LDB at the supplied path.
+func changelogPathFor(ldbPath string) string {
+	return filepath.Join(filepath.Dir(ldbPath), DefaultChangelogFilename)
+}
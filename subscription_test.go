@@ -0,0 +1,76 @@
+package ctlstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/segmentio/ctlstore/pkg/changelog"
+	"github.com/segmentio/ctlstore/pkg/ldb"
+	"github.com/segmentio/ctlstore/pkg/logwriter"
+	"github.com/segmentio/ctlstore/pkg/tests"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	db, teardown := ldb.LDBForTest(t)
+	defer teardown()
+	_, err := db.Exec(initSQLForTableSchema)
+	require.NoError(t, err)
+
+	f, teardown := tests.WithTmpFile(t, "changelog")
+	defer teardown()
+	w := &changelog.ChangelogWriter{
+		WriteLine: &logwriter.SizedLogWriter{Path: f.Name(), FileMode: 0644, RotateSize: 1024 * 1024},
+	}
+	for _, entry := range []changelog.ChangelogEntry{
+		{Seq: 1, Family: "foo", Table: "baz", Op: changelog.OpInsert,
+			New: map[string]interface{}{"k1": "a", "k2": []byte{1}, "num": 1, "data": nil, "ratio": 0.5}},
+		{Seq: 2, Family: "foo", Table: "bar", Op: changelog.OpInsert,
+			New: map[string]interface{}{"key": "a", "value": "b"}},
+		{Seq: 3, Family: "foo", Table: "baz", Op: changelog.OpUpdate,
+			Old: map[string]interface{}{"k1": "a", "k2": []byte{1}, "num": 1, "data": nil, "ratio": 0.5},
+			New: map[string]interface{}{"k1": "a", "k2": []byte{1}, "num": 9007199254740993, "data": []byte("hi"), "ratio": 0.5}},
+		{Seq: 4, Family: "foo", Table: "baz", Op: changelog.OpDelete,
+			Old: map[string]interface{}{"k1": "a", "k2": []byte{1}, "num": 9007199254740993, "data": []byte("hi"), "ratio": 0.5}},
+		{Seq: 5, Family: "foo", Table: "baz"},
+	} {
+		require.NoError(t, w.WriteChange(entry))
+	}
+
+	reader := &LDBReader{Db: db}
+	_, err = reader.Subscribe(ctx, "foo", "baz")
+	require.Equal(t, ErrNoChangelog, err)
+
+	reader.changelogPath = f.Name()
+	sub, err := reader.Subscribe(ctx, "foo", "baz")
+	require.NoError(t, err)
+	defer sub.Close()
+
+	for _, expected := range []ChangeEvent{
+		{
+			Sequence: 1, FamilyName: "foo", TableName: "baz", Op: ChangeOpInsert,
+			NewRow: map[string]interface{}{"k1": "a", "k2": []byte{1}, "num": int64(1), "data": nil, "ratio": 0.5},
+		},
+		{
+			Sequence: 3, FamilyName: "foo", TableName: "baz", Op: ChangeOpUpdate,
+			OldRow: map[string]interface{}{"k1": "a", "k2": []byte{1}, "num": int64(1), "data": nil, "ratio": 0.5},
+			NewRow: map[string]interface{}{"k1": "a", "k2": []byte{1}, "num": int64(9007199254740993), "data": []byte("hi"), "ratio": 0.5},
+		},
+		{
+			Sequence: 4, FamilyName: "foo", TableName: "baz", Op: ChangeOpDelete,
+			OldRow: map[string]interface{}{"k1": "a", "k2": []byte{1}, "num": int64(9007199254740993), "data": []byte("hi"), "ratio": 0.5},
+		},
+	} {
+		ce, err := sub.Next(ctx)
+		require.NoError(t, err)
+		require.Equal(t, expected, ce)
+	}
+
+	// the last change was logged without row values
+	_, err = sub.Next(ctx)
+	require.Equal(t, ErrNoRowValues, err)
+}
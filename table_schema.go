@@ -0,0 +1,167 @@
+package ctlstore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/segmentio/ctlstore/pkg/globalstats"
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/segmentio/ctlstore/pkg/sqlite"
+	"github.com/segmentio/errors-go"
+	"github.com/segmentio/stats/v4"
+)
+
+// TableSchema describes the fields and primary key of a table in the LDB.
+type TableSchema struct {
+	FamilyName string
+	TableName  string
+	Fields     []schema.NamedFieldType // in column order
+	PrimaryKey schema.PrimaryKey
+}
+
+// ListFamilies returns the names of the families that have at least one
+// table in the LDB, in sorted order.
+func (reader *LDBReader) ListFamilies(ctx context.Context) ([]string, error) {
+	start := time.Now()
+	defer func() {
+		globalstats.Observe("list_families", time.Now().Sub(start))
+	}()
+
+	tables, err := reader.listLDBTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the tables are sorted by their LDB names, in which the '_' separating
+	// the family from the table can sort after characters of family names,
+	// so the families are sorted on their own.
+	families := []string{}
+	seen := map[string]bool{}
+	for _, ft := range tables {
+		if !seen[ft.Family] {
+			seen[ft.Family] = true
+			families = append(families, ft.Family)
+		}
+	}
+	sort.Strings(families)
+	return families, nil
+}
+
+// ListTables returns the names of the tables in the specified family, in
+// sorted order.
+func (reader *LDBReader) ListTables(ctx context.Context, familyName string) ([]string, error) {
+	start := time.Now()
+	defer func() {
+		globalstats.Observe("list_tables", time.Now().Sub(start),
+			stats.T("family", familyName))
+	}()
+
+	famName, err := schema.NewFamilyName(familyName)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := reader.listLDBTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, ft := range tables {
+		if ft.Family == famName.Name {
+			names = append(names, ft.Table)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// DescribeTable returns the fields and primary key of the specified table.
+// ErrTableNotFound is returned if the table does not exist.
+func (reader *LDBReader) DescribeTable(ctx context.Context, familyName string, tableName string) (TableSchema, error) {
+	start := time.Now()
+	defer func() {
+		globalstats.Observe("describe_table", time.Now().Sub(start),
+			stats.T("family", familyName),
+			stats.T("table", tableName))
+	}()
+
+	reader.reopenIfReplaced()
+	reader.mu.RLock()
+	defer reader.mu.RUnlock()
+
+	famName, err := schema.NewFamilyName(familyName)
+	if err != nil {
+		return TableSchema{}, err
+	}
+	tblName, err := schema.NewTableName(tableName)
+	if err != nil {
+		return TableSchema{}, err
+	}
+	ldbTable := schema.LDBTableName(famName, tblName)
+
+	dbInfo := sqlite.SqliteDBInfo{Db: reader.Db}
+	colInfos, err := dbInfo.GetColumnInfo(ctx, []string{ldbTable})
+	if err != nil {
+		return TableSchema{}, errors.Wrap(err, "get column info")
+	}
+	if len(colInfos) == 0 {
+		return TableSchema{}, ErrTableNotFound
+	}
+	fields := make([]schema.NamedFieldType, len(colInfos))
+	for i, colInfo := range colInfos {
+		fn, err := schema.NewFieldName(colInfo.ColumnName)
+		if err != nil {
+			return TableSchema{}, err
+		}
+		ft, ok := schema.SqlTypeToFieldType(colInfo.DataType)
+		if !ok {
+			return TableSchema{}, errors.Errorf("no field type found for '%s'", colInfo.DataType)
+		}
+		fields[i] = schema.NamedFieldType{Name: fn, FieldType: ft}
+	}
+
+	pk, err := reader.getPrimaryKey(ctx, ldbTable) // assumes RLock held
+	if err != nil {
+		return TableSchema{}, err
+	}
+
+	return TableSchema{
+		FamilyName: famName.Name,
+		TableName:  tblName.Name,
+		Fields:     fields,
+		PrimaryKey: pk,
+	}, nil
+}
+
+// listLDBTables returns the family and table names of each table in the LDB
+// sorted by LDB table name, skipping over the tables which ctlstore uses
+// internally.
+func (reader *LDBReader) listLDBTables(ctx context.Context) ([]schema.FamilyTable, error) {
+	reader.reopenIfReplaced()
+	reader.mu.RLock()
+	defer reader.mu.RUnlock()
+
+	const qs = "SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name ASC"
+	rows, err := reader.Db.QueryContext(ctx, qs)
+	if err != nil {
+		return nil, errors.Wrap(err, "query sqlite_master error")
+	}
+	defer rows.Close()
+
+	var tables []schema.FamilyTable
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		famName, tblName, err := schema.DecodeLDBTableName(name)
+		if err != nil {
+			// not a family table, e.g. _ldb_seq
+			continue
+		}
+		tables = append(tables, schema.FamilyTable{Family: famName.Name, Table: tblName.Name})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return tables, nil
+}
@@ -0,0 +1,125 @@
+package ctlstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/ctlstore/pkg/ldb"
+	"github.com/segmentio/ctlstore/pkg/schema"
+	"github.com/stretchr/testify/require"
+)
+
+const initSQLForTableSchema = `
+	CREATE TABLE foo___bar ("key" VARCHAR(191), "value" TEXT, PRIMARY KEY("key"));
+	CREATE TABLE foo___baz ("k1" VARCHAR(191), "k2" BLOB(255), "num" INTEGER, "data" BLOB, "ratio" REAL, PRIMARY KEY("k2","k1"));
+	CREATE TABLE qux___bar ("id" INTEGER, PRIMARY KEY("id"));
+	CREATE TABLE foo1___bar ("id" INTEGER, PRIMARY KEY("id"));
+	CREATE INDEX foo___baz___by_num ON foo___baz ("num");
+`
+
+func TestListFamiliesAndTables(t *testing.T) {
+	ctx := context.Background()
+	db, teardown := ldb.LDBForTest(t)
+	defer teardown()
+	reader := LDBReader{Db: db}
+
+	families, err := reader.ListFamilies(ctx)
+	require.NoError(t, err)
+	require.Empty(t, families)
+
+	_, err = db.Exec(initSQLForTableSchema)
+	require.NoError(t, err)
+
+	families, err = reader.ListFamilies(ctx)
+	require.NoError(t, err)
+	// foo1___bar sorts before foo___bar in the LDB
+	require.Equal(t, []string{"foo", "foo1", "qux"}, families)
+
+	for _, test := range []struct {
+		family   string
+		expected []string
+		err      error
+	}{
+		{family: "foo", expected: []string{"bar", "baz"}},
+		{family: "qux", expected: []string{"bar"}},
+		{family: "nope", expected: []string{}},
+		{family: "no", err: schema.ErrFamilyNameTooShort},
+	} {
+		t.Run(test.family, func(t *testing.T) {
+			tables, err := reader.ListTables(ctx, test.family)
+			if test.err != nil {
+				require.Equal(t, test.err, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, tables)
+		})
+	}
+}
+
+func TestDescribeTable(t *testing.T) {
+	for _, test := range []struct {
+		desc     string
+		table    string
+		expected TableSchema
+		err      error
+	}{
+		{
+			desc:  "single key",
+			table: "bar",
+			expected: TableSchema{
+				FamilyName: "foo",
+				TableName:  "bar",
+				Fields: []schema.NamedFieldType{
+					{Name: schema.FieldName{Name: "key"}, FieldType: schema.FTString},
+					{Name: schema.FieldName{Name: "value"}, FieldType: schema.FTText},
+				},
+				PrimaryKey: schema.PrimaryKey{
+					Fields: []schema.FieldName{{Name: "key"}},
+					Types:  []schema.FieldType{schema.FTString},
+				},
+			},
+		},
+		{
+			desc:  "composite key",
+			table: "baz",
+			expected: TableSchema{
+				FamilyName: "foo",
+				TableName:  "baz",
+				Fields: []schema.NamedFieldType{
+					{Name: schema.FieldName{Name: "k1"}, FieldType: schema.FTString},
+					{Name: schema.FieldName{Name: "k2"}, FieldType: schema.FTByteString},
+					{Name: schema.FieldName{Name: "num"}, FieldType: schema.FTInteger},
+					{Name: schema.FieldName{Name: "data"}, FieldType: schema.FTBinary},
+					{Name: schema.FieldName{Name: "ratio"}, FieldType: schema.FTDecimal},
+				},
+				PrimaryKey: schema.PrimaryKey{
+					Fields: []schema.FieldName{{Name: "k2"}, {Name: "k1"}},
+					Types:  []schema.FieldType{schema.FTByteString, schema.FTString},
+				},
+			},
+		},
+		{
+			desc:  "missing table",
+			table: "nope",
+			err:   ErrTableNotFound,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			ctx := context.Background()
+			db, teardown := ldb.LDBForTest(t)
+			defer teardown()
+			_, err := db.Exec(initSQLForTableSchema)
+			require.NoError(t, err)
+
+			reader := LDBReader{Db: db}
+			ts, err := reader.DescribeTable(ctx, "foo", test.table)
+			if test.err != nil {
+				require.Equal(t, test.err, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, ts)
+		})
+	}
+}